@@ -73,38 +73,108 @@ func main() {
 	deviceRepo := repository.NewDeviceRepository(db)
 	projectRepo := repository.NewProjectRepository(db)
 	chapterRepo := repository.NewChapterRepository(db)
+	characterStateRepo := repository.NewCharacterStateRepository(db)
+	chapterDraftRepo := repository.NewChapterDraftRepository(db)
 	modelConfigRepo := repository.NewModelConfigRepository(db)
+	usageRepo := repository.NewUsageRepository(db)
 
 	// 初始化 LLM 管理器
 	llmManager := llm.NewManager()
-	// 注册默认适配器
-	llmManager.Register("openai", llm.NewOpenAIAdapter("", "gpt-3.5-turbo"))
+	if err := llmManager.SetHTTPConfig(llm.HTTPConfig{
+		Timeout:             cfg.LLM.HTTP.Timeout,
+		StreamTimeout:       cfg.LLM.HTTP.StreamTimeout,
+		ConnectTimeout:      cfg.LLM.HTTP.ConnectTimeout,
+		IdleConnTimeout:     cfg.LLM.HTTP.IdleConnTimeout,
+		MaxIdleConnsPerHost: cfg.LLM.HTTP.MaxIdleConnsPerHost,
+		Proxy:               cfg.LLM.HTTP.Proxy,
+	}); err != nil {
+		logger.Fatal("LLM HTTP 配置无效", zap.Error(err))
+	}
+	llmManager.SetRetryPolicy(llm.RetryPolicy{
+		MaxAttempts: cfg.LLM.Retry.MaxAttempts,
+		BaseDelay:   cfg.LLM.Retry.BaseDelay,
+		MaxDelay:    cfg.LLM.Retry.MaxDelay,
+	})
+	llmManager.SetGuardPolicy(llm.GuardPolicy{
+		MaxConcurrent:    cfg.LLM.Concurrency.MaxInFlight,
+		QueueTimeout:     cfg.LLM.Concurrency.QueueTimeout,
+		FailureThreshold: cfg.LLM.Breaker.FailureThreshold,
+		OpenDuration:     cfg.LLM.Breaker.OpenDuration,
+	})
+
+	// 模型价格表
+	modelPrices := make([]llm.ModelPrice, 0, len(cfg.LLM.Pricing.Models))
+	for _, p := range cfg.LLM.Pricing.Models {
+		modelPrices = append(modelPrices, llm.ModelPrice{Model: p.Model, Input: p.Input, Output: p.Output})
+	}
+	priceTable := llm.NewPriceTable(cfg.LLM.Pricing.Currency, modelPrices)
+
+	// 响应缓存
+	responseCache := newResponseCache(cfg, db)
+	llmManager.SetCache(responseCache)
+
+	// 录制/回放
+	switch mode := cfg.LLM.Fixtures.Mode; mode {
+	case llm.FixtureModeOff:
+	case llm.FixtureModeRecord, llm.FixtureModeReplay:
+		llmManager.SetFixtures(mode, llm.NewFixtureStore(cfg.LLM.Fixtures.Dir), cfg.LLM.Fixtures.Strict)
+		logger.Info("LLM 录制/回放已启用",
+			zap.String("mode", mode),
+			zap.String("dir", cfg.LLM.Fixtures.Dir),
+			zap.Bool("strict", cfg.LLM.Fixtures.Strict),
+		)
+	default:
+		logger.Fatal("未知的 LLM 录制/回放模式", zap.String("mode", mode))
+	}
 
 	// 初始化仓储 - 对话
 	chatRepo := repository.NewChatRepository(db)
 
+	// 初始化仓储 - 后台任务
+	jobRepo := repository.NewJobRepository(db)
+
 	// 初始化服务
 	deviceService := service.NewDeviceService(deviceRepo)
 	exportService := service.NewExportService(projectRepo, chapterRepo)
-	projectService := service.NewProjectService(projectRepo, chapterRepo, modelConfigRepo, llmManager, exportService)
-	chapterService := service.NewChapterService(projectRepo, chapterRepo, modelConfigRepo, llmManager)
+	usageService := service.NewUsageService(usageRepo, priceTable, responseCache, service.QuotaLimits{
+		MonthlyTokens: cfg.LLM.Quota.MonthlyTokens,
+		MonthlyCost:   cfg.LLM.Quota.MonthlyCost,
+		WarnRatio:     cfg.LLM.Quota.WarnRatio,
+	})
+	modelInvoker := service.NewModelInvoker(modelConfigRepo, llmManager, usageService)
+	projectService := service.NewProjectService(projectRepo, chapterRepo, characterStateRepo, modelInvoker, exportService)
+	chapterService := service.NewChapterService(projectRepo, chapterRepo, characterStateRepo, chapterDraftRepo, modelInvoker, service.ChapterOptions{
+		SummaryTokenBudget: cfg.Generation.SummaryTokenBudget,
+		PreviousParagraphs: cfg.Generation.PreviousParagraphs,
+		PreviousMaxChars:   cfg.Generation.PreviousMaxChars,
+	})
+	retriever := newRetriever(cfg, db, modelInvoker, chapterRepo)
+	projectService.SetRetriever(retriever)
+	chapterService.SetRetriever(retriever)
 	modelConfigService := service.NewModelConfigService(modelConfigRepo, llmManager)
-	chatService := service.NewChatService(chatRepo, projectRepo, modelConfigRepo, llmManager)
-	writingAssistantService := service.NewWritingAssistantService(projectRepo, chapterRepo, modelConfigRepo, llmManager)
-	graphService := service.NewGraphService(projectRepo, chapterRepo, modelConfigRepo, llmManager)
-	reviewService := service.NewReviewService(projectRepo, chapterRepo, modelConfigRepo, llmManager)
+	chatService := service.NewChatService(chatRepo, projectRepo, chapterRepo, modelInvoker)
+	writingAssistantService := service.NewWritingAssistantService(projectRepo, chapterRepo, modelInvoker)
+	graphService := service.NewGraphService(projectRepo, chapterRepo, modelInvoker)
+	reviewService := service.NewReviewService(projectRepo, chapterRepo, modelInvoker)
 	backupService := service.NewBackupService(db, projectRepo, chapterRepo, chatRepo)
+	jobService := service.NewJobService(jobRepo, service.JobOptions{
+		Workers:     cfg.Jobs.Workers,
+		MaxAttempts: cfg.Jobs.MaxAttempts,
+	})
+	service.RegisterGenerationJobs(jobService, projectService, chapterService, graphService)
 
 	// 初始化处理器
 	deviceHandler := handler.NewDeviceHandler(deviceService)
-	projectHandler := handler.NewProjectHandler(projectService)
-	chapterHandler := handler.NewChapterHandler(chapterService, projectService)
+	projectHandler := handler.NewProjectHandler(projectService, jobService)
+	chapterHandler := handler.NewChapterHandler(chapterService, projectService, jobService)
 	modelConfigHandler := handler.NewModelConfigHandler(modelConfigService)
 	chatHandler := handler.NewChatHandler(chatService)
 	writingAssistantHandler := handler.NewWritingAssistantHandler(writingAssistantService)
-	graphHandler := handler.NewGraphHandler(graphService)
+	graphHandler := handler.NewGraphHandler(graphService, jobService)
 	reviewHandler := handler.NewReviewHandler(reviewService)
 	backupHandler := handler.NewBackupHandler(backupService)
+	usageHandler := handler.NewUsageHandler(usageService)
+	jobHandler := handler.NewJobHandler(jobService)
 
 	// 设置 Gin
 	if cfg.Server.Mode == "release" {
@@ -114,7 +184,7 @@ func main() {
 	r := gin.New()
 
 	// 设置路由
-	router.SetupRouter(r, deviceRepo, deviceHandler, projectHandler, chapterHandler, modelConfigHandler, chatHandler, writingAssistantHandler, graphHandler, reviewHandler, backupHandler)
+	router.SetupRouter(r, deviceRepo, deviceHandler, projectHandler, chapterHandler, modelConfigHandler, chatHandler, writingAssistantHandler, graphHandler, reviewHandler, backupHandler, usageHandler, jobHandler)
 
 	// 启动服务器
 	srv := &http.Server{
@@ -122,6 +192,11 @@ func main() {
 		Handler: r,
 	}
 
+	// 恢复上次未完成的后台任务
+	if err := jobService.Resume(context.Background()); err != nil {
+		logger.Error("恢复后台任务失败", zap.Error(err))
+	}
+
 	// 在 goroutine 中启动服务器
 	go func() {
 		logger.Info("服务器启动",
@@ -147,6 +222,9 @@ func main() {
 		logger.Error("服务器关闭失败", zap.Error(err))
 	}
 
+	// 中断后台任务，未完成的任务在下次启动时继续执行
+	jobService.Shutdown(ctx)
+
 	// 关闭数据库连接
 	if err := sqlDB.Close(); err != nil {
 		logger.Error("数据库连接关闭失败", zap.Error(err))
@@ -155,6 +233,73 @@ func main() {
 	logger.Info("服务器已关闭")
 }
 
+// newResponseCache 按配置创建 LLM 响应缓存，未启用或初始化失败时返回 nil
+func newResponseCache(cfg *config.Config, db *gorm.DB) *llm.ResponseCache {
+	cacheCfg := cfg.LLM.Cache
+	if !cacheCfg.Enabled {
+		return nil
+	}
+
+	var store llm.CacheStore
+	switch cacheCfg.Backend {
+	case "file":
+		fileStore, err := llm.NewFileCacheStore(cacheCfg.Dir)
+		if err != nil {
+			logger.Warn("创建磁盘缓存失败，响应缓存未启用", zap.String("dir", cacheCfg.Dir), zap.Error(err))
+			return nil
+		}
+		store = fileStore
+	default:
+		cacheRepo := repository.NewLLMCacheRepository(db)
+		if n, err := cacheRepo.DeleteExpired(context.Background()); err == nil && n > 0 {
+			logger.Info("已清理过期的 LLM 响应缓存", zap.Int64("count", n))
+		}
+		store = cacheRepo
+	}
+
+	logger.Info("LLM 响应缓存已启用",
+		zap.String("backend", cacheCfg.Backend),
+		zap.Duration("ttl", cacheCfg.TTL),
+	)
+	return llm.NewResponseCache(store, cacheCfg.TTL, cacheCfg.MaxTemperature)
+}
+
+// newRetriever 按配置创建前文检索，未启用时返回 nil；pgvector 不可用时回退到进程内索引
+func newRetriever(cfg *config.Config, db *gorm.DB, invoker *service.ModelInvoker, chapterRepo *repository.ChapterRepository) *service.Retriever {
+	ragCfg := cfg.RAG
+	if !ragCfg.Enabled {
+		return nil
+	}
+
+	var store repository.VectorStore
+	storeName := ragCfg.Store
+	switch storeName {
+	case "memory":
+		store = repository.NewMemoryVectorStore()
+	default:
+		pgStore, err := repository.NewPgVectorStore(db, ragCfg.Dimensions)
+		if err != nil {
+			logger.Warn("初始化 pgvector 失败，前文检索改用进程内索引（重启后需重建）", zap.Error(err))
+			store, storeName = repository.NewMemoryVectorStore(), "memory"
+		} else {
+			store, storeName = pgStore, "pgvector"
+		}
+	}
+
+	logger.Info("前文检索已启用",
+		zap.String("store", storeName),
+		zap.Int("chunk_size", ragCfg.ChunkSize),
+		zap.Int("top_k", ragCfg.TopK),
+		zap.Int("dimensions", ragCfg.Dimensions),
+	)
+	return service.NewRetriever(store, storeName, invoker, chapterRepo, service.RetrievalOptions{
+		ChunkSize:  ragCfg.ChunkSize,
+		TopK:       ragCfg.TopK,
+		MaxChars:   ragCfg.MaxChars,
+		Dimensions: ragCfg.Dimensions,
+	})
+}
+
 // autoMigrate 自动迁移数据库表
 func autoMigrate(db *gorm.DB) error {
 	logger.Info("开始数据库迁移...")
@@ -165,11 +310,17 @@ func autoMigrate(db *gorm.DB) error {
 		&model.DeviceSetting{},
 		&model.Project{},
 		&model.Chapter{},
+		&model.CharacterStateVersion{},
+		&model.ChapterDraft{},
 		&model.ModelProvider{},
 		&model.ModelConfig{},
 		&model.ModelBinding{},
 		&model.Conversation{},
 		&model.Message{},
+		&model.LLMUsage{},
+		&model.UsageQuota{},
+		&model.LLMCacheEntry{},
+		&model.Job{},
 	)
 
 	if err != nil {
@@ -236,6 +387,30 @@ func initDefaultProviders(db *gorm.DB) error {
 			AuthType:    "api_key",
 			IsActive:    true,
 		},
+		{
+			ID:          7,
+			Name:        "gemini",
+			DisplayName: "Google Gemini",
+			BaseURL:     "https://generativelanguage.googleapis.com/v1beta",
+			AuthType:    "api_key",
+			IsActive:    true,
+		},
+		{
+			ID:          8,
+			Name:        "ollama",
+			DisplayName: "Ollama（本地）",
+			BaseURL:     "http://localhost:11434",
+			AuthType:    "none",
+			IsActive:    true,
+		},
+		{
+			ID:          9,
+			Name:        "mock",
+			DisplayName: "模拟输出（仅调试）",
+			BaseURL:     "",
+			AuthType:    "none",
+			IsActive:    true,
+		},
 	}
 
 	for _, provider := range providers {
@@ -249,9 +424,10 @@ func initDefaultProviders(db *gorm.DB) error {
 				)
 			}
 		} else {
-			db.Model(&existing).Select("display_name", "base_url", "is_active").Updates(model.ModelProvider{
+			db.Model(&existing).Select("display_name", "base_url", "auth_type", "is_active").Updates(model.ModelProvider{
 				DisplayName: provider.DisplayName,
 				BaseURL:     provider.BaseURL,
+				AuthType:    provider.AuthType,
 				IsActive:    true,
 			})
 		}
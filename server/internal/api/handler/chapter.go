@@ -1,30 +1,39 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"x-novel/internal/api/middleware"
 	"x-novel/internal/dto"
+	"x-novel/internal/model"
 	"x-novel/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // ChapterHandler 章节处理器
 type ChapterHandler struct {
 	chapterService *service.ChapterService
 	projectService *service.ProjectService
+	jobService     *service.JobService
 }
 
 // NewChapterHandler 创建章节处理器
 func NewChapterHandler(
 	chapterService *service.ChapterService,
 	projectService *service.ProjectService,
+	jobService *service.JobService,
 ) *ChapterHandler {
 	return &ChapterHandler{
 		chapterService: chapterService,
 		projectService: projectService,
+		jobService:     jobService,
 	}
 }
 
@@ -204,14 +213,14 @@ func (h *ChapterHandler) Update(c *gin.Context) {
 
 // GenerateContent 生成章节内容
 // @Summary 生成章节内容
-// @Description 使用AI生成章节内容
+// @Description 提交章节生成任务，通过 /api/v1/jobs/{id} 查询进度与结果；stream=true 时以 SSE 流式返回
 // @Tags chapter
 // @Accept json
 // @Produce json
 // @Param id path string true "项目ID"
 // @Param chapterNumber path int true "章节号"
 // @Param request body dto.GenerateChapterRequest true "生成请求"
-// @Success 200 {object} dto.Response{data=dto.ChapterResponse}
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
 // @Router /api/v1/projects/{id}/chapters/{chapterNumber}/generate [post]
 func (h *ChapterHandler) GenerateContent(c *gin.Context) {
 	deviceUUID, err := middleware.GetDeviceUUID(c)
@@ -246,22 +255,14 @@ func (h *ChapterHandler) GenerateContent(c *gin.Context) {
 	}
 
 	req.ChapterNumber = chapterNumber
-	updatedChapter, err := h.chapterService.GenerateChapterContent(c.Request.Context(), deviceUUID, projectID, chapter.ID.String(), &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
+	if req.Stream {
+		h.streamChapter(c, deviceUUID, projectID, chapterNumber, func() (*service.ChapterStream, error) {
+			return h.chapterService.PrepareChapterContentStream(c.Request.Context(), deviceUUID, projectID, chapter.ID.String(), &req)
 		})
 		return
 	}
 
-	response := dto.ChapterFromModel(updatedChapter)
-
-	c.JSON(http.StatusOK, dto.Response{
-		Code:    http.StatusOK,
-		Message: "success",
-		Data:    response,
-	})
+	submitJob(c, h.jobService, service.ChapterJob(deviceUUID, projectID, &req))
 }
 
 // Finalize 定稿章节
@@ -327,7 +328,7 @@ func (h *ChapterHandler) Finalize(c *gin.Context) {
 
 // Enrich 扩写章节
 // @Summary 扩写章节
-// @Description 扩写章节内容到目标字数
+// @Description 扩写章节内容到目标字数，stream=true 时以 SSE 流式返回
 // @Tags chapter
 // @Accept json
 // @Produce json
@@ -368,10 +369,18 @@ func (h *ChapterHandler) Enrich(c *gin.Context) {
 		return
 	}
 
+	if req.Stream {
+		h.streamChapter(c, deviceUUID, projectID, chapterNumber, func() (*service.ChapterStream, error) {
+			return h.chapterService.PrepareEnrichStream(c.Request.Context(), deviceUUID, projectID, chapter.ID.String(), &req)
+		})
+		return
+	}
+
 	updatedChapter, err := h.chapterService.EnrichChapter(c.Request.Context(), deviceUUID, projectID, chapter.ID.String(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{
+			Code:    status,
 			Message: err.Error(),
 		})
 		return
@@ -385,3 +394,476 @@ func (h *ChapterHandler) Enrich(c *gin.Context) {
 		Data:    response,
 	})
 }
+
+// streamChapter 以 SSE 返回章节生成过程：逐段推送 {"content"}，结束时推送 {"done", "chapter"}；
+// 出错时推送 {"error"}，已保存部分内容的章节一并返回。
+// 章节正在后台生成或前置检查失败时在发出响应头之前返回 JSON 错误
+func (h *ChapterHandler) streamChapter(c *gin.Context, deviceID uuid.UUID, projectID string, chapterNumber int, prepare func() (*service.ChapterStream, error)) {
+	// 与后台生成任务同时写入同一章节会互相覆盖
+	spec := service.ChapterJob(deviceID, projectID, &dto.GenerateChapterRequest{ChapterNumber: chapterNumber})
+	active, err := h.jobService.Active(c.Request.Context(), spec)
+	if err == nil && active != nil {
+		err = service.ErrChapterJobActive
+	}
+	var stream *service.ChapterStream
+	if err == nil {
+		stream, err = prepare()
+	}
+	if err != nil {
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "不支持流式响应"})
+		return
+	}
+
+	callback := func(chunk string) error {
+		data, _ := json.Marshal(map[string]string{"content": chunk})
+		_, err := fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	chapter, err := stream.Run(c.Request.Context(), callback)
+	if err != nil {
+		event := map[string]interface{}{"error": err.Error()}
+		if chapter != nil {
+			event["chapter"] = dto.ChapterFromModel(chapter)
+		}
+		errData, _ := json.Marshal(event)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", errData)
+		flusher.Flush()
+		return
+	}
+
+	doneData, _ := json.Marshal(map[string]interface{}{
+		"done":    true,
+		"chapter": dto.ChapterFromModel(chapter),
+	})
+	fmt.Fprintf(c.Writer, "data: %s\n\n", doneData)
+	flusher.Flush()
+}
+
+// GenerateBatch 批量生成章节
+// @Summary 批量生成章节
+// @Description 提交批量生成任务，按顺序生成范围内的章节，通过 /api/v1/projects/{id}/chapters/batch 查看每章结果
+// @Tags chapter
+// @Accept json
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param request body dto.GenerateChapterBatchRequest true "批量生成请求"
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
+// @Router /api/v1/projects/{id}/chapters/batch [post]
+func (h *ChapterHandler) GenerateBatch(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "未授权",
+		})
+		return
+	}
+
+	var req dto.GenerateChapterBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误",
+		})
+		return
+	}
+	req.ResumeFrom = ""
+
+	submitJob(c, h.jobService, service.ChapterBatchJob(deviceUUID, c.Param("id"), &req))
+}
+
+// GetBatch 获取最近一次批量生成的状态
+// @Summary 获取批量生成状态
+// @Description 返回项目最近一次批量生成任务，result 中为每一章的结果（执行中随进度更新）
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} dto.Response{data=dto.JobResponse}
+// @Router /api/v1/projects/{id}/chapters/batch [get]
+func (h *ChapterHandler) GetBatch(c *gin.Context) {
+	job, ok := h.latestBatchJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    dto.JobFromModel(job),
+	})
+}
+
+// ResumeBatch 继续最近一次批量生成
+// @Summary 继续批量生成
+// @Description 沿用最近一次批量生成的参数，重新生成失败与未完成的章节；任务仍在执行时返回该任务
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
+// @Router /api/v1/projects/{id}/chapters/batch/resume [post]
+func (h *ChapterHandler) ResumeBatch(c *gin.Context) {
+	job, ok := h.latestBatchJob(c)
+	if !ok {
+		return
+	}
+
+	if !job.Finished() {
+		c.JSON(http.StatusAccepted, dto.Response{
+			Code:    http.StatusAccepted,
+			Message: "success",
+			Data:    dto.JobFromModel(job),
+		})
+		return
+	}
+
+	spec, err := service.ChapterBatchResumeJob(job)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNothingToResume) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	submitJob(c, h.jobService, spec)
+}
+
+// latestBatchJob 获取项目最近一次批量生成任务，失败时已写入响应
+func (h *ChapterHandler) latestBatchJob(c *gin.Context) (*model.Job, bool) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: http.StatusUnauthorized, Message: "未授权"})
+		return nil, false
+	}
+
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: http.StatusBadRequest, Message: "项目 ID 无效"})
+		return nil, false
+	}
+
+	job, err := h.jobService.Latest(c.Request.Context(), deviceUUID, projectID, model.JobTypeChapterBatch)
+	if err != nil {
+		status := jobErrorStatus(err)
+		message := err.Error()
+		if errors.Is(err, service.ErrJobNotFound) {
+			message = "没有批量生成任务"
+		}
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: message})
+		return nil, false
+	}
+	return job, true
+}
+
+// ListCharacterStates 获取角色状态历史
+// @Summary 获取角色状态历史
+// @Description 返回每次定稿更新后的角色状态，chapter_number 为 0 的是架构阶段的初始状态
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} dto.Response{data=[]dto.CharacterStateVersionResponse}
+// @Router /api/v1/projects/{id}/character-states [get]
+func (h *ChapterHandler) ListCharacterStates(c *gin.Context) {
+	versions, err := h.chapterService.ListCharacterStates(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取角色状态历史失败",
+		})
+		return
+	}
+
+	response := make([]*dto.CharacterStateVersionResponse, 0, len(versions))
+	for _, version := range versions {
+		response = append(response, dto.CharacterStateFromModel(version))
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+// DiffCharacterState 比较角色状态变化
+// @Summary 比较角色状态变化
+// @Description 逐行比较某一章定稿后的角色状态与上一版本
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Success 200 {object} dto.Response{data=dto.CharacterStateDiffResponse}
+// @Router /api/v1/projects/{id}/character-states/{chapterNumber}/diff [get]
+func (h *ChapterHandler) DiffCharacterState(c *gin.Context) {
+	chapterNumber, err := strconv.Atoi(c.Param("chapterNumber"))
+	if err != nil || chapterNumber < 0 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的章节号",
+		})
+		return
+	}
+
+	diff, err := h.chapterService.DiffCharacterState(c.Request.Context(), c.Param("id"), chapterNumber)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrCharacterStateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    diff,
+	})
+}
+
+// RetrievalStatus 获取前文检索索引状态
+// @Summary 获取前文检索索引状态
+// @Description 返回已建立检索索引的章节与尚未索引的已完成章节
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} dto.Response{data=dto.RetrievalStatusResponse}
+// @Router /api/v1/projects/{id}/retrieval [get]
+func (h *ChapterHandler) RetrievalStatus(c *gin.Context) {
+	status, err := h.chapterService.RetrievalStatus(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取检索索引状态失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    status,
+	})
+}
+
+// ReindexRetrieval 重建前文检索索引
+// @Summary 重建前文检索索引
+// @Description 重新切分并索引项目全部已完成章节，用于更换嵌入模型或定稿后修改正文的情况
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} dto.Response{data=dto.RetrievalReindexResponse}
+// @Router /api/v1/projects/{id}/retrieval/reindex [post]
+func (h *ChapterHandler) ReindexRetrieval(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "未授权",
+		})
+		return
+	}
+
+	result, err := h.chapterService.ReindexRetrieval(c.Request.Context(), deviceUUID, c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrRetrievalDisabled) || errors.Is(err, service.ErrEmbeddingNotConfigured) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    result,
+	})
+}
+
+// GenerateDrafts 生成章节候选稿
+// @Summary 生成章节候选稿
+// @Description 提交任务，为一章生成多个候选稿（可指定轮流使用的模型配置），不改动章节正文；通过 /api/v1/jobs/{id} 查询进度与结果
+// @Tags chapter
+// @Accept json
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Param request body dto.GenerateChapterDraftsRequest true "候选稿请求"
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
+// @Router /api/v1/projects/{id}/chapters/{chapterNumber}/drafts [post]
+func (h *ChapterHandler) GenerateDrafts(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "未授权",
+		})
+		return
+	}
+
+	var req dto.GenerateChapterDraftsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误",
+		})
+		return
+	}
+
+	req.ChapterNumber, _ = strconv.Atoi(c.Param("chapterNumber"))
+	submitJob(c, h.jobService, service.ChapterDraftsJob(deviceUUID, c.Param("id"), &req))
+}
+
+// ListDrafts 获取章节候选稿
+// @Summary 获取章节候选稿
+// @Description 返回一章的全部候选稿（含选用其他候选稿前被替换的正文），只包含开头片段
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Success 200 {object} dto.Response{data=[]dto.ChapterDraftResponse}
+// @Router /api/v1/projects/{id}/chapters/{chapterNumber}/drafts [get]
+func (h *ChapterHandler) ListDrafts(c *gin.Context) {
+	chapterNumber, _ := strconv.Atoi(c.Param("chapterNumber"))
+
+	drafts, err := h.chapterService.ListChapterDrafts(c.Request.Context(), c.Param("id"), chapterNumber)
+	if err != nil {
+		draftError(c, err, "获取候选稿失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    drafts,
+	})
+}
+
+// CompareDrafts 比较章节候选稿
+// @Summary 比较章节候选稿
+// @Description 返回指定候选稿的全文，ids 中的 current 表示章节当前正文；恰好两个时附带逐段差异
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Param ids query string true "候选稿 ID，逗号分隔"
+// @Success 200 {object} dto.Response{data=dto.ChapterDraftCompareResponse}
+// @Router /api/v1/projects/{id}/chapters/{chapterNumber}/drafts/compare [get]
+func (h *ChapterHandler) CompareDrafts(c *gin.Context) {
+	chapterNumber, _ := strconv.Atoi(c.Param("chapterNumber"))
+
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请至少选择两个版本进行比较",
+		})
+		return
+	}
+
+	result, err := h.chapterService.CompareChapterDrafts(c.Request.Context(), c.Param("id"), chapterNumber, ids)
+	if err != nil {
+		draftError(c, err, "比较候选稿失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    result,
+	})
+}
+
+// PromoteDraft 选用候选稿
+// @Summary 选用候选稿
+// @Description 将候选稿写入章节正文，原有正文另存为候选稿；章节回到草稿状态，需要重新定稿，原正文的摘要、角色状态与检索索引一并移除
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Param draftId path string true "候选稿ID"
+// @Success 200 {object} dto.Response{data=dto.ChapterResponse}
+// @Router /api/v1/projects/{id}/chapters/{chapterNumber}/drafts/{draftId}/promote [post]
+func (h *ChapterHandler) PromoteDraft(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "未授权",
+		})
+		return
+	}
+
+	chapterNumber, _ := strconv.Atoi(c.Param("chapterNumber"))
+
+	chapter, err := h.chapterService.PromoteChapterDraft(c.Request.Context(), deviceUUID, c.Param("id"), chapterNumber, c.Param("draftId"))
+	if err != nil {
+		draftError(c, err, "选用候选稿失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+		Data:    dto.ChapterFromModel(chapter),
+	})
+}
+
+// DeleteDraft 删除候选稿
+// @Summary 删除候选稿
+// @Tags chapter
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param chapterNumber path int true "章节号"
+// @Param draftId path string true "候选稿ID"
+// @Success 200 {object} dto.Response
+// @Router /api/v1/projects/{id}/chapters/{chapterNumber}/drafts/{draftId} [delete]
+func (h *ChapterHandler) DeleteDraft(c *gin.Context) {
+	chapterNumber, _ := strconv.Atoi(c.Param("chapterNumber"))
+
+	if err := h.chapterService.DeleteChapterDraft(c.Request.Context(), c.Param("id"), chapterNumber, c.Param("draftId")); err != nil {
+		draftError(c, err, "删除候选稿失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    http.StatusOK,
+		Message: "success",
+	})
+}
+
+// draftError 候选稿接口的错误响应：章节或候选稿不存在时返回 404
+func draftError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrChapterDraftNotFound) || errors.Is(err, service.ErrChapterNotFound) {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Code: http.StatusNotFound, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: http.StatusInternalServerError, Message: message})
+}
@@ -145,7 +145,8 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 
 	userMsg, assistantMsg, err := h.chatService.SendMessage(c.Request.Context(), deviceUUID, conversationID, req.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: fmt.Sprintf("发送消息失败: %s", err.Error())})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: fmt.Sprintf("发送消息失败: %s", err.Error())})
 		return
 	}
 
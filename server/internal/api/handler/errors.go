@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"x-novel/internal/llm"
+	"x-novel/internal/service"
+)
+
+// llmErrorStatus 模型调用类接口的错误状态码
+func llmErrorStatus(err error) int {
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		return http.StatusTooManyRequests
+	}
+	// 未配置模型需要用户先完成配置（或显式选择模拟提供商），不是服务端故障
+	if errors.Is(err, service.ErrModelNotConfigured) {
+		return http.StatusBadRequest
+	}
+	// 生成前置条件不满足
+	if errors.Is(err, service.ErrChapterNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, service.ErrChapterHasContent) || errors.Is(err, service.ErrChapterJobActive) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, service.ErrChapterEmpty) {
+		return http.StatusBadRequest
+	}
+	// 模型已熔断或并发排队超时，稍后重试即可
+	if errors.Is(err, llm.ErrCircuitOpen) || errors.Is(err, llm.ErrQueueTimeout) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -13,13 +13,14 @@ import (
 
 type GraphHandler struct {
 	graphService *service.GraphService
+	jobService   *service.JobService
 }
 
-func NewGraphHandler(graphService *service.GraphService) *GraphHandler {
-	return &GraphHandler{graphService: graphService}
+func NewGraphHandler(graphService *service.GraphService, jobService *service.JobService) *GraphHandler {
+	return &GraphHandler{graphService: graphService, jobService: jobService}
 }
 
-// GenerateGraph 从项目架构生成关系图谱
+// GenerateGraph 从项目架构生成关系图谱（后台任务）
 func (h *GraphHandler) GenerateGraph(c *gin.Context) {
 	deviceUUID, err := middleware.GetDeviceUUID(c)
 	if err != nil {
@@ -27,15 +28,7 @@ func (h *GraphHandler) GenerateGraph(c *gin.Context) {
 		return
 	}
 
-	projectID := c.Param("id")
-
-	graphData, err := h.graphService.GenerateGraph(c.Request.Context(), deviceUUID, projectID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, dto.Response{Code: 200, Message: "success", Data: graphData})
+	submitJob(c, h.jobService, service.GraphJob(deviceUUID, c.Param("id")))
 }
 
 // GetGraph 获取项目关系图谱
@@ -68,7 +61,8 @@ func (h *GraphHandler) UpdateFromChapter(c *gin.Context) {
 
 	graphData, err := h.graphService.UpdateGraphFromChapter(c.Request.Context(), deviceUUID, projectID, chapterNumber)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
 		return
 	}
 
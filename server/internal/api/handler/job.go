@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"x-novel/internal/api/middleware"
+	"x-novel/internal/dto"
+	"x-novel/internal/llm"
+	"x-novel/internal/repository"
+	"x-novel/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler 后台任务处理器
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+// NewJobHandler 创建后台任务处理器
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+// List 获取任务列表
+// 查询参数：project_id、type、status（均可选），page、page_size
+func (h *JobHandler) List(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	projectID, ok := parseOptionalUUID(c.Query("project_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "项目 ID 无效"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	jobs, total, err := h.jobService.List(c.Request.Context(), repository.JobFilter{
+		DeviceID:  deviceUUID,
+		ProjectID: projectID,
+		Type:      c.Query("type"),
+		Status:    c.Query("status"),
+	}, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "获取任务列表失败"})
+		return
+	}
+
+	resp := &dto.JobListResponse{Jobs: make([]dto.JobResponse, 0, len(jobs)), Total: total}
+	for _, job := range jobs {
+		resp.Jobs = append(resp.Jobs, *dto.JobFromModel(job))
+	}
+
+	c.JSON(http.StatusOK, dto.Response{Code: 200, Message: "success", Data: resp})
+}
+
+// Get 获取任务状态与进度
+func (h *JobHandler) Get(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	job, err := h.jobService.Get(c.Request.Context(), deviceUUID, c.Param("id"))
+	if err != nil {
+		status := jobErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{Code: 200, Message: "success", Data: dto.JobFromModel(job)})
+}
+
+// Cancel 取消任务
+func (h *JobHandler) Cancel(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	job, err := h.jobService.Cancel(c.Request.Context(), deviceUUID, c.Param("id"))
+	if err != nil {
+		status := jobErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{Code: 200, Message: "success", Data: dto.JobFromModel(job)})
+}
+
+// jobErrorStatus 任务查询与取消接口的错误状态码
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrJobFinished):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// submitJob 提交后台生成任务并立即返回 202 与任务信息，
+// 前置条件不满足时直接返回错误；同一对象已有未完成的同类任务时返回该任务
+func submitJob(c *gin.Context, jobService *service.JobService, spec service.JobSpec) {
+	spec.NoCache = llm.CacheBypassed(c.Request.Context())
+
+	job, _, err := jobService.Submit(c.Request.Context(), spec)
+	if err != nil {
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.Response{
+		Code:    http.StatusAccepted,
+		Message: "success",
+		Data:    dto.JobFromModel(job),
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"x-novel/internal/api/middleware"
 	"x-novel/internal/dto"
+	"x-novel/internal/model"
 	"x-novel/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -109,7 +110,7 @@ func (h *ModelConfigHandler) List(c *gin.Context) {
 
 	configResponses := make([]dto.ModelConfigResponse, 0, len(configs))
 	for _, config := range configs {
-		configResponses = append(configResponses, *dto.ModelConfigFromModel(config))
+		configResponses = append(configResponses, *h.withHealth(dto.ModelConfigFromModel(config)))
 	}
 
 	c.JSON(http.StatusOK, dto.Response{
@@ -146,10 +147,28 @@ func (h *ModelConfigHandler) GetByID(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    http.StatusOK,
 		Message: "success",
-		Data:    dto.ModelConfigFromModel(config),
+		Data:    h.withHealth(dto.ModelConfigFromModel(config)),
 	})
 }
 
+// withHealth 附加模型配置的并发与熔断状态，供前端展示"服务降级"
+func (h *ModelConfigHandler) withHealth(resp *dto.ModelConfigResponse) *dto.ModelConfigResponse {
+	resp.Health = dto.ModelHealthFromStatus(h.modelConfigService.Health(resp.ID))
+	return resp
+}
+
+// bindingResponse 转换功能绑定，并附加模型链中各模型的熔断状态
+func (h *ModelConfigHandler) bindingResponse(b *model.ModelBinding) *dto.ModelBindingResponse {
+	resp := dto.ModelBindingFromModel(b)
+	if resp.ModelConfig != nil {
+		h.withHealth(resp.ModelConfig)
+	}
+	for i := range resp.Chain {
+		h.withHealth(&resp.Chain[i])
+	}
+	return resp
+}
+
 // Update 更新模型配置
 // @Summary 更新模型配置
 // @Description 更新模型配置信息
@@ -301,7 +320,7 @@ func (h *ModelConfigHandler) ListBindings(c *gin.Context) {
 
 	resp := make([]dto.ModelBindingResponse, 0, len(bindings))
 	for _, b := range bindings {
-		resp = append(resp, *dto.ModelBindingFromModel(b))
+		resp = append(resp, *h.bindingResponse(b))
 	}
 
 	c.JSON(http.StatusOK, dto.Response{Code: http.StatusOK, Message: "success", Data: resp})
@@ -327,13 +346,26 @@ func (h *ModelConfigHandler) UpsertBinding(c *gin.Context) {
 		return
 	}
 
-	binding, err := h.modelConfigService.UpsertBinding(c.Request.Context(), deviceUUID, req.Purpose, configID)
+	var fallbackIDs []uuid.UUID
+	if req.FallbackConfigIDs != nil {
+		fallbackIDs = make([]uuid.UUID, 0, len(req.FallbackConfigIDs))
+		for _, raw := range req.FallbackConfigIDs {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: http.StatusBadRequest, Message: "无效的备用模型配置 ID"})
+				return
+			}
+			fallbackIDs = append(fallbackIDs, id)
+		}
+	}
+
+	binding, err := h.modelConfigService.UpsertBinding(c.Request.Context(), deviceUUID, req.Purpose, configID, fallbackIDs, req.Params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: http.StatusInternalServerError, Message: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.Response{Code: http.StatusOK, Message: "success", Data: dto.ModelBindingFromModel(binding)})
+	c.JSON(http.StatusOK, dto.Response{Code: http.StatusOK, Message: "success", Data: h.bindingResponse(binding)})
 }
 
 // DeleteBinding 删除功能绑定
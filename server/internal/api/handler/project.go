@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -14,12 +15,14 @@ import (
 // ProjectHandler 项目处理器
 type ProjectHandler struct {
 	projectService *service.ProjectService
+	jobService     *service.JobService
 }
 
 // NewProjectHandler 创建项目处理器
-func NewProjectHandler(projectService *service.ProjectService) *ProjectHandler {
+func NewProjectHandler(projectService *service.ProjectService, jobService *service.JobService) *ProjectHandler {
 	return &ProjectHandler{
 		projectService: projectService,
+		jobService:     jobService,
 	}
 }
 
@@ -223,13 +226,13 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 
 // GenerateArchitecture 生成小说架构
 // @Summary 生成小说架构
-// @Description 生成小说的架构信息
+// @Description 提交架构生成任务，通过 /api/v1/jobs/{id} 查询进度与结果
 // @Tags project
 // @Accept json
 // @Produce json
 // @Param id path string true "项目ID"
 // @Param request body dto.GenerateArchitectureRequest true "生成请求"
-// @Success 200 {object} dto.Response{data=dto.ProjectResponse}
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
 // @Router /api/v1/projects/{id}/architecture/generate [post]
 func (h *ProjectHandler) GenerateArchitecture(c *gin.Context) {
 	deviceUUID, err := middleware.GetDeviceUUID(c)
@@ -252,33 +255,56 @@ func (h *ProjectHandler) GenerateArchitecture(c *gin.Context) {
 		return
 	}
 
-	project, err := h.projectService.GenerateArchitecture(c.Request.Context(), deviceUUID, id, &req)
+	submitJob(c, h.jobService, service.ArchitectureJob(deviceUUID, id, &req))
+}
+
+// RegenerateArchitectureStep 重新生成单个架构步骤
+// @Summary 重新生成单个架构步骤
+// @Description 按修改要求重新生成某一步（如 world_building），下游步骤保留并默认标记为需要更新
+// @Tags project
+// @Accept json
+// @Produce json
+// @Param id path string true "项目ID"
+// @Param step path string true "步骤名"
+// @Param request body dto.RegenerateArchitectureStepRequest false "修改要求"
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
+// @Router /api/v1/projects/{id}/architecture/steps/{step}/regenerate [post]
+func (h *ProjectHandler) RegenerateArchitectureStep(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "未授权",
 		})
 		return
 	}
 
-	response := dto.FromModel(project)
+	id := c.Param("id")
 
-	c.JSON(http.StatusOK, dto.Response{
-		Code:    http.StatusOK,
-		Message: "success",
-		Data:    response,
-	})
+	var req dto.RegenerateArchitectureStepRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "请求参数错误",
+			})
+			return
+		}
+	}
+	req.Step = c.Param("step")
+
+	submitJob(c, h.jobService, service.ArchitectureStepJob(deviceUUID, id, &req))
 }
 
 // GenerateBlueprint 生成章节大纲
 // @Summary 生成章节大纲
-// @Description 生成章节大纲
+// @Description 提交大纲生成任务，通过 /api/v1/jobs/{id} 查询进度与结果
 // @Tags project
 // @Accept json
 // @Produce json
 // @Param id path string true "项目ID"
 // @Param request body dto.GenerateBlueprintRequest true "生成请求"
-// @Success 200 {object} dto.Response{data=dto.ProjectResponse}
+// @Success 202 {object} dto.Response{data=dto.JobResponse}
 // @Router /api/v1/projects/{id}/blueprint/generate [post]
 func (h *ProjectHandler) GenerateBlueprint(c *gin.Context) {
 	deviceUUID, err := middleware.GetDeviceUUID(c)
@@ -301,21 +327,46 @@ func (h *ProjectHandler) GenerateBlueprint(c *gin.Context) {
 		return
 	}
 
-	project, err := h.projectService.GenerateBlueprint(c.Request.Context(), deviceUUID, id, &req)
+	submitJob(c, h.jobService, service.BlueprintJob(deviceUUID, id, &req))
+}
+
+// SyncBlueprint 解析章节大纲
+// @Summary 解析章节大纲
+// @Description 将项目的章节大纲解析为各章节的大纲信息，不存在的章节会被创建，返回未能解析的章节
+// @Tags project
+// @Accept json
+// @Produce json
+// @Param id path string true "项目ID"
+// @Success 200 {object} dto.Response{data=dto.BlueprintSyncResponse}
+// @Router /api/v1/projects/{id}/blueprint/sync [post]
+func (h *ProjectHandler) SyncBlueprint(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := h.projectService.GetByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "项目不存在",
+		})
+		return
+	}
+
+	result, err := h.projectService.SyncBlueprintChapters(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrBlueprintNotGenerated) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, dto.ErrorResponse{
+			Code:    status,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	response := dto.FromModel(project)
-
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    http.StatusOK,
 		Message: "success",
-		Data:    response,
+		Data:    result,
 	})
 }
 
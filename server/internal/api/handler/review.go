@@ -35,7 +35,8 @@ func (h *ReviewHandler) DetectErrors(c *gin.Context) {
 
 	result, err := h.reviewService.DetectErrors(c.Request.Context(), deviceUUID, req.Content, req.Types)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "检测失败: " + err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: "检测失败: " + err.Error()})
 		return
 	}
 
@@ -63,7 +64,8 @@ func (h *ReviewHandler) ReviewChapter(c *gin.Context) {
 
 	result, err := h.reviewService.ReviewChapter(c.Request.Context(), deviceUUID, projectID, chapterNumber)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "审阅失败: " + err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: "审阅失败: " + err.Error()})
 		return
 	}
 
@@ -86,7 +88,8 @@ func (h *ReviewHandler) ReviewProject(c *gin.Context) {
 
 	result, err := h.reviewService.ReviewProject(c.Request.Context(), deviceUUID, projectID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "审阅失败: " + err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: "审阅失败: " + err.Error()})
 		return
 	}
 
@@ -109,7 +112,8 @@ func (h *ReviewHandler) MarketPredict(c *gin.Context) {
 
 	result, err := h.reviewService.MarketPredict(c.Request.Context(), deviceUUID, projectID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "市场预测失败: " + err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: "市场预测失败: " + err.Error()})
 		return
 	}
 
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"x-novel/internal/api/middleware"
+	"x-novel/internal/dto"
+	"x-novel/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// GetUsage 获取用量与费用统计
+// 查询参数：from / to（YYYY-MM-DD，默认最近 30 天），project_id（可选）
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	to, err := parseDateQuery(c, "to", today)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "to 日期格式错误，应为 YYYY-MM-DD"})
+		return
+	}
+	from, err := parseDateQuery(c, "from", to.AddDate(0, 0, -29))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "from 日期格式错误，应为 YYYY-MM-DD"})
+		return
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "from 不能晚于 to"})
+		return
+	}
+
+	projectID, ok := parseOptionalUUID(c.Query("project_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "项目 ID 无效"})
+		return
+	}
+
+	result, err := h.usageService.GetSummary(c.Request.Context(), deviceUUID, from, to, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "获取用量统计失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    200,
+		Message: "success",
+		Data:    result,
+	})
+}
+
+// GetCacheStats 获取 LLM 响应缓存命中统计
+func (h *UsageHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    200,
+		Message: "success",
+		Data:    h.usageService.GetCacheStats(),
+	})
+}
+
+// ListQuotas 获取月度配额及本月使用情况
+func (h *UsageHandler) ListQuotas(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	quotas, err := h.usageService.ListQuotas(c.Request.Context(), deviceUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "获取配额失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    200,
+		Message: "success",
+		Data:    quotas,
+	})
+}
+
+// UpsertQuota 设置设备级或项目级月度配额
+func (h *UsageHandler) UpsertQuota(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	var req dto.UpsertUsageQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "请求参数错误: " + err.Error()})
+		return
+	}
+
+	projectID, ok := parseOptionalUUID(req.ProjectID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "项目 ID 无效"})
+		return
+	}
+
+	quota, err := h.usageService.UpsertQuota(c.Request.Context(), deviceUUID, projectID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "设置配额失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    200,
+		Message: "success",
+		Data:    quota,
+	})
+}
+
+// DeleteQuota 删除配额，查询参数 project_id 为空时删除设备级配额
+func (h *UsageHandler) DeleteQuota(c *gin.Context) {
+	deviceUUID, err := middleware.GetDeviceUUID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Code: 401, Message: "未授权"})
+		return
+	}
+
+	projectID, ok := parseOptionalUUID(c.Query("project_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Code: 400, Message: "项目 ID 无效"})
+		return
+	}
+
+	if err := h.usageService.DeleteQuota(c.Request.Context(), deviceUUID, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "删除配额失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    200,
+		Message: "success",
+	})
+}
+
+// parseOptionalUUID 解析可选的 UUID，为空时返回 nil
+func parseOptionalUUID(v string) (*uuid.UUID, bool) {
+	if v == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
+
+// parseDateQuery 解析日期查询参数，为空时返回默认值
+func parseDateQuery(c *gin.Context, key string, def time.Time) (time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return def, nil
+	}
+	return time.ParseInLocation(time.DateOnly, v, time.Local)
+}
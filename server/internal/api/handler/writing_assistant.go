@@ -7,6 +7,7 @@ import (
 
 	"x-novel/internal/api/middleware"
 	"x-novel/internal/dto"
+	"x-novel/internal/llm"
 	"x-novel/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -58,14 +59,18 @@ func (h *WritingAssistantHandler) Assist(c *gin.Context) {
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Code: 500, Message: "处理失败: " + err.Error()})
+		status := llmErrorStatus(err)
+		c.JSON(status, dto.ErrorResponse{Code: status, Message: "处理失败: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    200,
 		Message: "success",
-		Data:    map[string]string{"result": result},
+		Data: map[string]interface{}{
+			"result":    result,
+			"synthetic": llm.IsSynthetic(c.Request.Context()),
+		},
 	})
 }
 
@@ -120,7 +125,11 @@ func (h *WritingAssistantHandler) handleStream(c *gin.Context, deviceUUID interf
 		return
 	}
 
-	doneData, _ := json.Marshal(map[string]interface{}{"done": true, "result": result})
+	doneData, _ := json.Marshal(map[string]interface{}{
+		"done":      true,
+		"result":    result,
+		"synthetic": llm.IsSynthetic(c.Request.Context()),
+	})
 	fmt.Fprintf(c.Writer, "data: %s\n\n", doneData)
 	flusher.Flush()
 }
@@ -1,6 +1,10 @@
 package middleware
 
 import (
+	"context"
+	"strings"
+
+	"x-novel/internal/llm"
 	"x-novel/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +18,7 @@ func CORS() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Device-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", SyntheticHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -24,6 +29,61 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// LLMCacheBypass 请求头 Cache-Control: no-cache 或查询参数 no_cache=true 时跳过 LLM 响应缓存
+func LLMCacheBypass() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.Contains(c.GetHeader("Cache-Control"), "no-cache") || c.Query("no_cache") == "true" {
+			c.Request = c.Request.WithContext(llm.WithCacheBypass(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
+
+// SyntheticHeader 响应内容包含模拟提供商输出时设置的响应头
+const SyntheticHeader = "X-Synthetic-Content"
+
+// SyntheticContent 记录请求处理过程中是否使用了模拟提供商，使用时在响应头中标记
+func SyntheticContent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := llm.TrackSynthetic(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &syntheticWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Next()
+	}
+}
+
+// syntheticWriter 在响应头发出前检查模拟输出标记（流式响应在首个数据块时发出响应头）
+type syntheticWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *syntheticWriter) markHeader() {
+	if !w.Written() && llm.IsSynthetic(w.ctx) {
+		w.Header().Set(SyntheticHeader, "true")
+	}
+}
+
+func (w *syntheticWriter) WriteHeaderNow() {
+	w.markHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *syntheticWriter) Write(data []byte) (int, error) {
+	w.markHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *syntheticWriter) WriteString(s string) (int, error) {
+	w.markHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *syntheticWriter) Flush() {
+	w.markHeader()
+	w.ResponseWriter.Flush()
+}
+
 // Logger 日志中间件
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
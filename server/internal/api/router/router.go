@@ -21,12 +21,16 @@ func SetupRouter(
 	graphHandler *handler.GraphHandler,
 	reviewHandler *handler.ReviewHandler,
 	backupHandler *handler.BackupHandler,
+	usageHandler *handler.UsageHandler,
+	jobHandler *handler.JobHandler,
 ) {
 	// 全局中间件
 	r.Use(middleware.CORS())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
 	r.Use(middleware.Device(deviceRepo))
+	r.Use(middleware.LLMCacheBypass())
+	r.Use(middleware.SyntheticContent())
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -75,17 +79,37 @@ func SetupRouter(
 			// 项目子资源
 			projects.GET("/:id/chapters", chapterHandler.List)
 			projects.POST("/:id/chapters", chapterHandler.Create)
+			projects.POST("/:id/chapters/batch", chapterHandler.GenerateBatch)
+			projects.GET("/:id/chapters/batch", chapterHandler.GetBatch)
+			projects.POST("/:id/chapters/batch/resume", chapterHandler.ResumeBatch)
 			projects.GET("/:id/chapters/:chapterNumber", chapterHandler.GetByNumber)
 			projects.PUT("/:id/chapters/:chapterNumber", chapterHandler.Update)
 			projects.POST("/:id/chapters/:chapterNumber/generate", chapterHandler.GenerateContent)
 			projects.POST("/:id/chapters/:chapterNumber/finalize", chapterHandler.Finalize)
 			projects.POST("/:id/chapters/:chapterNumber/enrich", chapterHandler.Enrich)
 
+			// 章节候选稿
+			projects.POST("/:id/chapters/:chapterNumber/drafts", chapterHandler.GenerateDrafts)
+			projects.GET("/:id/chapters/:chapterNumber/drafts", chapterHandler.ListDrafts)
+			projects.GET("/:id/chapters/:chapterNumber/drafts/compare", chapterHandler.CompareDrafts)
+			projects.POST("/:id/chapters/:chapterNumber/drafts/:draftId/promote", chapterHandler.PromoteDraft)
+			projects.DELETE("/:id/chapters/:chapterNumber/drafts/:draftId", chapterHandler.DeleteDraft)
+
+			// 角色状态历史
+			projects.GET("/:id/character-states", chapterHandler.ListCharacterStates)
+			projects.GET("/:id/character-states/:chapterNumber/diff", chapterHandler.DiffCharacterState)
+
+			// 前文检索
+			projects.GET("/:id/retrieval", chapterHandler.RetrievalStatus)
+			projects.POST("/:id/retrieval/reindex", chapterHandler.ReindexRetrieval)
+
 			// 架构生成
 			projects.POST("/:id/architecture/generate", projectHandler.GenerateArchitecture)
+			projects.POST("/:id/architecture/steps/:step/regenerate", projectHandler.RegenerateArchitectureStep)
 
 			// 大纲生成
 			projects.POST("/:id/blueprint/generate", projectHandler.GenerateBlueprint)
+			projects.POST("/:id/blueprint/sync", projectHandler.SyncBlueprint)
 
 			// 导出
 			projects.GET("/:id/export/:format", projectHandler.ExportProject)
@@ -106,6 +130,24 @@ func SetupRouter(
 		v1.POST("/projects/:id/review/chapters/:chapterNumber", reviewHandler.ReviewChapter)
 		v1.POST("/projects/:id/market-predict", reviewHandler.MarketPredict)
 
+		// 用量与费用统计
+		usage := v1.Group("/usage")
+		{
+			usage.GET("", usageHandler.GetUsage)
+			usage.GET("/cache", usageHandler.GetCacheStats)
+			usage.GET("/quotas", usageHandler.ListQuotas)
+			usage.PUT("/quotas", usageHandler.UpsertQuota)
+			usage.DELETE("/quotas", usageHandler.DeleteQuota)
+		}
+
+		// 后台生成任务
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("", jobHandler.List)
+			jobs.GET("/:id", jobHandler.Get)
+			jobs.POST("/:id/cancel", jobHandler.Cancel)
+		}
+
 		// 数据备份
 		backup := v1.Group("/backup")
 		{
@@ -4,15 +4,19 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
-	LLM      LLMConfig      `mapstructure:"llm"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Logger     LoggerConfig     `mapstructure:"logger"`
+	LLM        LLMConfig        `mapstructure:"llm"`
+	Jobs       JobsConfig       `mapstructure:"jobs"`
+	Generation GenerationConfig `mapstructure:"generation"`
+	RAG        RAGConfig        `mapstructure:"rag"`
 }
 
 type ServerConfig struct {
@@ -30,6 +34,29 @@ type DatabaseConfig struct {
 	SSLMode  string `mapstructure:"sslmode"`
 }
 
+// JobsConfig 后台生成任务配置
+type JobsConfig struct {
+	Workers     int `mapstructure:"workers"`      // 同时执行的任务数
+	MaxAttempts int `mapstructure:"max_attempts"` // 任务被服务重启中断后最多执行的次数
+}
+
+// GenerationConfig 章节生成的上下文配置
+type GenerationConfig struct {
+	SummaryTokenBudget int `mapstructure:"summary_token_budget"` // 全局摘要的 token 预算，超出时压缩较早章节的摘要
+	PreviousParagraphs int `mapstructure:"previous_paragraphs"`  // 生成时附带的上一章结尾段落数
+	PreviousMaxChars   int `mapstructure:"previous_max_chars"`   // 上一章结尾原文的最大字数
+}
+
+// RAGConfig 前文检索配置：定稿章节切分后建立向量索引，生成时按本章大纲检索相关片段
+type RAGConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Store      string `mapstructure:"store"`      // pgvector, memory
+	ChunkSize  int    `mapstructure:"chunk_size"` // 每个片段的目标字数
+	TopK       int    `mapstructure:"top_k"`      // 每次检索返回的片段数
+	MaxChars   int    `mapstructure:"max_chars"`  // 写入提示词的检索内容最大字数
+	Dimensions int    `mapstructure:"dimensions"` // 向量维度：pgvector 按该维度建表并建立 HNSW 索引
+}
+
 type LoggerConfig struct {
 	Level  string `mapstructure:"level"`  // debug, info, warn, error
 	Format string `mapstructure:"format"` // json, console
@@ -38,6 +65,78 @@ type LoggerConfig struct {
 type LLMConfig struct {
 	DefaultProvider string            `mapstructure:"default_provider"`
 	Providers      map[string]Provider `mapstructure:"providers"`
+	Retry          RetryConfig         `mapstructure:"retry"`
+	Concurrency    ConcurrencyConfig   `mapstructure:"concurrency"`
+	Breaker        BreakerConfig       `mapstructure:"breaker"`
+	HTTP           HTTPConfig          `mapstructure:"http"`
+	Pricing        PricingConfig       `mapstructure:"pricing"`
+	Quota          QuotaConfig         `mapstructure:"quota"`
+	Cache          CacheConfig         `mapstructure:"cache"`
+	Fixtures       FixturesConfig      `mapstructure:"fixtures"`
+}
+
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"` // 含首次调用，1 表示不重试
+	BaseDelay   time.Duration `mapstructure:"base_delay"`
+	MaxDelay    time.Duration `mapstructure:"max_delay"`
+}
+
+// ConcurrencyConfig 每个模型配置的并发限制
+type ConcurrencyConfig struct {
+	MaxInFlight  int           `mapstructure:"max_in_flight"` // 0 表示不限
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"` // 等待名额的最长时间
+}
+
+// BreakerConfig 每个模型配置的熔断器，连续失败达到阈值后在 OpenDuration 内直接拒绝请求
+type BreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 0 表示不熔断
+	OpenDuration     time.Duration `mapstructure:"open_duration"`
+}
+
+// HTTPConfig 访问模型上游的 HTTP 连接配置，所有适配器共享连接池
+type HTTPConfig struct {
+	Timeout             time.Duration `mapstructure:"timeout"`        // 非流式请求总超时
+	StreamTimeout       time.Duration `mapstructure:"stream_timeout"` // 流式请求总超时，0 表示不限
+	ConnectTimeout      time.Duration `mapstructure:"connect_timeout"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	Proxy               string        `mapstructure:"proxy"` // 为空时使用 HTTP_PROXY / HTTPS_PROXY 环境变量
+}
+
+// PricingConfig 模型价格表，用于计算调用费用
+type PricingConfig struct {
+	Currency string       `mapstructure:"currency"`
+	Models   []ModelPrice `mapstructure:"models"`
+}
+
+// ModelPrice 单个模型的价格（每百万 token），Model 支持前缀匹配
+type ModelPrice struct {
+	Model  string  `mapstructure:"model"`
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// QuotaConfig 设备默认月度配额，可按设备或项目单独覆盖；限额为 0 表示不限
+type QuotaConfig struct {
+	MonthlyTokens int64   `mapstructure:"monthly_tokens"`
+	MonthlyCost   float64 `mapstructure:"monthly_cost"`
+	WarnRatio     float64 `mapstructure:"warn_ratio"` // 用量达到限额的该比例时告警
+}
+
+// CacheConfig LLM 响应缓存，仅缓存温度不高于 MaxTemperature 的非流式调用
+type CacheConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Backend        string        `mapstructure:"backend"` // postgres, file
+	Dir            string        `mapstructure:"dir"`     // file 后端的缓存目录
+	TTL            time.Duration `mapstructure:"ttl"`
+	MaxTemperature float32       `mapstructure:"max_temperature"`
+}
+
+// FixturesConfig LLM 调用录制/回放，用于无网络环境下的端到端测试
+type FixturesConfig struct {
+	Mode   string `mapstructure:"mode"`   // 空：关闭；record：录制；replay：回放
+	Dir    string `mapstructure:"dir"`    // fixture 文件目录
+	Strict bool   `mapstructure:"strict"` // 回放时遇到未录制的请求直接失败，而不是调用真实上游
 }
 
 type Provider struct {
@@ -111,6 +210,17 @@ func applyEnvOverrides(c *Config) {
 	if v := os.Getenv("LOG_FORMAT"); v != "" {
 		c.Logger.Format = v
 	}
+	if v := os.Getenv("LLM_FIXTURES_MODE"); v != "" {
+		c.LLM.Fixtures.Mode = v
+	}
+	if v := os.Getenv("LLM_FIXTURES_DIR"); v != "" {
+		c.LLM.Fixtures.Dir = v
+	}
+	if v := os.Getenv("LLM_FIXTURES_STRICT"); v != "" {
+		if strict, err := strconv.ParseBool(v); err == nil {
+			c.LLM.Fixtures.Strict = strict
+		}
+	}
 }
 
 func setDefaults() {
@@ -129,6 +239,45 @@ func setDefaults() {
 	viper.SetDefault("logger.format", "console")
 
 	viper.SetDefault("llm.default_provider", "openai")
+	viper.SetDefault("llm.retry.max_attempts", 3)
+	viper.SetDefault("llm.retry.base_delay", time.Second)
+	viper.SetDefault("llm.retry.max_delay", 30*time.Second)
+	viper.SetDefault("llm.concurrency.max_in_flight", 4)
+	viper.SetDefault("llm.concurrency.queue_timeout", time.Minute)
+	viper.SetDefault("llm.breaker.failure_threshold", 5)
+	viper.SetDefault("llm.breaker.open_duration", 30*time.Second)
+	viper.SetDefault("llm.http.timeout", 120*time.Second)
+	viper.SetDefault("llm.http.stream_timeout", 10*time.Minute)
+	viper.SetDefault("llm.http.connect_timeout", 10*time.Second)
+	viper.SetDefault("llm.http.idle_conn_timeout", 90*time.Second)
+	viper.SetDefault("llm.http.max_idle_conns_per_host", 16)
+	viper.SetDefault("llm.http.proxy", "")
+	viper.SetDefault("llm.pricing.currency", "USD")
+	viper.SetDefault("llm.quota.monthly_tokens", 0)
+	viper.SetDefault("llm.quota.monthly_cost", 0)
+	viper.SetDefault("llm.quota.warn_ratio", 0.8)
+	viper.SetDefault("llm.cache.enabled", true)
+	viper.SetDefault("llm.cache.backend", "postgres")
+	viper.SetDefault("llm.cache.dir", "data/llm-cache")
+	viper.SetDefault("llm.cache.ttl", 24*time.Hour)
+	viper.SetDefault("llm.cache.max_temperature", 0.5)
+	viper.SetDefault("llm.fixtures.mode", "")
+	viper.SetDefault("llm.fixtures.dir", "testdata/llm-fixtures")
+	viper.SetDefault("llm.fixtures.strict", true)
+
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.max_attempts", 3)
+
+	viper.SetDefault("generation.summary_token_budget", 2000)
+	viper.SetDefault("generation.previous_paragraphs", 3)
+	viper.SetDefault("generation.previous_max_chars", 1500)
+
+	viper.SetDefault("rag.enabled", true)
+	viper.SetDefault("rag.store", "pgvector")
+	viper.SetDefault("rag.chunk_size", 500)
+	viper.SetDefault("rag.top_k", 6)
+	viper.SetDefault("rag.max_chars", 3000)
+	viper.SetDefault("rag.dimensions", 1536)
 }
 
 func (c *Config) GetDSN() string {
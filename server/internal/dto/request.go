@@ -1,5 +1,7 @@
 package dto
 
+import "x-novel/internal/model"
+
 // ========== 项目相关 ==========
 
 // CreateProjectRequest 创建项目请求
@@ -41,6 +43,13 @@ type GenerateArchitectureRequest struct {
 	Overwrite bool `json:"overwrite"` // 是否覆盖已有架构
 }
 
+// RegenerateArchitectureStepRequest 重新生成单个架构步骤请求
+type RegenerateArchitectureStepRequest struct {
+	Step        string `json:"step"`        // 步骤名，取自路径参数
+	Instruction string `json:"instruction"` // 修改要求（可选）
+	Downstream  string `json:"downstream"`  // 下游步骤处理方式：stale（默认，标记为需要更新）或 keep
+}
+
 // GenerateBlueprintRequest 生成大纲请求
 type GenerateBlueprintRequest struct {
 	Overwrite bool `json:"overwrite"` // 是否覆盖已有大纲
@@ -74,22 +83,46 @@ type UpdateChapterRequest struct {
 	BlueprintForeshadowing *string `json:"blueprint_foreshadowing"`
 	BlueprintTwistLevel  *string `json:"blueprint_twist_level"`
 	BlueprintSummary     *string `json:"blueprint_summary"`
+
+	// 正文摘要，修改后在下次定稿时计入全局摘要
+	Summary *string `json:"summary"`
 }
 
 // GenerateChapterRequest 生成章节请求
 type GenerateChapterRequest struct {
 	ChapterNumber int    `json:"chapter_number"` // 从 URL 路径设置，非必填
 	Overwrite     bool   `json:"overwrite"` // 是否覆盖已有内容
+	Stream        bool   `json:"stream"`    // 以 SSE 流式返回，不创建后台任务
+}
+
+// GenerateChapterBatchRequest 批量生成章节请求
+type GenerateChapterBatchRequest struct {
+	From         int    `json:"from" binding:"required,min=1"` // 起始章节号
+	To           int    `json:"to" binding:"required,min=1"`   // 结束章节号（包含）
+	SkipExisting bool   `json:"skip_existing"`                 // 跳过已有内容的章节
+	Overwrite    bool   `json:"overwrite"`                     // 覆盖已有内容
+	StopOnError  bool   `json:"stop_on_error"`                 // 遇到第一个失败的章节即停止
+	KeepDraft    bool   `json:"keep_draft"`                    // 生成后保留为草稿，不定稿、不更新摘要
+	ResumeFrom   string `json:"resume_from,omitempty"`         // 继续的上一次任务 ID，由继续接口设置
+}
+
+// GenerateChapterDraftsRequest 生成章节候选稿请求
+type GenerateChapterDraftsRequest struct {
+	ChapterNumber  int      `json:"chapter_number"`                                       // 从 URL 路径设置，非必填
+	Count          int      `json:"count" binding:"omitempty,min=1,max=5"`                // 候选稿数量，默认为指定的模型数，未指定模型时为 2
+	ModelConfigIDs []string `json:"model_config_ids" binding:"omitempty,max=5,dive,uuid"` // 依次轮流使用的模型配置，为空时使用章节生成绑定的模型
 }
 
 // FinalizeChapterRequest 定稿章节请求
 type FinalizeChapterRequest struct {
-	UpdateSummary bool `json:"update_summary"` // 是否更新全局摘要
+	UpdateSummary        bool `json:"update_summary"`         // 是否生成本章摘要并更新全局摘要
+	UpdateCharacterState bool `json:"update_character_state"` // 是否根据本章内容更新角色状态
 }
 
 // EnrichChapterRequest 扩写章节请求
 type EnrichChapterRequest struct {
 	TargetWords int    `json:"target_words"` // 目标字数
+	Stream      bool   `json:"stream"`       // 以 SSE 流式返回
 }
 
 // ========== 模型配置相关 ==========
@@ -98,7 +131,7 @@ type EnrichChapterRequest struct {
 type CreateModelConfigRequest struct {
 	ProviderID int    `json:"provider_id" binding:"required"`
 	ModelName  string `json:"model_name" binding:"required"`
-	APIKey     string `json:"api_key" binding:"required"`
+	APIKey     string `json:"api_key"` // 无需鉴权的提供商（如本地 Ollama）可为空
 	BaseURL    string `json:"base_url"`
 }
 
@@ -112,14 +145,29 @@ type UpdateModelConfigRequest struct {
 
 // UpsertModelBindingRequest 创建/更新功能绑定请求
 type UpsertModelBindingRequest struct {
-	Purpose       string `json:"purpose" binding:"required,oneof=architecture chapter writing review general"`
+	Purpose       string `json:"purpose" binding:"required,oneof=architecture chapter writing review general embedding"`
 	ModelConfigID string `json:"model_config_id" binding:"required"`
+	// 备用模型配置 ID，按顺序故障切换；不传则保留原有设置，传空数组则清空
+	FallbackConfigIDs []string `json:"fallback_config_ids"`
+	// 生成参数，覆盖各功能的默认值；不传则保留原有设置，传 {} 则清空
+	Params *model.GenerationParams `json:"params"`
+}
+
+// UpsertUsageQuotaRequest 设置月度用量配额请求，限额为 0 表示不限
+type UpsertUsageQuotaRequest struct {
+	// 为空表示设备级配额
+	ProjectID     string  `json:"project_id"`
+	MonthlyTokens int64   `json:"monthly_tokens" binding:"min=0"`
+	MonthlyCost   float64 `json:"monthly_cost" binding:"min=0"`
+	// 达到限额的该比例时告警，0 表示使用默认值
+	WarnRatio float64 `json:"warn_ratio" binding:"min=0,max=1"`
 }
 
 // ValidateModelConfigRequest 验证模型配置请求
 type ValidateModelConfigRequest struct {
 	ProviderID int    `json:"provider_id" binding:"required"`
-	APIKey     string `json:"api_key" binding:"required"`
+	ModelName  string `json:"model_name"`
+	APIKey     string `json:"api_key"`
 	BaseURL    string `json:"base_url"`
 }
 
@@ -2,8 +2,10 @@ package dto
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
+	"x-novel/internal/llm"
 	"x-novel/internal/model"
 
 	"github.com/google/uuid"
@@ -50,10 +52,13 @@ type ProjectResponse struct {
 	PlotArchitecture    string `json:"plot_architecture,omitempty"`
 	CharacterState      string `json:"character_state,omitempty"`
 	ArchitectureGenerated bool  `json:"architecture_generated"`
+	ArchitectureSynthetic bool  `json:"architecture_synthetic"` // 由模拟提供商生成
+	ArchitectureStale   []string `json:"architecture_stale,omitempty"` // 上游重新生成后需要更新的步骤
 
 	// 大纲数据
 	ChapterBlueprint   string `json:"chapter_blueprint,omitempty"`
 	BlueprintGenerated bool   `json:"blueprint_generated"`
+	BlueprintSynthetic bool   `json:"blueprint_synthetic"` // 由模拟提供商生成
 
 	// 统计
 	GlobalSummary      string `json:"global_summary,omitempty"`
@@ -89,15 +94,21 @@ type ChapterResponse struct {
 	BlueprintForeshadowing string `json:"blueprint_foreshadowing,omitempty"`
 	BlueprintTwistLevel  string `json:"blueprint_twist_level,omitempty"`
 	BlueprintSummary     string `json:"blueprint_summary,omitempty"`
+	Summary              string `json:"summary,omitempty"` // 定稿时根据正文生成的摘要
 
 	// 内容
 	Content   string `json:"content,omitempty"`
 	WordCount int    `json:"word_count"`
+	Synthetic bool   `json:"synthetic"` // 由模拟提供商生成
+	PartialContent string `json:"partial_content,omitempty"` // 流式生成中断时保留的部分内容
 
 	// 状态
 	Status      string `json:"status"`
 	IsFinalized bool   `json:"is_finalized"`
 
+	// 本次生成的提示，如上一章不存在或尚未定稿
+	Warnings []string `json:"warnings,omitempty"`
+
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -120,6 +131,19 @@ type ModelConfigResponse struct {
 	CreatedAt  time.Time             `json:"created_at"`
 	UpdatedAt  time.Time             `json:"updated_at"`
 	Provider   *ModelProviderResponse `json:"provider,omitempty"`
+	Health     *ModelHealthResponse   `json:"health,omitempty"`
+}
+
+// ModelHealthResponse 模型配置的并发与熔断状态
+type ModelHealthResponse struct {
+	State               string     `json:"state"`    // closed, open, half_open
+	Degraded            bool       `json:"degraded"` // 熔断中或正在探测恢复
+	InFlight            int        `json:"in_flight"`
+	MaxConcurrent       int        `json:"max_concurrent"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
 }
 
 // ModelConfigListResponse 模型配置列表响应
@@ -144,8 +168,71 @@ type ModelBindingResponse struct {
 	Purpose       string             `json:"purpose"`
 	ModelConfigID uuid.UUID          `json:"model_config_id"`
 	ModelConfig   *ModelConfigResponse `json:"model_config,omitempty"`
-	CreatedAt     time.Time          `json:"created_at"`
-	UpdatedAt     time.Time          `json:"updated_at"`
+	// 备用模型配置 ID，按故障切换顺序排列
+	FallbackConfigIDs []uuid.UUID `json:"fallback_config_ids"`
+	// 生成参数，未设置的字段使用各功能的默认值
+	Params model.GenerationParams `json:"params"`
+	// 完整模型链：主模型在前，备用模型按顺序在后
+	Chain     []ModelConfigResponse `json:"chain"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// ========== 用量响应 ==========
+
+// UsageStats 用量统计
+type UsageStats struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	Calls            int64   `json:"calls"`
+}
+
+// DailyUsageResponse 按天用量
+type DailyUsageResponse struct {
+	Date string `json:"date"`
+	UsageStats
+}
+
+// ProjectUsageResponse 按项目用量，project_id 为空表示未关联项目的调用
+type ProjectUsageResponse struct {
+	ProjectID    *uuid.UUID `json:"project_id"`
+	ProjectTitle string     `json:"project_title"`
+	UsageStats
+}
+
+// UsageSummaryResponse 用量汇总响应
+type UsageSummaryResponse struct {
+	Currency string                 `json:"currency"`
+	From     string                 `json:"from"`
+	To       string                 `json:"to"`
+	Total    UsageStats             `json:"total"`
+	Days     []DailyUsageResponse   `json:"days"`
+	Projects []ProjectUsageResponse `json:"projects"`
+}
+
+// CacheStatsResponse LLM 响应缓存统计
+type CacheStatsResponse struct {
+	Enabled    bool    `json:"enabled"`
+	TTLSeconds int64   `json:"ttl_seconds"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+}
+
+// UsageQuotaResponse 配额及本月使用情况
+type UsageQuotaResponse struct {
+	// 为空表示设备级配额
+	ProjectID     *uuid.UUID `json:"project_id"`
+	Period        string     `json:"period"` // YYYY-MM
+	MonthlyTokens int64      `json:"monthly_tokens"`
+	MonthlyCost   float64    `json:"monthly_cost"`
+	WarnRatio     float64    `json:"warn_ratio"`
+	UsedTokens    int64      `json:"used_tokens"`
+	UsedCost      float64    `json:"used_cost"`
+	Warning       bool       `json:"warning"`  // 已达到预警阈值
+	Exceeded      bool       `json:"exceeded"` // 已达到限额，后续调用将被拒绝
 }
 
 // ========== 设备响应 ==========
@@ -171,14 +258,199 @@ type DeviceSettingResponse struct {
 
 // ========== 生成任务响应 ==========
 
-// GenerationTaskResponse 生成任务响应
-type GenerationTaskResponse struct {
-	TaskID    string    `json:"task_id"`
-	Status    string    `json:"status"`    // pending, processing, completed, failed
-	Progress  int       `json:"progress"`  // 0-100
-	Message   string    `json:"message,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+// JobResponse 后台生成任务响应
+type JobResponse struct {
+	ID         uuid.UUID       `json:"id"`
+	ProjectID  *uuid.UUID      `json:"project_id,omitempty"`
+	Type       string          `json:"type"`   // architecture, blueprint, chapter, graph
+	Status     string          `json:"status"` // pending, running, succeeded, failed, canceled
+	Step       string          `json:"step,omitempty"`
+	StepIndex  int             `json:"step_index"`
+	TotalSteps int             `json:"total_steps"`
+	Result     json.RawMessage `json:"result,omitempty"` // 成功时为生成结果（项目、章节或图谱）
+	Error      string          `json:"error,omitempty"`
+	Synthetic  bool            `json:"synthetic,omitempty"` // 由模拟提供商生成
+	Attempts   int             `json:"attempts"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// JobListResponse 任务列表响应
+type JobListResponse struct {
+	Jobs  []JobResponse `json:"jobs"`
+	Total int64         `json:"total"`
+}
+
+// ========== 批量生成响应 ==========
+
+// 批量生成中单章的状态
+const (
+	ChapterBatchPending   = "pending"
+	ChapterBatchSucceeded = "succeeded"
+	ChapterBatchSkipped   = "skipped"
+	ChapterBatchFailed    = "failed"
+)
+
+// ChapterBatchResponse 批量生成章节的结果，执行中随每一章更新
+type ChapterBatchResponse struct {
+	From      int                `json:"from"`
+	To        int                `json:"to"`
+	Succeeded int                `json:"succeeded"`
+	Skipped   int                `json:"skipped"`
+	Failed    int                `json:"failed"`
+	Chapters  []ChapterBatchItem `json:"chapters"`
+}
+
+// Tally 按各章的状态重新统计
+func (r *ChapterBatchResponse) Tally() {
+	r.Succeeded, r.Skipped, r.Failed = 0, 0, 0
+	for _, item := range r.Chapters {
+		switch item.Status {
+		case ChapterBatchSucceeded:
+			r.Succeeded++
+		case ChapterBatchSkipped:
+			r.Skipped++
+		case ChapterBatchFailed:
+			r.Failed++
+		}
+	}
+}
+
+// ChapterBatchItem 批量生成中单章的结果
+type ChapterBatchItem struct {
+	ChapterNumber int      `json:"chapter_number"`
+	Status        string   `json:"status"` // pending, succeeded, skipped, failed
+	Error         string   `json:"error,omitempty"`
+	Warnings      []string `json:"warnings,omitempty"` // 生成时的提示，如上一章尚未定稿
+	WordCount     int      `json:"word_count,omitempty"`
+	Synthetic     bool     `json:"synthetic,omitempty"`
+}
+
+// ========== 大纲解析响应 ==========
+
+// BlueprintSyncResponse 章节大纲解析结果
+type BlueprintSyncResponse struct {
+	Parsed    int                  `json:"parsed"`    // 成功解析的章节数
+	Created   int                  `json:"created"`   // 新建的章节数
+	Updated   int                  `json:"updated"`   // 大纲信息有变化的已有章节数
+	Unchanged int                  `json:"unchanged"` // 大纲信息无变化的已有章节数
+	Unparsed  []BlueprintSyncIssue `json:"unparsed"`  // 未能解析的章节
+}
+
+// BlueprintSyncIssue 未能解析的章节
+type BlueprintSyncIssue struct {
+	ChapterNumber int    `json:"chapter_number,omitempty"`
+	Line          int    `json:"line,omitempty"` // 在大纲中的行号
+	Text          string `json:"text,omitempty"`
+	Reason        string `json:"reason"`
+}
+
+// BlueprintGenerateResponse 生成章节大纲任务的结果
+type BlueprintGenerateResponse struct {
+	Project  *ProjectResponse       `json:"project"`
+	Chapters *BlueprintSyncResponse `json:"chapters"`
+}
+
+// ========== 角色状态响应 ==========
+
+// CharacterStateVersionResponse 角色状态历史版本
+type CharacterStateVersionResponse struct {
+	ChapterNumber int       `json:"chapter_number"` // 0 为架构阶段的初始状态
+	Content       string    `json:"content"`
+	Synthetic     bool      `json:"synthetic"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CharacterStateDiffResponse 某一章定稿后角色状态相对上一版本的变化
+type CharacterStateDiffResponse struct {
+	ChapterNumber         int        `json:"chapter_number"`
+	PreviousChapterNumber *int       `json:"previous_chapter_number"` // 没有上一版本时为 null
+	Added                 int        `json:"added"`                   // 新增行数
+	Removed               int        `json:"removed"`                 // 删除行数
+	Lines                 []DiffLine `json:"lines"`
+}
+
+// DiffLine 逐行比较结果，Op 为 equal / add / remove
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// 逐行比较的操作类型
+const (
+	DiffEqual  = "equal"
+	DiffAdd    = "add"
+	DiffRemove = "remove"
+)
+
+// ========== 候选稿响应 ==========
+
+// ChapterDraftResponse 章节候选稿
+type ChapterDraftResponse struct {
+	ID            string     `json:"id"` // 比较时章节当前正文的 ID 为 current
+	ChapterNumber int        `json:"chapter_number"`
+	Source        string     `json:"source"` // generated, replaced, current
+	ModelConfigID *uuid.UUID `json:"model_config_id,omitempty"`
+	Model         string     `json:"model,omitempty"`
+	WordCount     int        `json:"word_count"`
+	Paragraphs    int        `json:"paragraphs"`
+	Synthetic     bool       `json:"synthetic"`
+	Current       bool       `json:"current"` // 与章节当前正文相同
+	PromotedAt    *time.Time `json:"promoted_at,omitempty"`
+	Preview       string     `json:"preview,omitempty"` // 列表中返回开头的片段
+	Content       string     `json:"content,omitempty"` // 比较时返回全文
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ChapterDraftsResponse 生成候选稿的结果，执行中随每一稿更新
+type ChapterDraftsResponse struct {
+	ChapterNumber int                `json:"chapter_number"`
+	Succeeded     int                `json:"succeeded"`
+	Failed        int                `json:"failed"`
+	Drafts        []ChapterDraftItem `json:"drafts"`
+}
+
+// ChapterDraftItem 单个候选稿的生成结果，状态同批量生成：pending, succeeded, failed
+type ChapterDraftItem struct {
+	Index         int                   `json:"index"`
+	ModelConfigID string                `json:"model_config_id,omitempty"` // 指定的模型配置
+	Status        string                `json:"status"`
+	Error         string                `json:"error,omitempty"`
+	Draft         *ChapterDraftResponse `json:"draft,omitempty"`
+}
+
+// ChapterDraftCompareResponse 候选稿比较
+type ChapterDraftCompareResponse struct {
+	Drafts []ChapterDraftResponse `json:"drafts"`
+	// 恰好比较两个版本时第二个相对第一个的逐段差异
+	Diff *ChapterDraftDiff `json:"diff,omitempty"`
+}
+
+// ChapterDraftDiff 两个版本的逐段差异
+type ChapterDraftDiff struct {
+	Added   int        `json:"added"`   // 新增段落数
+	Removed int        `json:"removed"` // 删除段落数
+	Lines   []DiffLine `json:"lines"`
+}
+
+// ========== 前文检索响应 ==========
+
+// RetrievalStatusResponse 前文检索索引状态
+type RetrievalStatusResponse struct {
+	Enabled         bool   `json:"enabled"`
+	Store           string `json:"store,omitempty"`  // pgvector, memory
+	IndexedChapters []int  `json:"indexed_chapters"` // 已建立索引的章节号
+	MissingChapters []int  `json:"missing_chapters"` // 已完成但尚未索引的章节号
+}
+
+// RetrievalReindexResponse 重建索引结果
+type RetrievalReindexResponse struct {
+	Chapters int   `json:"chapters"` // 成功索引的章节数
+	Chunks   int   `json:"chunks"`   // 片段总数
+	Failed   []int `json:"failed"`   // 索引失败的章节号
 }
 
 // ========== 导出响应 ==========
@@ -215,6 +487,7 @@ type MessageResponse struct {
 	ID        uuid.UUID `json:"id"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
+	Synthetic bool      `json:"synthetic,omitempty"` // 由模拟提供商生成
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -242,6 +515,7 @@ func ConversationFromModel(c *model.Conversation) *ConversationResponse {
 				ID:        m.ID,
 				Role:      m.Role,
 				Content:   m.Content,
+				Synthetic: m.Synthetic,
 				CreatedAt: m.CreatedAt,
 			}
 		}
@@ -256,6 +530,7 @@ func MessageFromModel(m *model.Message) *MessageResponse {
 		ID:        m.ID,
 		Role:      m.Role,
 		Content:   m.Content,
+		Synthetic: m.Synthetic,
 		CreatedAt: m.CreatedAt,
 	}
 }
@@ -295,8 +570,11 @@ func FromModel(p *model.Project) *ProjectResponse {
 		PlotArchitecture:     p.PlotArchitecture,
 		CharacterState:       p.CharacterState,
 		ArchitectureGenerated: p.ArchitectureGenerated,
+		ArchitectureSynthetic: p.ArchitectureSynthetic,
+		ArchitectureStale:    p.GetArchitectureStale(),
 		ChapterBlueprint:     p.ChapterBlueprint,
 		BlueprintGenerated:   p.BlueprintGenerated,
+		BlueprintSynthetic:   p.BlueprintSynthetic,
 		GlobalSummary:        p.GlobalSummary,
 		TotalChapters:        totalChapters,
 		CompletedChapters:    completedChapters,
@@ -320,10 +598,14 @@ func ChapterFromModel(c *model.Chapter) *ChapterResponse {
 		BlueprintForeshadowing: c.BlueprintForeshadowing,
 		BlueprintTwistLevel:  c.BlueprintTwistLevel,
 		BlueprintSummary:     c.BlueprintSummary,
+		Summary:              c.Summary,
 		Content:              c.Content,
 		WordCount:            c.WordCount,
+		Synthetic:            c.Synthetic,
+		PartialContent:       c.PartialContent,
 		Status:               c.Status,
 		IsFinalized:          c.IsFinalized,
+		Warnings:             c.GenerationWarnings,
 		CreatedAt:            c.CreatedAt,
 		UpdatedAt:            c.UpdatedAt,
 	}
@@ -355,19 +637,123 @@ func ModelConfigFromModel(mc *model.ModelConfig) *ModelConfigResponse {
 	return resp
 }
 
+// ModelHealthFromStatus 从熔断状态转换为响应
+func ModelHealthFromStatus(status llm.GuardStatus) *ModelHealthResponse {
+	return &ModelHealthResponse{
+		State:               string(status.State),
+		Degraded:            status.State != llm.BreakerClosed,
+		InFlight:            status.InFlight,
+		MaxConcurrent:       status.MaxConcurrent,
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		LastError:           status.LastError,
+		OpenedAt:            status.OpenedAt,
+		RetryAt:             status.RetryAt,
+	}
+}
+
 // ModelBindingFromModel 从模型转换为功能绑定响应
 func ModelBindingFromModel(mb *model.ModelBinding) *ModelBindingResponse {
 	resp := &ModelBindingResponse{
-		ID:            mb.ID,
-		Purpose:       mb.Purpose,
-		ModelConfigID: mb.ModelConfigID,
-		CreatedAt:     mb.CreatedAt,
-		UpdatedAt:     mb.UpdatedAt,
+		ID:                mb.ID,
+		Purpose:           mb.Purpose,
+		ModelConfigID:     mb.ModelConfigID,
+		FallbackConfigIDs: mb.GetFallbackConfigIDs(),
+		Params:            mb.GetParams(),
+		Chain:             []ModelConfigResponse{},
+		CreatedAt:         mb.CreatedAt,
+		UpdatedAt:         mb.UpdatedAt,
+	}
+	if resp.FallbackConfigIDs == nil {
+		resp.FallbackConfigIDs = []uuid.UUID{}
 	}
 
 	if mb.ModelConfig != nil {
 		resp.ModelConfig = ModelConfigFromModel(mb.ModelConfig)
+		resp.Chain = append(resp.Chain, *resp.ModelConfig)
+	}
+	for _, fc := range mb.FallbackConfigs {
+		resp.Chain = append(resp.Chain, *ModelConfigFromModel(fc))
 	}
 
 	return resp
 }
+
+// CharacterStateFromModel 从模型转换为角色状态版本响应
+func CharacterStateFromModel(v *model.CharacterStateVersion) *CharacterStateVersionResponse {
+	return &CharacterStateVersionResponse{
+		ChapterNumber: v.ChapterNumber,
+		Content:       v.Content,
+		Synthetic:     v.Synthetic,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+	}
+}
+
+// ChapterDraftFromModel 从模型转换为候选稿响应，current 为章节当前正文；withContent 为 false 时只返回开头片段
+func ChapterDraftFromModel(d *model.ChapterDraft, current string, withContent bool) *ChapterDraftResponse {
+	resp := &ChapterDraftResponse{
+		ID:            d.ID.String(),
+		ChapterNumber: d.ChapterNumber,
+		Source:        d.Source,
+		ModelConfigID: d.ModelConfigID,
+		Model:         d.Model,
+		WordCount:     d.WordCount,
+		Paragraphs:    countParagraphs(d.Content),
+		Synthetic:     d.Synthetic,
+		Current:       d.Content == current,
+		PromotedAt:    d.PromotedAt,
+		CreatedAt:     d.CreatedAt,
+	}
+	if withContent {
+		resp.Content = d.Content
+	} else {
+		resp.Preview = draftPreview(d.Content)
+	}
+	return resp
+}
+
+// draftPreviewChars 候选稿列表中预览的字数
+const draftPreviewChars = 120
+
+func draftPreview(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= draftPreviewChars {
+		return string(runes)
+	}
+	return string(runes[:draftPreviewChars]) + "……"
+}
+
+// countParagraphs 非空段落数
+func countParagraphs(content string) int {
+	n := 0
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// JobFromModel 从模型转换为任务响应
+func JobFromModel(j *model.Job) *JobResponse {
+	resp := &JobResponse{
+		ID:         j.ID,
+		ProjectID:  j.ProjectID,
+		Type:       j.Type,
+		Status:     j.Status,
+		Step:       j.Step,
+		StepIndex:  j.StepIndex,
+		TotalSteps: j.TotalSteps,
+		Error:      j.Error,
+		Synthetic:  j.Synthetic,
+		Attempts:   j.Attempts,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+		CreatedAt:  j.CreatedAt,
+		UpdatedAt:  j.UpdatedAt,
+	}
+	if j.Result != "" {
+		resp.Result = json.RawMessage(j.Result)
+	}
+	return resp
+}
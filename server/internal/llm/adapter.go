@@ -2,12 +2,18 @@ package llm
 
 import (
 	"context"
+	"sync"
 )
 
 // ChatMessage 聊天消息
 type ChatMessage struct {
-	Role    string `json:"role"`    // system, user, assistant
+	Role    string `json:"role"`    // system, user, assistant, tool
 	Content string `json:"content"`
+
+	// ToolCalls assistant 消息中模型发起的工具调用
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID tool 消息对应的工具调用 ID
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // ChatOptions 聊天选项
@@ -16,6 +22,53 @@ type ChatOptions struct {
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Stream      bool    `json:"stream,omitempty"`
 	APIKey      string  `json:"-"` // API Key，不序列化到 JSON
+
+	// 以下参数为 0 或空时不发送，使用上游默认值；不支持的提供商会忽略
+	TopP             float32  `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  float32  `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float32  `json:"frequency_penalty,omitempty"`
+
+	// ResponseFormat 结构化输出要求，为空时输出普通文本
+	ResponseFormat *ResponseFormat `json:"-"`
+	// NoCache 跳过响应缓存
+	NoCache bool `json:"-"`
+	// Tools 可供模型调用的工具，仅 OpenAI 兼容与 Anthropic 适配器支持，其他适配器忽略
+	Tools []Tool `json:"-"`
+}
+
+// hasSamplingParams 是否设置了任一生成参数
+func (o ChatOptions) hasSamplingParams() bool {
+	return o.Temperature > 0 || o.MaxTokens > 0 || o.TopP > 0 || len(o.Stop) > 0 ||
+		o.PresencePenalty != 0 || o.FrequencyPenalty != 0
+}
+
+// optionalFloat 0 表示未设置，返回 nil 以省略该字段
+func optionalFloat(v float32) *float32 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// Usage token 用量
+type Usage struct {
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated,omitempty"` // 上游未返回用量，按字符数估算
+}
+
+// ChatResponse 聊天补全结果
+type ChatResponse struct {
+	Content      string `json:"content"`
+	Usage        Usage  `json:"usage"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	Cached       bool   `json:"cached,omitempty"` // 命中响应缓存，未调用上游
+	Synthetic    bool   `json:"synthetic,omitempty"` // 由模拟提供商生成，并非真实模型输出
+
+	// ToolCalls 模型要求调用的工具，非空时 Content 可能为空
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
 // StreamCallback 流式响应回调
@@ -24,10 +77,13 @@ type StreamCallback func(chunk string) error
 // LLMAdapter LLM 适配器接口
 type LLMAdapter interface {
 	// ChatCompletion 聊天补全
-	ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (string, error)
+	ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error)
 
-	// StreamChatCompletion 流式聊天补全
-	StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (string, error)
+	// StreamChatCompletion 流式聊天补全，出错时返回已接收的部分内容
+	StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error)
+
+	// Embed 计算文本的向量嵌入，不支持的提供商返回 ErrEmbeddingNotSupported
+	Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error)
 
 	// ValidateConfig 验证配置
 	ValidateConfig(apiKey, baseURL string) error
@@ -36,66 +92,200 @@ type LLMAdapter interface {
 	GetDefaultModel() string
 }
 
+// NewAdapter 按提供商名称创建适配器，未知提供商按 OpenAI 兼容协议处理
+func NewAdapter(provider, baseURL, model string) LLMAdapter {
+	return newProviderAdapter(provider, baseURL, model, defaultHTTPClients())
+}
+
+func newProviderAdapter(provider, baseURL, model string, clients *HTTPClients) LLMAdapter {
+	switch provider {
+	case "anthropic":
+		return newAnthropicAdapter(baseURL, model, clients)
+	case "gemini":
+		return newGeminiAdapter(baseURL, model, clients)
+	case "ollama":
+		return newOllamaAdapter(baseURL, model, clients)
+	case MockProvider:
+		return NewMockAdapter(model)
+	default:
+		return newOpenAIAdapter(baseURL, model, clients)
+	}
+}
+
+// AdapterFactory 按 BaseURL 和模型名创建适配器
+type AdapterFactory func(baseURL, model string) LLMAdapter
+
 // Manager LLM 管理器
 type Manager struct {
-	adapters map[string]LLMAdapter
+	factories map[string]AdapterFactory
+	retry     RetryPolicy
+	cache     *ResponseCache
+
+	guardPolicy GuardPolicy
+	guardsMu    sync.Mutex
+	guards      map[string]*Guard
+
+	fixtureMode   string
+	fixtures      *FixtureStore
+	fixtureStrict bool
+
+	// 基础适配器池，按提供商、BaseURL 与模型复用，共享同一组 HTTP 客户端
+	clients *HTTPClients
+	poolMu  sync.Mutex
+	pool    map[string]LLMAdapter
 }
 
 // NewManager 创建 LLM 管理器
 func NewManager() *Manager {
 	return &Manager{
-		adapters: make(map[string]LLMAdapter),
+		factories: make(map[string]AdapterFactory),
+		retry:     DefaultRetryPolicy,
+
+		guardPolicy: DefaultGuardPolicy,
+		guards:      make(map[string]*Guard),
+
+		clients: defaultHTTPClients(),
+		pool:    make(map[string]LLMAdapter),
 	}
 }
 
-// Register 注册适配器
-func (m *Manager) Register(provider string, adapter LLMAdapter) {
-	m.adapters[provider] = adapter
+// SetHTTPConfig 设置访问上游的 HTTP 连接配置，并清空已缓存的适配器
+func (m *Manager) SetHTTPConfig(cfg HTTPConfig) error {
+	clients, err := NewHTTPClients(cfg)
+	if err != nil {
+		return err
+	}
+	m.poolMu.Lock()
+	defer m.poolMu.Unlock()
+	m.clients = clients
+	m.pool = make(map[string]LLMAdapter)
+	return nil
 }
 
-// Get 获取适配器
-func (m *Manager) Get(provider string) (LLMAdapter, bool) {
-	adapter, ok := m.adapters[provider]
-	return adapter, ok
+// BaseAdapter 从适配器池获取（或创建）不带重试、缓存等包装的基础适配器
+func (m *Manager) BaseAdapter(provider, baseURL, model string) LLMAdapter {
+	key := provider + "|" + baseURL + "|" + model
+	m.poolMu.Lock()
+	defer m.poolMu.Unlock()
+	if adapter, ok := m.pool[key]; ok {
+		return adapter
+	}
+	var adapter LLMAdapter
+	if factory, ok := m.factories[provider]; ok {
+		adapter = factory(baseURL, model)
+	} else {
+		adapter = newProviderAdapter(provider, baseURL, model, m.clients)
+	}
+	m.pool[key] = adapter
+	return adapter
 }
 
-// GetDefault 获取默认适配器
-func (m *Manager) GetDefault() (LLMAdapter, bool) {
-	return m.Get("openai")
+// SetRetryPolicy 设置 NewAdapter 创建的适配器所使用的重试策略
+func (m *Manager) SetRetryPolicy(policy RetryPolicy) {
+	m.retry = policy
 }
 
-// ChatCompletion 聊天补全（使用指定提供商）
-func (m *Manager) ChatCompletion(ctx context.Context, provider string, messages []ChatMessage, options ChatOptions) (string, error) {
-	adapter, ok := m.Get(provider)
+// SetGuardPolicy 设置 NewGuardedAdapter 使用的并发与熔断策略，仅对之后新建的 Guard 生效
+func (m *Manager) SetGuardPolicy(policy GuardPolicy) {
+	m.guardPolicy = policy
+}
+
+// guard 获取（或创建）指定键的并发与熔断控制
+func (m *Manager) guard(key string) *Guard {
+	m.guardsMu.Lock()
+	defer m.guardsMu.Unlock()
+	g, ok := m.guards[key]
 	if !ok {
-		adapter, ok = m.GetDefault()
-		if !ok {
-			return "", ErrNoAdapterAvailable
-		}
+		g = NewGuard(m.guardPolicy)
+		m.guards[key] = g
 	}
-
-	return adapter.ChatCompletion(ctx, messages, options)
+	return g
 }
 
-// StreamChatCompletion 流式聊天补全
-func (m *Manager) StreamChatCompletion(ctx context.Context, provider string, messages []ChatMessage, options ChatOptions, callback StreamCallback) (string, error) {
-	adapter, ok := m.Get(provider)
+// GuardStatus 获取指定键的并发与熔断状态，尚未发生过调用时 ok 为 false
+func (m *Manager) GuardStatus(key string) (GuardStatus, bool) {
+	m.guardsMu.Lock()
+	g, ok := m.guards[key]
+	m.guardsMu.Unlock()
 	if !ok {
-		adapter, ok = m.GetDefault()
-		if !ok {
-			return "", ErrNoAdapterAvailable
-		}
+		return GuardStatus{}, false
 	}
+	return g.Status(), true
+}
+
+// SetCache 设置 NewAdapter 创建的适配器所使用的响应缓存，为 nil 时不缓存
+func (m *Manager) SetCache(cache *ResponseCache) {
+	m.cache = cache
+}
+
+// Cache 获取响应缓存
+func (m *Manager) Cache() *ResponseCache {
+	return m.cache
+}
+
+// SetFixtures 设置录制/回放模式，mode 为 FixtureModeOff 时关闭
+func (m *Manager) SetFixtures(mode string, store *FixtureStore, strict bool) {
+	m.fixtureMode = mode
+	m.fixtures = store
+	m.fixtureStrict = strict
+}
 
-	return adapter.StreamChatCompletion(ctx, messages, options, callback)
+// RegisterFactory 注册自定义提供商的适配器工厂，优先于内置实现
+func (m *Manager) RegisterFactory(provider string, factory AdapterFactory) {
+	m.poolMu.Lock()
+	defer m.poolMu.Unlock()
+	m.factories[provider] = factory
+}
+
+// NewAdapter 为指定的提供商、BaseURL 和模型创建适配器（带重试与响应缓存）
+func (m *Manager) NewAdapter(provider, baseURL, model string) LLMAdapter {
+	return m.newAdapter(provider, baseURL, model, nil)
+}
+
+// NewGuardedAdapter 与 NewAdapter 相同，并按 guardKey（一般为模型配置 ID）共享并发限制与熔断状态
+func (m *Manager) NewGuardedAdapter(guardKey, provider, baseURL, model string) LLMAdapter {
+	if provider == MockProvider {
+		return m.newAdapter(provider, baseURL, model, nil)
+	}
+	return m.newAdapter(provider, baseURL, model, m.guard(guardKey))
+}
+
+func (m *Manager) newAdapter(provider, baseURL, model string, guard *Guard) LLMAdapter {
+	adapter := m.BaseAdapter(provider, baseURL, model)
+	// 模拟提供商不访问上游，无需重试、缓存与录制
+	if provider == MockProvider {
+		return adapter
+	}
+	if m.retry.MaxAttempts > 1 {
+		adapter = NewRetryAdapter(adapter, m.retry)
+	}
+	// 熔断在重试之外：一次调用的所有重试都失败才计为一次失败，且重试期间占用同一个并发名额
+	if guard != nil {
+		adapter = NewGuardAdapter(adapter, guard)
+	}
+	// 缓存在重试之外，命中时不触发任何上游请求
+	if m.cache != nil {
+		adapter = NewCacheAdapter(adapter, m.cache, provider+"|"+baseURL+"|"+model)
+	}
+	// 录制/回放在最外层：录制不受缓存命中影响，回放不会被缓存掩盖缺失的 fixture
+	switch m.fixtureMode {
+	case FixtureModeRecord:
+		adapter = NewRecordAdapter(adapter, m.fixtures)
+	case FixtureModeReplay:
+		adapter = NewReplayAdapter(adapter, m.fixtures, m.fixtureStrict)
+	}
+	return adapter
 }
 
 // Errors
 var (
-	ErrNoAdapterAvailable = &LLMError{Message: "没有可用的 LLM 适配器"}
-	ErrInvalidAPIKey      = &LLMError{Message: "无效的 API Key"}
-	ErrRateLimitExceeded  = &LLMError{Message: "API 请求频率超限"}
-	ErrInvalidResponse    = &LLMError{Message: "无效的响应"}
+	ErrInvalidAPIKey     = &LLMError{Message: "无效的 API Key"}
+	ErrRateLimitExceeded = &LLMError{Message: "API 请求频率超限"}
+	ErrInvalidResponse   = &LLMError{Message: "无效的响应"}
+	ErrServerError       = &LLMError{Message: "服务端错误"}
+	ErrTimeout           = &LLMError{Message: "请求超时"}
+	ErrNetwork           = &LLMError{Message: "网络连接失败"}
+	ErrRequestFailed     = &LLMError{Message: "请求被拒绝"}
 )
 
 // LLMError LLM 错误
@@ -0,0 +1,407 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	anthropicDefaultBaseURL = "https://api.anthropic.com/v1"
+	anthropicDefaultModel   = "claude-3-5-haiku-latest"
+	anthropicAPIVersion     = "2023-06-01"
+
+	// anthropicDefaultMaxTokens Messages API 要求必须指定 max_tokens
+	anthropicDefaultMaxTokens = 4096
+)
+
+// Anthropic 停止原因
+const (
+	AnthropicStopEndTurn   = "end_turn"
+	AnthropicStopMaxTokens = "max_tokens"
+	AnthropicStopSequence  = "stop_sequence"
+	AnthropicStopToolUse   = "tool_use"
+	AnthropicStopRefusal   = "refusal"
+)
+
+// AnthropicAdapter Anthropic Messages API 原生适配器
+type AnthropicAdapter struct {
+	baseURL string
+	model   string
+	clients *HTTPClients
+}
+
+// NewAnthropicAdapter 创建 Anthropic 适配器（使用进程级共享的 HTTP 客户端）
+func NewAnthropicAdapter(baseURL, model string) *AnthropicAdapter {
+	return newAnthropicAdapter(baseURL, model, defaultHTTPClients())
+}
+
+func newAnthropicAdapter(baseURL, model string, clients *HTTPClients) *AnthropicAdapter {
+	if baseURL == "" {
+		baseURL = anthropicDefaultBaseURL
+	}
+	if model == "" {
+		model = anthropicDefaultModel
+	}
+	return &AnthropicAdapter{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		clients: clients,
+	}
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	InputSchema *Schema `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	TopP        *float32           `json:"top_p,omitempty"`
+	Stop        []string           `json:"stop_sequences,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+type anthropicContentBlock struct {
+	Type      string          `json:"type"` // text, tool_use, tool_result
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`          // tool_use
+	Name      string          `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage `json:"input,omitempty"`       // tool_use
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result
+	Content   string          `json:"content,omitempty"`     // tool_result
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) toUsage() Usage {
+	usage := Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
+	usage.normalize()
+	return usage
+}
+
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+type anthropicErrorBody struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamEvent SSE 事件的 data 部分，按 type 字段区分
+type anthropicStreamEvent struct {
+	Type         string                `json:"type"`
+	Index        int                   `json:"index"`
+	ContentBlock anthropicContentBlock `json:"content_block"`
+	Message      anthropicResponse     `json:"message"` // message_start
+	Usage        anthropicUsage        `json:"usage"`   // message_delta，output_tokens 为累计值
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text,omitempty"`
+		PartialJSON string `json:"partial_json,omitempty"` // input_json_delta
+		StopReason  string `json:"stop_reason,omitempty"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toAnthropicMessages 拆分出 system 提示词，工具结果转为 user 消息中的 tool_result，并合并相邻的同角色消息
+func toAnthropicMessages(messages []ChatMessage) (string, []anthropicMessage) {
+	var system []string
+	out := make([]anthropicMessage, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+			continue
+		}
+		role := m.Role
+		if role != "assistant" {
+			role = "user"
+		}
+
+		var blocks []anthropicContentBlock
+		if m.Role == RoleTool {
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content})
+		} else if m.Content != "" {
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: m.Content})
+		}
+		for _, call := range m.ToolCalls {
+			input := json.RawMessage(toolArguments(call.Arguments))
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			continue
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+	return strings.Join(system, "\n\n"), out
+}
+
+func toAnthropicTools(tools []Tool) []anthropicTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]anthropicTool, len(tools))
+	for i, t := range tools {
+		out[i] = anthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.params()}
+	}
+	return out
+}
+
+func (a *AnthropicAdapter) buildRequest(messages []ChatMessage, options ChatOptions, stream bool) anthropicRequest {
+	system, msgs := toAnthropicMessages(messages)
+	// Messages API 没有 response_format，输出要求放入系统提示词
+	if options.ResponseFormat != nil {
+		system = strings.TrimSpace(system + "\n\n" + options.ResponseFormat.Instruction())
+	}
+	req := anthropicRequest{
+		Model:     a.model,
+		System:    system,
+		Messages:  msgs,
+		MaxTokens: options.MaxTokens,
+		Tools:     toAnthropicTools(options.Tools),
+		Stream:    stream,
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if options.Temperature > 0 {
+		// Anthropic 的 temperature 取值范围为 0-1
+		t := options.Temperature
+		if t > 1 {
+			t = 1
+		}
+		req.Temperature = &t
+	}
+	if options.TopP > 0 {
+		p := options.TopP
+		req.TopP = &p
+	}
+	// Messages API 不支持 presence/frequency penalty
+	req.Stop = options.Stop
+	return req
+}
+
+func (a *AnthropicAdapter) do(ctx context.Context, apiKey string, body anthropicRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	if body.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := a.clients.get(body.Stream).Do(httpReq)
+	if err != nil {
+		return nil, classifyTransportError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, anthropicStatusError(resp)
+	}
+	return resp, nil
+}
+
+func anthropicStatusError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body anthropicErrorBody
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+		msg = body.Error.Type + ": " + body.Error.Message
+	}
+	return newStatusError(resp.StatusCode, resp.Header, msg)
+}
+
+// ChatCompletion 聊天补全
+func (a *AnthropicAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, a.buildRequest(messages, options, false))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: err}
+	}
+
+	var content strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: toolArguments(string(block.Input))})
+		}
+	}
+
+	if content.Len() == 0 && len(toolCalls) == 0 {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: fmt.Errorf("stop_reason=%s", result.StopReason)}
+	}
+
+	return &ChatResponse{
+		Content:      content.String(),
+		Usage:        result.Usage.toUsage(),
+		FinishReason: result.StopReason,
+		ToolCalls:    toolCalls,
+	}, nil
+}
+
+// StreamChatCompletion 流式聊天补全
+func (a *AnthropicAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, a.buildRequest(messages, options, true))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 流式请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var fullContent strings.Builder
+	var stopReason string
+	var usage anthropicUsage
+	toolCalls := newToolCallBuilder()
+
+	err = readSSE(resp.Body, func(event string, data []byte) (bool, error) {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return false, fmt.Errorf("解析流式事件失败: %w", err)
+		}
+		if ev.Type == "" {
+			ev.Type = event
+		}
+
+		switch ev.Type {
+		case "message_start":
+			usage = ev.Message.Usage
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				toolCalls.add(ev.Index, ev.ContentBlock.ID, ev.ContentBlock.Name, "")
+			}
+		case "content_block_delta":
+			switch {
+			case ev.Delta.Type == "text_delta" && ev.Delta.Text != "":
+				fullContent.WriteString(ev.Delta.Text)
+				if cbErr := callback(ev.Delta.Text); cbErr != nil {
+					return false, cbErr
+				}
+			case ev.Delta.Type == "input_json_delta":
+				toolCalls.add(ev.Index, "", "", ev.Delta.PartialJSON)
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				stopReason = ev.Delta.StopReason
+			}
+			if ev.Usage.OutputTokens > 0 {
+				usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return true, nil
+		case "error":
+			return false, &APIError{
+				Kind: kindForStreamError(ev.Error.Type),
+				Err:  fmt.Errorf("%s: %s", ev.Error.Type, ev.Error.Message),
+			}
+		}
+		// content_block_stop / ping 无需处理
+		return false, nil
+	})
+	result := &ChatResponse{
+		Content:      fullContent.String(),
+		Usage:        usage.toUsage(),
+		FinishReason: stopReason,
+		ToolCalls:    toolCalls.build(),
+	}
+	if err != nil {
+		return result, fmt.Errorf("流式读取失败: %w", classifyTransportError(err))
+	}
+
+	if fullContent.Len() == 0 && stopReason == AnthropicStopRefusal {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: fmt.Errorf("stop_reason=%s", stopReason)}
+	}
+
+	return result, nil
+}
+
+// Embed Anthropic 不提供向量嵌入接口
+func (a *AnthropicAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	return nil, ErrEmbeddingNotSupported
+}
+
+// ValidateConfig 验证模型配置（发送真实测试请求）
+func (a *AnthropicAdapter) ValidateConfig(apiKey, baseURL string) error {
+	if apiKey == "" {
+		return ErrInvalidAPIKey
+	}
+	if baseURL == "" {
+		baseURL = a.baseURL
+	}
+
+	adapter := newAnthropicAdapter(baseURL, a.model, a.clients)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := adapter.ChatCompletion(ctx, []ChatMessage{
+		{Role: "user", Content: "Hi"},
+	}, ChatOptions{
+		APIKey:      apiKey,
+		Temperature: 0.1,
+		MaxTokens:   5,
+	})
+
+	if err != nil {
+		return &LLMError{Message: "连接验证失败", Err: err}
+	}
+	return nil
+}
+
+// GetDefaultModel 获取默认模型
+func (a *AnthropicAdapter) GetDefaultModel() string {
+	return a.model
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAnthropicTestServer 启动模拟的 Messages API，handler 收到解析后的请求体
+func newAnthropicTestServer(t *testing.T, handler func(w http.ResponseWriter, req anthropicRequest)) *AnthropicAdapter {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("请求路径为 %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != anthropicAPIVersion {
+			t.Errorf("请求头缺少 x-api-key 或 anthropic-version: %v", r.Header)
+		}
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		handler(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return newAnthropicAdapter(srv.URL, "claude-test", defaultHTTPClients())
+}
+
+// writeSSE 按 Anthropic 的格式写出一个事件
+func writeSSE(w http.ResponseWriter, event, data string) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	w.(http.Flusher).Flush()
+}
+
+func TestAnthropicChatCompletion(t *testing.T) {
+	adapter := newAnthropicTestServer(t, func(w http.ResponseWriter, req anthropicRequest) {
+		if req.Model != "claude-test" || req.System != "你是小说作家" || req.MaxTokens != anthropicDefaultMaxTokens || req.Stream {
+			t.Errorf("请求参数不符合预期: %+v", req)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content[0].Text != "写一句开头" {
+			t.Errorf("消息转换不符合预期: %+v", req.Messages)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_1", "type": "message", "role": "assistant",
+			"content": [{"type": "text", "text": "雾气漫过钟楼，"}, {"type": "text", "text": "他推开了表铺的门。"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 12, "output_tokens": 34}
+		}`)
+	})
+
+	resp, err := adapter.ChatCompletion(context.Background(), []ChatMessage{
+		{Role: "system", Content: "你是小说作家"},
+		{Role: "user", Content: "写一句开头"},
+	}, ChatOptions{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if resp.Content != "雾气漫过钟楼，他推开了表铺的门。" || resp.FinishReason != AnthropicStopEndTurn {
+		t.Fatalf("响应不符合预期: %+v", resp)
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 34 || resp.Usage.TotalTokens != 46 {
+		t.Fatalf("用量不符合预期: %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicStreamChatCompletion(t *testing.T) {
+	adapter := newAnthropicTestServer(t, func(w http.ResponseWriter, req anthropicRequest) {
+		if !req.Stream || len(req.Tools) != 1 {
+			t.Errorf("流式请求参数不符合预期: %+v", req)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeSSE(w, "message_start", `{"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":20,"output_tokens":1}}}`)
+		writeSSE(w, "ping", `{"type":"ping"}`)
+		writeSSE(w, "content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`)
+		writeSSE(w, "content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"我查一下"}}`)
+		writeSSE(w, "content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"第三章。"}}`)
+		writeSSE(w, "content_block_stop", `{"type":"content_block_stop","index":0}`)
+		writeSSE(w, "content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_chapter","input":{}}}`)
+		writeSSE(w, "content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"chapter_"}}`)
+		writeSSE(w, "content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"number\": 3}"}}`)
+		writeSSE(w, "content_block_stop", `{"type":"content_block_stop","index":1}`)
+		writeSSE(w, "message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":42}}`)
+		writeSSE(w, "message_stop", `{"type":"message_stop"}`)
+	})
+
+	var chunks []string
+	resp, err := adapter.StreamChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "第三章讲了什么"}}, ChatOptions{
+		APIKey: "test-key",
+		Tools:  []Tool{{Name: "get_chapter", Description: "获取章节"}},
+	}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("流式调用失败: %v", err)
+	}
+	if strings.Join(chunks, "|") != "我查一下|第三章。" || resp.Content != "我查一下第三章。" {
+		t.Fatalf("流式内容不符合预期: chunks=%q content=%q", chunks, resp.Content)
+	}
+	if resp.FinishReason != AnthropicStopToolUse {
+		t.Fatalf("停止原因为 %q", resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "toolu_1" || resp.ToolCalls[0].Name != "get_chapter" {
+		t.Fatalf("工具调用不符合预期: %+v", resp.ToolCalls)
+	}
+	var args struct {
+		ChapterNumber int `json:"chapter_number"`
+	}
+	if err := json.Unmarshal([]byte(resp.ToolCalls[0].Arguments), &args); err != nil || args.ChapterNumber != 3 {
+		t.Fatalf("工具参数拼接错误: %q", resp.ToolCalls[0].Arguments)
+	}
+	// message_delta 中的 output_tokens 为累计值，覆盖 message_start 中的初始值
+	if resp.Usage.PromptTokens != 20 || resp.Usage.CompletionTokens != 42 || resp.Usage.TotalTokens != 62 {
+		t.Fatalf("用量不符合预期: %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicStatusErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     map[string]string
+		body       string
+		kind       *LLMError
+		retryAfter time.Duration
+	}{
+		{
+			name:       "限流",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"Retry-After": "7"},
+			body:       `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`,
+			kind:       ErrRateLimitExceeded,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:   "过载",
+			status: 529,
+			body:   `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			kind:   ErrServerError,
+		},
+		{
+			name:   "密钥无效",
+			status: http.StatusUnauthorized,
+			body:   `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			kind:   ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := newAnthropicTestServer(t, func(w http.ResponseWriter, req anthropicRequest) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			// 非流式与流式请求的错误归类一致
+			_, chatErr := adapter.ChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{APIKey: "test-key"})
+			_, streamErr := adapter.StreamChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{APIKey: "test-key"}, func(string) error { return nil })
+			for _, err := range []error{chatErr, streamErr} {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("应返回 APIError，实际为 %v", err)
+				}
+				if !errors.Is(err, tt.kind) || apiErr.StatusCode != tt.status || apiErr.RetryAfter != tt.retryAfter {
+					t.Fatalf("错误归类不符合预期: kind=%v status=%d retryAfter=%v", apiErr.Kind, apiErr.StatusCode, apiErr.RetryAfter)
+				}
+				if want := tt.kind != ErrInvalidAPIKey; apiErr.Retryable() != want {
+					t.Fatalf("Retryable() = %v", apiErr.Retryable())
+				}
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamErrorEvent(t *testing.T) {
+	adapter := newAnthropicTestServer(t, func(w http.ResponseWriter, req anthropicRequest) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeSSE(w, "message_start", `{"type":"message_start","message":{"usage":{"input_tokens":5,"output_tokens":1}}}`)
+		writeSSE(w, "content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"写到一半"}}`)
+		writeSSE(w, "error", `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	})
+
+	resp, err := adapter.StreamChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{APIKey: "test-key"}, func(string) error { return nil })
+	if !errors.Is(err, ErrServerError) {
+		t.Fatalf("流中途的 overloaded_error 应归类为 ErrServerError，实际为 %v", err)
+	}
+	// 出错时返回已收到的部分内容
+	if resp == nil || resp.Content != "写到一半" {
+		t.Fatalf("应返回已收到的部分内容: %+v", resp)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	// 注释行、多行 data、没有 event 的消息，以及结尾缺少空行的最后一条消息
+	stream := ": keep-alive\n\n" +
+		"event: first\ndata: {\"a\":\ndata: 1}\n\n" +
+		"data: plain\n\n" +
+		"event: last\ndata: tail"
+
+	type message struct{ event, data string }
+	var got []message
+	err := readSSE(strings.NewReader(stream), func(event string, data []byte) (bool, error) {
+		got = append(got, message{event, string(data)})
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	want := []message{{"first", "{\"a\":\n1}"}, {"", "plain"}, {"last", "tail"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// handle 返回 true 后不再读取后续消息
+	calls := 0
+	err = readSSE(strings.NewReader("data: 1\n\ndata: 2\n\n"), func(string, []byte) (bool, error) {
+		calls++
+		return true, nil
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("结束后仍继续读取: calls=%d err=%v", calls, err)
+	}
+}
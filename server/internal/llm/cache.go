@@ -0,0 +1,196 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// CacheStore 响应缓存存储后端
+type CacheStore interface {
+	// Get 读取未过期的缓存，不存在或已过期时 ok 为 false
+	Get(ctx context.Context, key string) (resp *ChatResponse, ok bool, err error)
+	// Set 写入缓存
+	Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass 标记本次请求跳过响应缓存（仍会写入新结果）
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed 请求是否要求跳过缓存
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// ResponseCache 内容寻址的响应缓存，仅缓存温度不高于 MaxTemperature 的非流式调用
+type ResponseCache struct {
+	store          CacheStore
+	ttl            time.Duration
+	maxTemperature float32
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewResponseCache 创建响应缓存
+func NewResponseCache(store CacheStore, ttl time.Duration, maxTemperature float32) *ResponseCache {
+	return &ResponseCache{store: store, ttl: ttl, maxTemperature: maxTemperature}
+}
+
+// Stats 获取命中统计
+func (c *ResponseCache) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// TTL 缓存有效期
+func (c *ResponseCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// cacheable 高温度调用期望每次结果不同，不缓存；带工具的调用结果依赖工具执行，也不缓存
+func (c *ResponseCache) cacheable(options ChatOptions) bool {
+	return !options.NoCache && len(options.Tools) == 0 && options.Temperature <= c.maxTemperature
+}
+
+// requestKey 由模型标识、消息与生成参数计算的内容哈希，不包含 API Key
+func requestKey(scope string, messages []ChatMessage, options ChatOptions) string {
+	payload, _ := json.Marshal(struct {
+		Scope            string          `json:"scope"`
+		Messages         []ChatMessage   `json:"messages"`
+		Temperature      float32         `json:"temperature"`
+		MaxTokens        int             `json:"max_tokens"`
+		Format           *ResponseFormat `json:"format,omitempty"`
+		TopP             float32         `json:"top_p,omitempty"`
+		Stop             []string        `json:"stop,omitempty"`
+		PresencePenalty  float32         `json:"presence_penalty,omitempty"`
+		FrequencyPenalty float32         `json:"frequency_penalty,omitempty"`
+		Tools            []Tool          `json:"tools,omitempty"`
+	}{scope, messages, options.Temperature, options.MaxTokens, options.ResponseFormat,
+		options.TopP, options.Stop, options.PresencePenalty, options.FrequencyPenalty, options.Tools})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheAdapter 在适配器前增加响应缓存，流式调用不经过缓存
+type CacheAdapter struct {
+	LLMAdapter
+	cache *ResponseCache
+	scope string // 提供商、BaseURL 与模型，区分不同上游
+}
+
+// NewCacheAdapter 创建带缓存的适配器
+func NewCacheAdapter(adapter LLMAdapter, cache *ResponseCache, scope string) *CacheAdapter {
+	return &CacheAdapter{LLMAdapter: adapter, cache: cache, scope: scope}
+}
+
+// ChatCompletion 聊天补全，命中缓存时直接返回且不产生用量
+func (a *CacheAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	if !a.cache.cacheable(options) {
+		return a.LLMAdapter.ChatCompletion(ctx, messages, options)
+	}
+
+	key := requestKey(a.scope, messages, options)
+	if !CacheBypassed(ctx) {
+		cached, ok, err := a.cache.store.Get(ctx, key)
+		if err != nil {
+			logger.Warn("读取 LLM 响应缓存失败", zap.Error(err))
+		}
+		if ok {
+			a.cache.hits.Add(1)
+			return &ChatResponse{Content: cached.Content, FinishReason: cached.FinishReason, Cached: true}, nil
+		}
+	}
+	a.cache.misses.Add(1)
+
+	resp, err := a.LLMAdapter.ChatCompletion(ctx, messages, options)
+	if err != nil {
+		return resp, err
+	}
+	if err := a.cache.store.Set(context.WithoutCancel(ctx), key, resp, a.cache.ttl); err != nil {
+		logger.Warn("写入 LLM 响应缓存失败", zap.Error(err))
+	}
+	return resp, nil
+}
+
+// FileCacheStore 本地磁盘缓存，每个条目一个 JSON 文件
+type FileCacheStore struct {
+	dir string
+}
+
+// NewFileCacheStore 创建磁盘缓存
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCacheStore{dir: dir}, nil
+}
+
+type fileCacheEntry struct {
+	ExpiresAt time.Time     `json:"expires_at"`
+	Response  *ChatResponse `json:"response"`
+}
+
+func (s *FileCacheStore) path(key string) string {
+	// 按前两位分目录，避免单目录文件过多
+	return filepath.Join(s.dir, key[:2], key+".json")
+}
+
+// Get 读取缓存，过期条目顺带删除
+func (s *FileCacheStore) Get(ctx context.Context, key string) (*ChatResponse, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		os.Remove(s.path(key))
+		return nil, false, err
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		os.Remove(s.path(key))
+		return nil, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+// Set 写入缓存（先写临时文件再重命名，避免读到半截内容）
+func (s *FileCacheStore) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error {
+	data, err := json.Marshal(fileCacheEntry{ExpiresAt: time.Now().Add(ttl), Response: resp})
+	if err != nil {
+		return err
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+)
+
+// EmbeddingOptions 向量嵌入选项
+type EmbeddingOptions struct {
+	APIKey     string `json:"-"`
+	Dimensions int    `json:"dimensions,omitempty"` // 输出维度，0 表示使用模型默认值；不支持的提供商会忽略
+}
+
+// EmbeddingResponse 向量嵌入结果，Vectors 与输入文本一一对应
+type EmbeddingResponse struct {
+	Vectors   [][]float32 `json:"vectors"`
+	Usage     Usage       `json:"usage"`
+	Synthetic bool        `json:"synthetic,omitempty"` // 由模拟提供商生成
+}
+
+// ErrEmbeddingNotSupported 提供商不支持向量嵌入
+var ErrEmbeddingNotSupported = &LLMError{Message: "该提供商不支持向量嵌入"}
+
+// checkEmbeddings 检查返回的向量数量与输入是否一致
+func checkEmbeddings(vectors [][]float32, inputs int) error {
+	if len(vectors) != inputs {
+		return &LLMError{Message: ErrInvalidResponse.Message}
+	}
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return &LLMError{Message: ErrInvalidResponse.Message}
+		}
+	}
+	return nil
+}
+
+// CosineSimilarity 余弦相似度，维度不同或存在零向量时返回 0
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+// mockEmbeddingDimensions 模拟嵌入的默认维度
+const mockEmbeddingDimensions = 256
+
+// mockEmbedding 按字与相邻两字哈希到固定维度的归一化向量：
+// 不具备语义，但字面相近的文本相似度更高，足以在调试时检验检索流程
+func mockEmbedding(text string, dimensions int) []float32 {
+	vector := make([]float32, dimensions)
+	runes := []rune(strings.ToLower(text))
+	add := func(feature string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		sign := float32(1)
+		if sum&1 == 1 {
+			sign = -1
+		}
+		vector[(sum>>1)%uint32(dimensions)] += sign * weight
+	}
+	for i, r := range runes {
+		if r == ' ' || r == '\n' || r == '\t' {
+			continue
+		}
+		add(string(r), 0.5)
+		if i+1 < len(runes) {
+			add(string(runes[i:i+2]), 1)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
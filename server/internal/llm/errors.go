@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError 上游接口错误，Kind 为归类后的 LLMError 哨兵，可通过 errors.Is 判断
+type APIError struct {
+	Kind       *LLMError
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode > 0 {
+		return fmt.Sprintf("%s (HTTP %d): %v", e.Kind.Message, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Kind.Message, e.Err)
+}
+
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Retryable 是否为可重试的临时性错误
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimitExceeded, ErrServerError, ErrTimeout, ErrNetwork:
+		return true
+	}
+	return false
+}
+
+// isBadRequest 是否为上游返回的 400 参数错误
+func isBadRequest(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest
+}
+
+// kindForStatus 按 HTTP 状态码归类错误
+func kindForStatus(code int) *LLMError {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ErrInvalidAPIKey
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimitExceeded
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case code >= 500:
+		// 包括 Anthropic 的 529 overloaded
+		return ErrServerError
+	default:
+		return ErrRequestFailed
+	}
+}
+
+// newStatusError 根据响应状态码与响应体构造错误
+func newStatusError(code int, header http.Header, msg string) *APIError {
+	return &APIError{
+		Kind:       kindForStatus(code),
+		StatusCode: code,
+		RetryAfter: parseRetryAfter(header),
+		Err:        errors.New(msg),
+	}
+}
+
+// classifyTransportError 归类请求发送阶段的错误（超时、连接失败）
+func classifyTransportError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &APIError{Kind: ErrTimeout, Err: err}
+	}
+	if errors.As(err, &netErr) {
+		return &APIError{Kind: ErrNetwork, Err: err}
+	}
+	return err
+}
+
+// kindForStreamError 归类流式响应中途返回的错误事件类型
+func kindForStreamError(errType string) *LLMError {
+	switch {
+	case strings.Contains(errType, "rate_limit"):
+		return ErrRateLimitExceeded
+	case strings.Contains(errType, "overloaded"), strings.Contains(errType, "api_error"):
+		return ErrServerError
+	case strings.Contains(errType, "authentication"), strings.Contains(errType, "permission"):
+		return ErrInvalidAPIKey
+	default:
+		return ErrRequestFailed
+	}
+}
+
+// parseRetryAfter 解析 Retry-After / retry-after-ms 响应头
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if v := header.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,310 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 录制/回放模式
+const (
+	FixtureModeOff    = ""       // 不录制也不回放
+	FixtureModeRecord = "record" // 调用真实上游并把请求与响应保存为 fixture
+	FixtureModeReplay = "replay" // 从 fixture 返回响应
+)
+
+// ErrFixtureNotFound 严格回放模式下没有与请求匹配的 fixture
+var ErrFixtureNotFound = &LLMError{Message: "未找到匹配的录制数据"}
+
+// fixtureRequest fixture 中记录的请求，仅用于人工查看，匹配依据为文件名中的哈希
+type fixtureRequest struct {
+	Messages         []ChatMessage   `json:"messages"`
+	Temperature      float32         `json:"temperature"`
+	MaxTokens        int             `json:"max_tokens"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	TopP             float32         `json:"top_p,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	PresencePenalty  float32         `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float32         `json:"frequency_penalty,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+}
+
+// Fixture 一次调用的录制数据
+type Fixture struct {
+	Key      string         `json:"key"`
+	Model    string         `json:"model"`
+	Request  fixtureRequest `json:"request"`
+	Response *ChatResponse  `json:"response"`
+}
+
+// EmbeddingFixture 一次向量嵌入调用的录制数据
+type EmbeddingFixture struct {
+	Key        string             `json:"key"`
+	Model      string             `json:"model"`
+	Texts      []string           `json:"texts"`
+	Dimensions int                `json:"dimensions,omitempty"`
+	Response   *EmbeddingResponse `json:"response"`
+}
+
+// FixtureStore fixture 文件目录，文件名为请求哈希
+type FixtureStore struct {
+	dir string
+}
+
+// NewFixtureStore 创建 fixture 目录
+func NewFixtureStore(dir string) *FixtureStore {
+	return &FixtureStore{dir: dir}
+}
+
+// fixtureKey 请求哈希不包含提供商与模型，同一份 fixture 可在不同模型配置下回放
+func fixtureKey(messages []ChatMessage, options ChatOptions) string {
+	return requestKey("", messages, options)
+}
+
+// embeddingFixtureKey 向量嵌入的请求哈希包含模型：不同模型的向量不能混用
+func embeddingFixtureKey(model string, texts []string, options EmbeddingOptions) string {
+	payload, _ := json.Marshal(struct {
+		Model      string   `json:"model"`
+		Texts      []string `json:"texts"`
+		Dimensions int      `json:"dimensions,omitempty"`
+	}{model, texts, options.Dimensions})
+	sum := sha256.Sum256(payload)
+	return "embed-" + hex.EncodeToString(sum[:])
+}
+
+func (s *FixtureStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// load 读取 fixture 文件，不存在时返回 false
+func (s *FixtureStore) load(key string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("解析录制数据 %s 失败: %w", s.path(key), err)
+	}
+	return true, nil
+}
+
+// save 保存 fixture 文件（格式化输出，便于代码评审）
+func (s *FixtureStore) save(key string, v interface{}) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(key), data, 0o644)
+}
+
+// Load 读取 fixture，不存在时返回 nil
+func (s *FixtureStore) Load(key string) (*Fixture, error) {
+	var fixture Fixture
+	if ok, err := s.load(key, &fixture); !ok || err != nil {
+		return nil, err
+	}
+	if fixture.Response == nil {
+		return nil, fmt.Errorf("录制数据 %s 缺少 response", s.path(key))
+	}
+	return &fixture, nil
+}
+
+// Save 保存 fixture
+func (s *FixtureStore) Save(fixture *Fixture) error {
+	return s.save(fixture.Key, fixture)
+}
+
+// LoadEmbedding 读取向量嵌入的 fixture，不存在时返回 nil
+func (s *FixtureStore) LoadEmbedding(key string) (*EmbeddingFixture, error) {
+	var fixture EmbeddingFixture
+	if ok, err := s.load(key, &fixture); !ok || err != nil {
+		return nil, err
+	}
+	if fixture.Response == nil {
+		return nil, fmt.Errorf("录制数据 %s 缺少 response", s.path(key))
+	}
+	return &fixture, nil
+}
+
+// SaveEmbedding 保存向量嵌入的 fixture
+func (s *FixtureStore) SaveEmbedding(fixture *EmbeddingFixture) error {
+	return s.save(fixture.Key, fixture)
+}
+
+// RecordAdapter 调用真实上游，并把成功的请求与响应写入 fixture
+type RecordAdapter struct {
+	LLMAdapter
+	store *FixtureStore
+}
+
+// NewRecordAdapter 创建录制适配器
+func NewRecordAdapter(adapter LLMAdapter, store *FixtureStore) *RecordAdapter {
+	return &RecordAdapter{LLMAdapter: adapter, store: store}
+}
+
+// ChatCompletion 聊天补全并录制
+func (a *RecordAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	resp, err := a.LLMAdapter.ChatCompletion(ctx, messages, options)
+	if err == nil {
+		a.record(messages, options, resp)
+	}
+	return resp, err
+}
+
+// StreamChatCompletion 流式聊天补全并录制完整内容
+func (a *RecordAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	resp, err := a.LLMAdapter.StreamChatCompletion(ctx, messages, options, callback)
+	if err == nil {
+		a.record(messages, options, resp)
+	}
+	return resp, err
+}
+
+// Embed 向量嵌入并录制
+func (a *RecordAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	resp, err := a.LLMAdapter.Embed(ctx, texts, options)
+	if err != nil {
+		return resp, err
+	}
+	model := a.GetDefaultModel()
+	fixture := &EmbeddingFixture{
+		Key:        embeddingFixtureKey(model, texts, options),
+		Model:      model,
+		Texts:      texts,
+		Dimensions: options.Dimensions,
+		Response:   resp,
+	}
+	if err := a.store.SaveEmbedding(fixture); err != nil {
+		logger.Warn("保存 LLM 录制数据失败", zap.String("key", fixture.Key), zap.Error(err))
+	}
+	return resp, nil
+}
+
+func (a *RecordAdapter) record(messages []ChatMessage, options ChatOptions, resp *ChatResponse) {
+	fixture := &Fixture{
+		Key:   fixtureKey(messages, options),
+		Model: a.GetDefaultModel(),
+		Request: fixtureRequest{
+			Messages:         messages,
+			Temperature:      options.Temperature,
+			MaxTokens:        options.MaxTokens,
+			ResponseFormat:   options.ResponseFormat,
+			TopP:             options.TopP,
+			Stop:             options.Stop,
+			PresencePenalty:  options.PresencePenalty,
+			FrequencyPenalty: options.FrequencyPenalty,
+			Tools:            options.Tools,
+		},
+		Response: &ChatResponse{Content: resp.Content, Usage: resp.Usage, FinishReason: resp.FinishReason, ToolCalls: resp.ToolCalls},
+	}
+	if err := a.store.Save(fixture); err != nil {
+		logger.Warn("保存 LLM 录制数据失败", zap.String("key", fixture.Key), zap.Error(err))
+	}
+}
+
+// ReplayAdapter 按请求哈希从 fixture 返回响应；
+// 严格模式下未知请求直接报错，否则交给被包装的适配器处理
+type ReplayAdapter struct {
+	LLMAdapter
+	store  *FixtureStore
+	strict bool
+}
+
+// NewReplayAdapter 创建回放适配器
+func NewReplayAdapter(adapter LLMAdapter, store *FixtureStore, strict bool) *ReplayAdapter {
+	return &ReplayAdapter{LLMAdapter: adapter, store: store, strict: strict}
+}
+
+func (a *ReplayAdapter) lookup(messages []ChatMessage, options ChatOptions) (*Fixture, error) {
+	key := fixtureKey(messages, options)
+	fixture, err := a.store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	if fixture == nil && a.strict {
+		return nil, fmt.Errorf("%w (key=%s)", ErrFixtureNotFound, key)
+	}
+	return fixture, nil
+}
+
+// ChatCompletion 回放聊天补全
+func (a *ReplayAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	fixture, err := a.lookup(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	if fixture == nil {
+		return a.LLMAdapter.ChatCompletion(ctx, messages, options)
+	}
+	resp := *fixture.Response
+	return &resp, nil
+}
+
+// Embed 回放向量嵌入，按文本与模型匹配
+func (a *ReplayAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	key := embeddingFixtureKey(a.GetDefaultModel(), texts, options)
+	fixture, err := a.store.LoadEmbedding(key)
+	if err != nil {
+		return nil, err
+	}
+	if fixture == nil {
+		if a.strict {
+			return nil, fmt.Errorf("%w (key=%s)", ErrFixtureNotFound, key)
+		}
+		return a.LLMAdapter.Embed(ctx, texts, options)
+	}
+	resp := *fixture.Response
+	return &resp, nil
+}
+
+// replayChunkRunes 回放流式响应时每个数据块的字符数
+const replayChunkRunes = 32
+
+// StreamChatCompletion 回放流式聊天补全，内容按固定长度分块推送
+func (a *ReplayAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	fixture, err := a.lookup(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	if fixture == nil {
+		return a.LLMAdapter.StreamChatCompletion(ctx, messages, options, callback)
+	}
+
+	if sent, err := streamChunks(fixture.Response.Content, callback); err != nil {
+		return &ChatResponse{Content: fixture.Response.Content[:sent]}, err
+	}
+	resp := *fixture.Response
+	return &resp, nil
+}
+
+// streamChunks 将完整内容按固定长度分块推送，返回出错前已推送的字节数
+func streamChunks(content string, callback StreamCallback) (int, error) {
+	for sent := 0; sent < len(content); {
+		end := sent
+		for n := 0; n < replayChunkRunes && end < len(content); n++ {
+			_, size := utf8.DecodeRuneInString(content[end:])
+			end += size
+		}
+		if err := callback(content[sent:end]); err != nil {
+			return sent, err
+		}
+		sent = end
+	}
+	return len(content), nil
+}
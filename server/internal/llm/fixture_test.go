@@ -0,0 +1,197 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// stubAdapter 返回固定内容并记录调用次数的适配器
+type stubAdapter struct {
+	content string
+	calls   int
+}
+
+func (a *stubAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	a.calls++
+	return &ChatResponse{Content: a.content, FinishReason: "stop", Usage: Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30}}, nil
+}
+
+func (a *stubAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	a.calls++
+	if err := callback(a.content); err != nil {
+		return &ChatResponse{}, err
+	}
+	return &ChatResponse{Content: a.content, FinishReason: "stop"}, nil
+}
+
+func (a *stubAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	a.calls++
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{float32(i), 1, 0.5}
+	}
+	return &EmbeddingResponse{Vectors: vectors, Usage: Usage{PromptTokens: 5, TotalTokens: 5}}, nil
+}
+
+func (a *stubAdapter) ValidateConfig(apiKey, baseURL string) error { return nil }
+
+func (a *stubAdapter) GetDefaultModel() string { return "stub" }
+
+func TestFixtureRecordReplay(t *testing.T) {
+	store := NewFixtureStore(t.TempDir())
+	messages := []ChatMessage{{Role: "user", Content: "写一段开头"}}
+	options := ChatOptions{Temperature: 0.7, MaxTokens: 100}
+
+	upstream := &stubAdapter{content: "清晨的薄雾还没有散去。"}
+	recorder := NewRecordAdapter(upstream, store)
+	recorded, err := recorder.ChatCompletion(context.Background(), messages, options)
+	if err != nil {
+		t.Fatalf("录制失败: %v", err)
+	}
+
+	// 回放时不应再访问上游
+	offline := &stubAdapter{content: "不应被调用"}
+	replayer := NewReplayAdapter(offline, store, true)
+	replayed, err := replayer.ChatCompletion(context.Background(), messages, options)
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if offline.calls != 0 {
+		t.Fatalf("回放访问了上游 %d 次", offline.calls)
+	}
+	if replayed.Content != recorded.Content || replayed.Usage != recorded.Usage || replayed.FinishReason != recorded.FinishReason {
+		t.Fatalf("回放结果与录制不一致: got %+v, want %+v", replayed, recorded)
+	}
+
+	// 流式回放与非流式共用同一份录制数据
+	var streamed strings.Builder
+	resp, err := replayer.StreamChatCompletion(context.Background(), messages, options, func(chunk string) error {
+		streamed.WriteString(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("流式回放失败: %v", err)
+	}
+	if streamed.String() != recorded.Content || resp.Content != recorded.Content {
+		t.Fatalf("流式回放内容不一致: %q", streamed.String())
+	}
+}
+
+func TestFixtureReplayStrictNotFound(t *testing.T) {
+	store := NewFixtureStore(t.TempDir())
+	messages := []ChatMessage{{Role: "user", Content: "没有录制过的请求"}}
+	upstream := &stubAdapter{content: "上游内容"}
+
+	strict := NewReplayAdapter(upstream, store, true)
+	if _, err := strict.ChatCompletion(context.Background(), messages, ChatOptions{}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("严格模式应返回 ErrFixtureNotFound，实际为 %v", err)
+	}
+	if _, err := strict.StreamChatCompletion(context.Background(), messages, ChatOptions{}, func(string) error { return nil }); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("严格模式的流式调用应返回 ErrFixtureNotFound，实际为 %v", err)
+	}
+	if upstream.calls != 0 {
+		t.Fatalf("严格模式访问了上游 %d 次", upstream.calls)
+	}
+
+	// 非严格模式交给被包装的适配器
+	lenient := NewReplayAdapter(upstream, store, false)
+	resp, err := lenient.ChatCompletion(context.Background(), messages, ChatOptions{})
+	if err != nil || resp.Content != upstream.content || upstream.calls != 1 {
+		t.Fatalf("非严格模式应调用上游: resp=%v err=%v calls=%d", resp, err, upstream.calls)
+	}
+}
+
+func TestStreamChunksMultiByte(t *testing.T) {
+	// 中文与 emoji 混合，长度不是分块大小的整数倍
+	content := strings.Repeat("薄雾散去，灯火渐明。🌙", 9)
+
+	var chunks []string
+	sent, err := streamChunks(content, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil || sent != len(content) {
+		t.Fatalf("streamChunks 返回 sent=%d err=%v", sent, err)
+	}
+	if got := strings.Join(chunks, ""); got != content {
+		t.Fatalf("分块拼接结果不一致")
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Fatalf("第 %d 块不是有效的 UTF-8: %q", i, chunk)
+		}
+		if n := utf8.RuneCountInString(chunk); n > replayChunkRunes || (i < len(chunks)-1 && n != replayChunkRunes) {
+			t.Fatalf("第 %d 块字符数为 %d", i, n)
+		}
+	}
+
+	// 回调出错时返回已推送的字节数，且停在字符边界上
+	stop := errors.New("客户端断开")
+	calls := 0
+	sent, err = streamChunks(content, func(chunk string) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("应返回回调的错误，实际为 %v", err)
+	}
+	if want := len(string([]rune(content)[:2*replayChunkRunes])); sent != want {
+		t.Fatalf("已推送 %d 字节，期望 %d", sent, want)
+	}
+	if !utf8.ValidString(content[:sent]) {
+		t.Fatalf("已推送内容不在字符边界上")
+	}
+}
+
+func TestFixtureEmbedRecordReplay(t *testing.T) {
+	store := NewFixtureStore(t.TempDir())
+	texts := []string{"雾城的钟楼", "修表匠的工作台"}
+
+	upstream := &stubAdapter{}
+	recorded, err := NewRecordAdapter(upstream, store).Embed(context.Background(), texts, EmbeddingOptions{})
+	if err != nil {
+		t.Fatalf("录制失败: %v", err)
+	}
+
+	offline := &stubAdapter{}
+	replayer := NewReplayAdapter(offline, store, true)
+	replayed, err := replayer.Embed(context.Background(), texts, EmbeddingOptions{})
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if offline.calls != 0 {
+		t.Fatalf("回放访问了上游 %d 次", offline.calls)
+	}
+	if len(replayed.Vectors) != len(recorded.Vectors) || replayed.Vectors[1][0] != recorded.Vectors[1][0] || replayed.Usage != recorded.Usage {
+		t.Fatalf("回放结果与录制不一致: got %+v, want %+v", replayed, recorded)
+	}
+
+	// 文本、维度或模型不同都不匹配
+	if _, err := replayer.Embed(context.Background(), texts[:1], EmbeddingOptions{}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("文本不同时应返回 ErrFixtureNotFound，实际为 %v", err)
+	}
+	if _, err := replayer.Embed(context.Background(), texts, EmbeddingOptions{Dimensions: 64}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("维度不同时应返回 ErrFixtureNotFound，实际为 %v", err)
+	}
+	otherModel := NewReplayAdapter(&modelStub{stubAdapter: offline, model: "other"}, store, true)
+	if _, err := otherModel.Embed(context.Background(), texts, EmbeddingOptions{}); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("模型不同时应返回 ErrFixtureNotFound，实际为 %v", err)
+	}
+	if offline.calls != 0 {
+		t.Fatalf("严格模式访问了上游 %d 次", offline.calls)
+	}
+}
+
+// modelStub 使用其他模型名的 stubAdapter
+type modelStub struct {
+	*stubAdapter
+	model string
+}
+
+func (a *modelStub) GetDefaultModel() string { return a.model }
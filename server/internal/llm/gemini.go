@@ -0,0 +1,356 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	geminiDefaultModel   = "gemini-2.0-flash"
+)
+
+// GeminiAdapter Google Gemini generateContent API 适配器
+type GeminiAdapter struct {
+	baseURL string
+	model   string
+	clients *HTTPClients
+}
+
+// NewGeminiAdapter 创建 Gemini 适配器（使用进程级共享的 HTTP 客户端）
+func NewGeminiAdapter(baseURL, model string) *GeminiAdapter {
+	return newGeminiAdapter(baseURL, model, defaultHTTPClients())
+}
+
+func newGeminiAdapter(baseURL, model string, clients *HTTPClients) *GeminiAdapter {
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+	if model == "" {
+		model = geminiDefaultModel
+	}
+	return &GeminiAdapter{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   strings.TrimPrefix(model, "models/"),
+		clients: clients,
+	}
+}
+
+type geminiPart struct {
+	Text string `json:"text,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"topP,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float32 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequencyPenalty,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (r *geminiResponse) usage() Usage {
+	usage := Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+	usage.normalize()
+	return usage
+}
+
+func (r *geminiResponse) finishReason() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	return r.Candidates[0].FinishReason
+}
+
+type geminiErrorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// text 拼接第一个候选结果的文本
+func (r *geminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range r.Candidates[0].Content.Parts {
+		sb.WriteString(p.Text)
+	}
+	return sb.String()
+}
+
+// toGeminiContents 拆分出 systemInstruction，assistant 角色映射为 model
+func toGeminiContents(messages []ChatMessage) (*geminiContent, []geminiContent) {
+	var system []geminiPart
+	out := make([]geminiContent, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, geminiPart{Text: m.Content})
+			continue
+		}
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Parts = append(out[n-1].Parts, geminiPart{Text: m.Content})
+			continue
+		}
+		out = append(out, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+	if len(system) == 0 {
+		return nil, out
+	}
+	return &geminiContent{Parts: system}, out
+}
+
+// buildRequest 不支持工具调用，options.Tools 被忽略，历史中的工具调用按文本发送
+func (a *GeminiAdapter) buildRequest(messages []ChatMessage, options ChatOptions) geminiRequest {
+	system, contents := toGeminiContents(flattenToolMessages(messages))
+	req := geminiRequest{
+		SystemInstruction: system,
+		Contents:          contents,
+	}
+	if options.hasSamplingParams() || options.ResponseFormat != nil {
+		req.GenerationConfig = &geminiGenerationConfig{
+			MaxOutputTokens:  options.MaxTokens,
+			StopSequences:    options.Stop,
+			Temperature:      optionalFloat(options.Temperature),
+			TopP:             optionalFloat(options.TopP),
+			PresencePenalty:  optionalFloat(options.PresencePenalty),
+			FrequencyPenalty: optionalFloat(options.FrequencyPenalty),
+		}
+	}
+	// responseSchema 只接受 OpenAPI 子集，Schema 以系统指令形式提供
+	if f := options.ResponseFormat; f != nil {
+		req.GenerationConfig.ResponseMimeType = "application/json"
+		if req.SystemInstruction == nil {
+			req.SystemInstruction = &geminiContent{}
+		}
+		req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: f.Instruction()})
+	}
+	return req
+}
+
+func (a *GeminiAdapter) do(ctx context.Context, apiKey, method string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/models/%s:%s", a.baseURL, a.model, method)
+	if method == "streamGenerateContent" {
+		url += "?alt=sse"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", apiKey)
+
+	resp, err := a.clients.get(method == "streamGenerateContent").Do(httpReq)
+	if err != nil {
+		return nil, classifyTransportError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, geminiStatusError(resp)
+	}
+	return resp, nil
+}
+
+func geminiStatusError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body geminiErrorBody
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+		msg = body.Error.Status + ": " + body.Error.Message
+	}
+	return newStatusError(resp.StatusCode, resp.Header, msg)
+}
+
+// ChatCompletion 聊天补全
+func (a *GeminiAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, "generateContent", a.buildRequest(messages, options))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: err}
+	}
+
+	content := result.text()
+	if content == "" {
+		reason := result.PromptFeedback.BlockReason
+		if reason == "" {
+			reason = result.finishReason()
+		}
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: fmt.Errorf("finish_reason=%s", reason)}
+	}
+
+	return &ChatResponse{
+		Content:      content,
+		Usage:        result.usage(),
+		FinishReason: result.finishReason(),
+	}, nil
+}
+
+// StreamChatCompletion 流式聊天补全
+func (a *GeminiAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, "streamGenerateContent", a.buildRequest(messages, options))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 流式请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var fullContent strings.Builder
+	result := &ChatResponse{}
+
+	err = readSSE(resp.Body, func(_ string, data []byte) (bool, error) {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return false, fmt.Errorf("解析流式事件失败: %w", err)
+		}
+		// usageMetadata 为累计值，以最后一次为准
+		if u := chunk.usage(); !u.IsZero() {
+			result.Usage = u
+		}
+		if reason := chunk.finishReason(); reason != "" {
+			result.FinishReason = reason
+		}
+		if text := chunk.text(); text != "" {
+			fullContent.WriteString(text)
+			if cbErr := callback(text); cbErr != nil {
+				return false, cbErr
+			}
+		}
+		return false, nil
+	})
+	result.Content = fullContent.String()
+	if err != nil {
+		return result, fmt.Errorf("流式读取失败: %w", classifyTransportError(err))
+	}
+
+	return result, nil
+}
+
+type geminiEmbedRequest struct {
+	Model                string        `json:"model"`
+	Content              geminiContent `json:"content"`
+	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
+}
+
+type geminiBatchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// Embed 调用 batchEmbedContents 计算向量嵌入，上游不返回用量
+func (a *GeminiAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	requests := make([]geminiEmbedRequest, len(texts))
+	for i, text := range texts {
+		requests[i] = geminiEmbedRequest{
+			Model:                "models/" + a.model,
+			Content:              geminiContent{Parts: []geminiPart{{Text: text}}},
+			OutputDimensionality: options.Dimensions,
+		}
+	}
+
+	resp, err := a.do(ctx, options.APIKey, "batchEmbedContents", map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("向量嵌入请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result geminiBatchEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: err}
+	}
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, e := range result.Embeddings {
+		vectors[i] = e.Values
+	}
+	if err := checkEmbeddings(vectors, len(texts)); err != nil {
+		return nil, err
+	}
+	return &EmbeddingResponse{Vectors: vectors}, nil
+}
+
+// ValidateConfig 验证模型配置（发送真实测试请求）
+func (a *GeminiAdapter) ValidateConfig(apiKey, baseURL string) error {
+	if apiKey == "" {
+		return ErrInvalidAPIKey
+	}
+	if baseURL == "" {
+		baseURL = a.baseURL
+	}
+
+	adapter := newGeminiAdapter(baseURL, a.model, a.clients)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	_, err := adapter.ChatCompletion(ctx, []ChatMessage{
+		{Role: "user", Content: "Hi"},
+	}, ChatOptions{
+		APIKey:      apiKey,
+		Temperature: 0.1,
+		MaxTokens:   5,
+	})
+
+	if err != nil {
+		return &LLMError{Message: "连接验证失败", Err: err}
+	}
+	return nil
+}
+
+// GetDefaultModel 获取默认模型
+func (a *GeminiAdapter) GetDefaultModel() string {
+	return a.model
+}
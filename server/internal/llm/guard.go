@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// GuardPolicy 单个模型配置的并发与熔断策略
+type GuardPolicy struct {
+	MaxConcurrent    int           // 最大并发请求数，<=0 表示不限
+	QueueTimeout     time.Duration // 等待并发名额的最长时间，<=0 表示一直等到请求取消
+	FailureThreshold int           // 连续失败多少次后熔断，<=0 表示不熔断
+	OpenDuration     time.Duration // 熔断持续时间，之后放行一个探测请求
+}
+
+// DefaultGuardPolicy 默认并发与熔断策略
+var DefaultGuardPolicy = GuardPolicy{
+	MaxConcurrent:    4,
+	QueueTimeout:     60 * time.Second,
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常
+	BreakerOpen     BreakerState = "open"      // 熔断中，请求直接失败
+	BreakerHalfOpen BreakerState = "half_open" // 放行一个探测请求
+)
+
+var (
+	ErrCircuitOpen  = &LLMError{Message: "模型服务暂不可用（已熔断）"}
+	ErrQueueTimeout = &LLMError{Message: "等待模型并发名额超时"}
+)
+
+// GuardStatus 模型配置的并发与熔断状态
+type GuardStatus struct {
+	State               BreakerState `json:"state"`
+	InFlight            int          `json:"in_flight"`
+	MaxConcurrent       int          `json:"max_concurrent"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	RetryAt             *time.Time   `json:"retry_at,omitempty"` // 熔断结束、允许探测的时间
+}
+
+// Guard 并发信号量 + 熔断器，每个模型配置一个
+type Guard struct {
+	policy GuardPolicy
+	sem    chan struct{}
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	lastErr  string
+	openedAt time.Time
+	probing  bool
+	inFlight int
+}
+
+// NewGuard 创建并发与熔断控制
+func NewGuard(policy GuardPolicy) *Guard {
+	g := &Guard{policy: policy, state: BreakerClosed}
+	if policy.MaxConcurrent > 0 {
+		g.sem = make(chan struct{}, policy.MaxConcurrent)
+	}
+	return g
+}
+
+// Status 获取当前状态
+func (g *Guard) Status() GuardStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := GuardStatus{
+		State:               g.currentState(time.Now()),
+		InFlight:            g.inFlight,
+		MaxConcurrent:       g.policy.MaxConcurrent,
+		ConsecutiveFailures: g.failures,
+		LastError:           g.lastErr,
+	}
+	if g.state != BreakerClosed {
+		openedAt := g.openedAt
+		retryAt := openedAt.Add(g.policy.OpenDuration)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// currentState 熔断时间已过的 open 状态视为 half_open
+func (g *Guard) currentState(now time.Time) BreakerState {
+	if g.state == BreakerOpen && !now.Before(g.openedAt.Add(g.policy.OpenDuration)) {
+		return BreakerHalfOpen
+	}
+	return g.state
+}
+
+// acquire 检查熔断状态并获取并发名额，返回的 release 必须调用
+func (g *Guard) acquire(ctx context.Context) (release func(err error), err error) {
+	probe, err := g.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.sem != nil {
+		var timeout <-chan time.Time
+		if g.policy.QueueTimeout > 0 {
+			timer := time.NewTimer(g.policy.QueueTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case g.sem <- struct{}{}:
+		case <-ctx.Done():
+			g.cancelProbe(probe)
+			return nil, ctx.Err()
+		case <-timeout:
+			g.cancelProbe(probe)
+			return nil, ErrQueueTimeout
+		}
+	}
+
+	g.mu.Lock()
+	g.inFlight++
+	g.mu.Unlock()
+
+	return func(err error) {
+		if g.sem != nil {
+			<-g.sem
+		}
+		g.done(probe, err)
+	}, nil
+}
+
+// allow 熔断中直接拒绝；熔断时间已过时只放行一个探测请求
+func (g *Guard) allow() (probe bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.currentState(time.Now()) {
+	case BreakerOpen:
+		return false, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if g.probing {
+			return false, ErrCircuitOpen
+		}
+		g.state = BreakerHalfOpen
+		g.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+func (g *Guard) cancelProbe(probe bool) {
+	if !probe {
+		return
+	}
+	g.mu.Lock()
+	g.probing = false
+	g.mu.Unlock()
+}
+
+// done 记录调用结果：上游故障累计失败次数，其他结果（含参数错误）视为上游可用
+func (g *Guard) done(probe bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.inFlight--
+	if probe {
+		g.probing = false
+	}
+
+	if !isUpstreamFailure(err) {
+		// 调用方取消不能说明上游状态，探测请求被取消时保持 half_open
+		if err != nil && errors.Is(err, context.Canceled) {
+			return
+		}
+		if g.state != BreakerClosed {
+			logger.Info("模型服务恢复，关闭熔断")
+		}
+		g.state = BreakerClosed
+		g.failures = 0
+		g.lastErr = ""
+		return
+	}
+
+	g.failures++
+	g.lastErr = err.Error()
+	if g.policy.FailureThreshold <= 0 {
+		return
+	}
+	if probe || g.failures >= g.policy.FailureThreshold {
+		if g.state != BreakerOpen {
+			logger.Warn("模型服务连续失败，开启熔断",
+				zap.Int("failures", g.failures),
+				zap.Duration("open_duration", g.policy.OpenDuration),
+				zap.Error(err),
+			)
+		}
+		g.state = BreakerOpen
+		g.openedAt = time.Now()
+	}
+}
+
+// isUpstreamFailure 是否为说明上游不可用的错误（服务端错误、超时、网络故障）
+func isUpstreamFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrServerError) || errors.Is(err, ErrTimeout) ||
+		errors.Is(err, ErrNetwork) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// GuardAdapter 在适配器前增加并发限制与熔断
+type GuardAdapter struct {
+	LLMAdapter
+	guard *Guard
+}
+
+// NewGuardAdapter 创建带并发限制与熔断的适配器
+func NewGuardAdapter(adapter LLMAdapter, guard *Guard) *GuardAdapter {
+	return &GuardAdapter{LLMAdapter: adapter, guard: guard}
+}
+
+// ChatCompletion 聊天补全
+func (a *GuardAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	release, err := a.guard.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.LLMAdapter.ChatCompletion(ctx, messages, options)
+	release(err)
+	return resp, err
+}
+
+// StreamChatCompletion 流式聊天补全，名额在整个流结束后释放
+func (a *GuardAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	release, err := a.guard.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.LLMAdapter.StreamChatCompletion(ctx, messages, options, callback)
+	release(err)
+	return resp, err
+}
+
+// Embed 向量嵌入
+func (a *GuardAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	release, err := a.guard.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.LLMAdapter.Embed(ctx, texts, options)
+	release(err)
+	return resp, err
+}
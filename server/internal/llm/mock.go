@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// MockProvider 模拟提供商名称，需要用户显式配置为模型后才会被使用
+const MockProvider = "mock"
+
+const (
+	mockDefaultModel = "mock"
+	// mockMaxRunes 文本模拟输出的字数上限
+	mockMaxRunes = 1200
+)
+
+// mockHeader 模拟输出的开头声明，避免被误认为真实生成结果
+const mockHeader = "【模拟输出】本内容由模拟提供商生成，仅用于调试界面与流程，并非真实模型输出。"
+
+var mockParagraphs = []string{
+	"清晨的薄雾还没有散去，李明站在老城区的巷口，望着远处逐渐亮起的灯火，心里反复琢磨着昨夜听到的那句话。",
+	"他知道，一旦迈出这一步，就再也没有回头的余地。可那些被隐瞒的真相，像一根细刺，始终扎在心底。",
+	"街角的茶馆里，说书人正讲到紧要处，满座的听客屏住呼吸。李明在门口驻足片刻，终于推门走了进去。",
+	"角落里的老人抬起头，浑浊的眼睛里闪过一丝光亮：“你来了。我等这一天，已经等了整整二十年。”",
+}
+
+// MockAdapter 模拟适配器：不访问网络，返回带声明的固定文本；
+// 要求结构化输出时按 Schema 生成示例 JSON。所有响应都带 Synthetic 标记
+type MockAdapter struct {
+	model string
+}
+
+// NewMockAdapter 创建模拟适配器
+func NewMockAdapter(model string) *MockAdapter {
+	if model == "" {
+		model = mockDefaultModel
+	}
+	return &MockAdapter{model: model}
+}
+
+// ChatCompletion 返回模拟内容
+func (a *MockAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content := a.content(options)
+	return &ChatResponse{Content: content, FinishReason: "stop", Synthetic: true}, nil
+}
+
+// StreamChatCompletion 分块推送模拟内容
+func (a *MockAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content := a.content(options)
+	if sent, err := streamChunks(content, callback); err != nil {
+		return &ChatResponse{Content: content[:sent], Synthetic: true}, err
+	}
+	return &ChatResponse{Content: content, FinishReason: "stop", Synthetic: true}, nil
+}
+
+// Embed 返回按字面特征哈希得到的模拟向量
+func (a *MockAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dimensions := options.Dimensions
+	if dimensions <= 0 {
+		dimensions = mockEmbeddingDimensions
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = mockEmbedding(text, dimensions)
+	}
+	return &EmbeddingResponse{Vectors: vectors, Synthetic: true}, nil
+}
+
+// ValidateConfig 模拟提供商无需验证
+func (a *MockAdapter) ValidateConfig(apiKey, baseURL string) error {
+	return nil
+}
+
+// GetDefaultModel 获取默认模型
+func (a *MockAdapter) GetDefaultModel() string {
+	return a.model
+}
+
+func (a *MockAdapter) content(options ChatOptions) string {
+	if f := options.ResponseFormat; f != nil {
+		if f.Type == ResponseFormatJSONSchema && f.Schema != nil {
+			data, _ := json.Marshal(f.Schema.Sample())
+			return string(data)
+		}
+		return "{}"
+	}
+
+	limit := mockMaxRunes
+	if options.MaxTokens > 0 && options.MaxTokens < limit {
+		limit = options.MaxTokens
+	}
+	var b strings.Builder
+	b.WriteString(mockHeader)
+	for i := 0; utf8.RuneCountInString(b.String()) < limit; i++ {
+		b.WriteString("\n\n")
+		b.WriteString(mockParagraphs[i%len(mockParagraphs)])
+	}
+	return b.String()
+}
+
+type syntheticKey struct{}
+
+// syntheticMark 记录作用域内是否有模拟输出，标记会同时传递给外层作用域
+type syntheticMark struct {
+	set    atomic.Bool
+	parent *syntheticMark
+}
+
+// TrackSynthetic 开启一个记录模拟输出的作用域
+func TrackSynthetic(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(syntheticKey{}).(*syntheticMark)
+	return context.WithValue(ctx, syntheticKey{}, &syntheticMark{parent: parent})
+}
+
+// MarkSynthetic 标记当前作用域（及其外层）使用了模拟输出
+func MarkSynthetic(ctx context.Context) {
+	mark, _ := ctx.Value(syntheticKey{}).(*syntheticMark)
+	for ; mark != nil; mark = mark.parent {
+		mark.set.Store(true)
+	}
+}
+
+// IsSynthetic 当前作用域内是否有模拟输出
+func IsSynthetic(ctx context.Context) bool {
+	mark, _ := ctx.Value(syntheticKey{}).(*syntheticMark)
+	return mark != nil && mark.set.Load()
+}
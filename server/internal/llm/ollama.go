@@ -0,0 +1,298 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ollamaDefaultBaseURL = "http://localhost:11434"
+	ollamaDefaultModel   = "qwen2.5:7b"
+)
+
+// OllamaAdapter Ollama 原生 /api/chat 适配器，用于本地模型
+type OllamaAdapter struct {
+	baseURL string
+	model   string
+	clients *HTTPClients
+}
+
+// NewOllamaAdapter 创建 Ollama 适配器（使用进程级共享的 HTTP 客户端）
+func NewOllamaAdapter(baseURL, model string) *OllamaAdapter {
+	return newOllamaAdapter(baseURL, model, defaultHTTPClients())
+}
+
+func newOllamaAdapter(baseURL, model string, clients *HTTPClients) *OllamaAdapter {
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+	if model == "" {
+		model = ollamaDefaultModel
+	}
+	// 兼容填写了 OpenAI 兼容地址（.../v1）的情况
+	baseURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
+	return &OllamaAdapter{
+		baseURL: baseURL,
+		model:   model,
+		// 本地模型首次加载较慢，超时时间放宽
+		clients: clients.withMinTimeout(ollamaMinTimeout),
+	}
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	NumPredict       int      `json:"num_predict,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"` // "json" 或 JSON Schema
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model      string        `json:"model"`
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error"`
+
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (r *ollamaResponse) usage() Usage {
+	usage := Usage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+	usage.normalize()
+	return usage
+}
+
+// buildRequest 不支持工具调用，options.Tools 被忽略，历史中的工具调用按文本发送
+func (a *OllamaAdapter) buildRequest(messages []ChatMessage, options ChatOptions, stream bool) ollamaRequest {
+	messages = flattenToolMessages(messages)
+	msgs := make([]ollamaMessage, len(messages))
+	for i, m := range messages {
+		msgs[i] = ollamaMessage{Role: m.Role, Content: m.Content}
+	}
+	req := ollamaRequest{
+		Model:    a.model,
+		Messages: msgs,
+		Stream:   stream,
+	}
+	if f := options.ResponseFormat; f != nil {
+		req.Format = json.RawMessage(`"json"`)
+		if f.Type == ResponseFormatJSONSchema && f.Schema != nil {
+			if schema, err := json.Marshal(f.Schema); err == nil {
+				req.Format = schema
+			}
+		}
+	}
+	if options.hasSamplingParams() {
+		req.Options = &ollamaOptions{
+			NumPredict:       options.MaxTokens,
+			Stop:             options.Stop,
+			Temperature:      optionalFloat(options.Temperature),
+			TopP:             optionalFloat(options.TopP),
+			PresencePenalty:  optionalFloat(options.PresencePenalty),
+			FrequencyPenalty: optionalFloat(options.FrequencyPenalty),
+		}
+	}
+	return req
+}
+
+func (a *OllamaAdapter) do(ctx context.Context, apiKey string, body ollamaRequest) (*http.Response, error) {
+	return a.post(ctx, apiKey, "/api/chat", body, body.Stream)
+}
+
+func (a *OllamaAdapter) post(ctx context.Context, apiKey, path string, body interface{}, stream bool) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// 本地 Ollama 无需鉴权，经反向代理暴露时可能需要 Bearer Token
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := a.clients.get(stream).Do(httpReq)
+	if err != nil {
+		return nil, classifyTransportError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var body ollamaResponse
+		msg := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+			msg = body.Error
+		}
+		return nil, newStatusError(resp.StatusCode, resp.Header, msg)
+	}
+	return resp, nil
+}
+
+// ChatCompletion 聊天补全
+func (a *OllamaAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, a.buildRequest(messages, options, false))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: err}
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("LLM 请求失败: %s", result.Error)
+	}
+	if result.Message.Content == "" {
+		return nil, ErrInvalidResponse
+	}
+
+	return &ChatResponse{
+		Content:      result.Message.Content,
+		Usage:        result.usage(),
+		FinishReason: result.DoneReason,
+	}, nil
+}
+
+// StreamChatCompletion 流式聊天补全（Ollama 以 NDJSON 逐行返回）
+func (a *OllamaAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	resp, err := a.do(ctx, options.APIKey, a.buildRequest(messages, options, true))
+	if err != nil {
+		return nil, fmt.Errorf("LLM 流式请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var fullContent strings.Builder
+	result := &ChatResponse{}
+	partial := func() *ChatResponse {
+		result.Content = fullContent.String()
+		return result
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return partial(), fmt.Errorf("流式读取失败: %w", err)
+		}
+		if chunk.Error != "" {
+			return partial(), fmt.Errorf("流式读取失败: %s", chunk.Error)
+		}
+
+		if content := chunk.Message.Content; content != "" {
+			fullContent.WriteString(content)
+			if cbErr := callback(content); cbErr != nil {
+				return partial(), cbErr
+			}
+		}
+		if chunk.Done {
+			// 最后一行携带用量统计
+			result.Usage = chunk.usage()
+			result.FinishReason = chunk.DoneReason
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return partial(), fmt.Errorf("流式读取失败: %w", classifyTransportError(err))
+	}
+
+	return partial(), nil
+}
+
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// Embed 调用 /api/embed 计算向量嵌入，需要使用嵌入模型（如 bge-m3、nomic-embed-text）
+func (a *OllamaAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	resp, err := a.post(ctx, options.APIKey, "/api/embed", ollamaEmbedRequest{
+		Model:      a.model,
+		Input:      texts,
+		Dimensions: options.Dimensions,
+	}, false)
+	if err != nil {
+		return nil, fmt.Errorf("向量嵌入请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &LLMError{Message: ErrInvalidResponse.Message, Err: err}
+	}
+	if err := checkEmbeddings(result.Embeddings, len(texts)); err != nil {
+		return nil, err
+	}
+	usage := Usage{PromptTokens: result.PromptEvalCount}
+	usage.normalize()
+	return &EmbeddingResponse{Vectors: result.Embeddings, Usage: usage}, nil
+}
+
+// ValidateConfig 验证模型配置（发送真实测试请求，API Key 可为空）
+func (a *OllamaAdapter) ValidateConfig(apiKey, baseURL string) error {
+	if baseURL == "" {
+		baseURL = a.baseURL
+	}
+
+	adapter := newOllamaAdapter(baseURL, a.model, a.clients)
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	_, err := adapter.ChatCompletion(ctx, []ChatMessage{
+		{Role: "user", Content: "Hi"},
+	}, ChatOptions{
+		APIKey:      apiKey,
+		Temperature: 0.1,
+		MaxTokens:   5,
+	})
+
+	if err != nil {
+		return &LLMError{Message: "连接验证失败", Err: err}
+	}
+	return nil
+}
+
+// GetDefaultModel 获取默认模型
+func (a *OllamaAdapter) GetDefaultModel() string {
+	return a.model
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -16,79 +17,234 @@ import (
 type OpenAIAdapter struct {
 	baseURL string
 	model   string
+	clients *HTTPClients
 }
 
-// NewOpenAIAdapter 创建 OpenAI 适配器
+// NewOpenAIAdapter 创建 OpenAI 适配器（使用进程级共享的 HTTP 客户端）
 func NewOpenAIAdapter(baseURL, model string) *OpenAIAdapter {
+	return newOpenAIAdapter(baseURL, model, defaultHTTPClients())
+}
+
+func newOpenAIAdapter(baseURL, model string, clients *HTTPClients) *OpenAIAdapter {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
+	if model == "" {
+		model = "gpt-3.5-turbo"
+	}
 	return &OpenAIAdapter{
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		model:   model,
+		clients: clients,
+	}
+}
+
+// headerRecorder 记录最近一次错误响应的 Retry-After，go-openai 的错误类型不携带响应头
+type headerRecorder struct {
+	client     *http.Client
+	retryAfter time.Duration
+}
+
+func (h *headerRecorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := h.client.Do(req)
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		h.retryAfter = parseRetryAfter(resp.Header)
 	}
+	return resp, err
 }
 
-func (a *OpenAIAdapter) newClient(apiKey string) *openai.Client {
+// newClient 每次调用创建轻量的 openai.Client（API Key 随请求变化），底层连接由共享 Transport 复用
+func (a *OpenAIAdapter) newClient(apiKey string, stream bool) (*openai.Client, *headerRecorder) {
+	recorder := &headerRecorder{client: a.clients.get(stream)}
 	cfg := openai.DefaultConfig(apiKey)
 	cfg.BaseURL = a.baseURL
-	cfg.HTTPClient = &http.Client{Timeout: 120 * time.Second}
-	return openai.NewClientWithConfig(cfg)
+	cfg.HTTPClient = recorder
+	return openai.NewClientWithConfig(cfg), recorder
+}
+
+// classifyOpenAIError 将 go-openai 返回的错误归类为 APIError
+func classifyOpenAIError(err error, recorder *headerRecorder) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		e := newStatusError(apiErr.HTTPStatusCode, nil, apiErr.Message)
+		e.RetryAfter = recorder.retryAfter
+		return e
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		e := newStatusError(reqErr.HTTPStatusCode, nil, strings.TrimSpace(string(reqErr.Body)))
+		e.RetryAfter = recorder.retryAfter
+		return e
+	}
+	return classifyTransportError(err)
 }
 
 func toOpenAIMessages(messages []ChatMessage) []openai.ChatCompletionMessage {
 	out := make([]openai.ChatCompletionMessage, len(messages))
 	for i, m := range messages {
 		out[i] = openai.ChatCompletionMessage{
-			Role:    m.Role,
-			Content: m.Content,
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+		}
+		for _, call := range m.ToolCalls {
+			out[i].ToolCalls = append(out[i].ToolCalls, openai.ToolCall{
+				ID:       call.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: call.Name, Arguments: toolArguments(call.Arguments)},
+			})
 		}
 	}
 	return out
 }
 
+func toOpenAITools(tools []Tool) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]openai.Tool, len(tools))
+	for i, t := range tools {
+		out[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.params(),
+			},
+		}
+	}
+	return out
+}
+
+func fromOpenAIToolCalls(calls []openai.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: toolArguments(c.Function.Arguments)}
+	}
+	return out
+}
+
+// supportsJSONSchema 仅 OpenAI 官方接口支持 json_schema，其他兼容服务退化为 json_object
+func (a *OpenAIAdapter) supportsJSONSchema() bool {
+	return strings.Contains(a.baseURL, "api.openai.com")
+}
+
+func (a *OpenAIAdapter) responseFormat(f *ResponseFormat) *openai.ChatCompletionResponseFormat {
+	if f == nil {
+		return nil
+	}
+	if f.Type == ResponseFormatJSONSchema && f.Schema != nil && a.supportsJSONSchema() {
+		if schema, err := json.Marshal(f.Schema); err == nil {
+			return &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   f.Name,
+					Schema: json.RawMessage(schema),
+				},
+			}
+		}
+	}
+	return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+}
+
+// withFormatInstruction json_object 模式要求提示词中包含 JSON 字样，同时补充 Schema 说明
+func withFormatInstruction(messages []ChatMessage, f *ResponseFormat) []ChatMessage {
+	if f == nil {
+		return messages
+	}
+	out := make([]ChatMessage, 0, len(messages)+1)
+	out = append(out, ChatMessage{Role: "system", Content: f.Instruction()})
+	return append(out, messages...)
+}
+
+func fromOpenAIUsage(u openai.Usage) Usage {
+	usage := Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	usage.normalize()
+	return usage
+}
+
 // ChatCompletion 聊天补全
-func (a *OpenAIAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (string, error) {
-	client := a.newClient(options.APIKey)
+func (a *OpenAIAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	client, recorder := a.newClient(options.APIKey, false)
 
 	req := openai.ChatCompletionRequest{
-		Model:       a.model,
-		Messages:    toOpenAIMessages(messages),
-		Temperature: options.Temperature,
-		MaxTokens:   options.MaxTokens,
+		Model:            a.model,
+		Messages:         toOpenAIMessages(withFormatInstruction(messages, options.ResponseFormat)),
+		Temperature:      options.Temperature,
+		MaxTokens:        options.MaxTokens,
+		TopP:             options.TopP,
+		Stop:             options.Stop,
+		PresencePenalty:  options.PresencePenalty,
+		FrequencyPenalty: options.FrequencyPenalty,
+		ResponseFormat:   a.responseFormat(options.ResponseFormat),
+		Tools:            toOpenAITools(options.Tools),
 	}
 
 	resp, err := client.CreateChatCompletion(ctx, req)
+	if err != nil && req.ResponseFormat != nil && isBadRequest(classifyOpenAIError(err, recorder)) {
+		// 兼容服务不支持 response_format 时去掉该参数重试，输出要求仍保留在系统提示词中
+		req.ResponseFormat = nil
+		resp, err = client.CreateChatCompletion(ctx, req)
+	}
 	if err != nil {
-		return "", fmt.Errorf("LLM 请求失败: %w", err)
+		return nil, fmt.Errorf("LLM 请求失败: %w", classifyOpenAIError(err, recorder))
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", ErrInvalidResponse
+		return nil, ErrInvalidResponse
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	return &ChatResponse{
+		Content:      resp.Choices[0].Message.Content,
+		Usage:        fromOpenAIUsage(resp.Usage),
+		FinishReason: string(resp.Choices[0].FinishReason),
+		ToolCalls:    fromOpenAIToolCalls(resp.Choices[0].Message.ToolCalls),
+	}, nil
 }
 
 // StreamChatCompletion 流式聊天补全
-func (a *OpenAIAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (string, error) {
-	client := a.newClient(options.APIKey)
+func (a *OpenAIAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	client, recorder := a.newClient(options.APIKey, true)
 
 	req := openai.ChatCompletionRequest{
-		Model:       a.model,
-		Messages:    toOpenAIMessages(messages),
-		Temperature: options.Temperature,
-		MaxTokens:   options.MaxTokens,
-		Stream:      true,
+		Model:            a.model,
+		Messages:         toOpenAIMessages(withFormatInstruction(messages, options.ResponseFormat)),
+		Temperature:      options.Temperature,
+		MaxTokens:        options.MaxTokens,
+		TopP:             options.TopP,
+		Stop:             options.Stop,
+		PresencePenalty:  options.PresencePenalty,
+		FrequencyPenalty: options.FrequencyPenalty,
+		ResponseFormat:   a.responseFormat(options.ResponseFormat),
+		Tools:            toOpenAITools(options.Tools),
+		Stream:           true,
+		// 在最后一个数据块中返回用量
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
 
 	stream, err := client.CreateChatCompletionStream(ctx, req)
+	if err != nil && isBadRequest(classifyOpenAIError(err, recorder)) {
+		// 兼容服务不支持 stream_options 或 response_format 时去掉这两个参数重试，
+		// 此时没有用量信息，输出要求仍保留在系统提示词中
+		req.StreamOptions = nil
+		req.ResponseFormat = nil
+		stream, err = client.CreateChatCompletionStream(ctx, req)
+	}
 	if err != nil {
-		return "", fmt.Errorf("LLM 流式请求失败: %w", err)
+		return nil, fmt.Errorf("LLM 流式请求失败: %w", classifyOpenAIError(err, recorder))
 	}
 	defer stream.Close()
 
 	var fullContent strings.Builder
+	toolCalls := newToolCallBuilder()
+	result := &ChatResponse{}
 
 	for {
 		resp, err := stream.Recv()
@@ -96,21 +252,63 @@ func (a *OpenAIAdapter) StreamChatCompletion(ctx context.Context, messages []Cha
 			break
 		}
 		if err != nil {
-			return fullContent.String(), fmt.Errorf("流式读取失败: %w", err)
+			result.Content = fullContent.String()
+			return result, fmt.Errorf("流式读取失败: %w", classifyOpenAIError(err, recorder))
 		}
 
+		if resp.Usage != nil {
+			result.Usage = fromOpenAIUsage(*resp.Usage)
+		}
 		if len(resp.Choices) > 0 {
+			if resp.Choices[0].FinishReason != "" {
+				result.FinishReason = string(resp.Choices[0].FinishReason)
+			}
+			for i, call := range resp.Choices[0].Delta.ToolCalls {
+				index := i
+				if call.Index != nil {
+					index = *call.Index
+				}
+				toolCalls.add(index, call.ID, call.Function.Name, call.Function.Arguments)
+			}
 			content := resp.Choices[0].Delta.Content
 			if content != "" {
 				fullContent.WriteString(content)
 				if cbErr := callback(content); cbErr != nil {
-					return fullContent.String(), cbErr
+					result.Content = fullContent.String()
+					return result, cbErr
 				}
 			}
 		}
 	}
 
-	return fullContent.String(), nil
+	result.Content = fullContent.String()
+	result.ToolCalls = toolCalls.build()
+	return result, nil
+}
+
+// Embed 调用 /embeddings 计算向量嵌入
+func (a *OpenAIAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	client, recorder := a.newClient(options.APIKey, false)
+
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input:      texts,
+		Model:      openai.EmbeddingModel(a.model),
+		Dimensions: options.Dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("向量嵌入请求失败: %w", classifyOpenAIError(err, recorder))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index >= 0 && item.Index < len(vectors) {
+			vectors[item.Index] = item.Embedding
+		}
+	}
+	if err := checkEmbeddings(vectors, len(texts)); err != nil {
+		return nil, err
+	}
+	return &EmbeddingResponse{Vectors: vectors, Usage: fromOpenAIUsage(resp.Usage)}, nil
 }
 
 // ValidateConfig 验证模型配置（发送真实测试请求）
@@ -119,7 +317,7 @@ func (a *OpenAIAdapter) ValidateConfig(apiKey, baseURL string) error {
 		return ErrInvalidAPIKey
 	}
 
-	adapter := NewOpenAIAdapter(baseURL, a.model)
+	adapter := newOpenAIAdapter(baseURL, a.model, a.clients)
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
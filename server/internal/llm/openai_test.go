@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIStreamRetryWithoutStreamOptions(t *testing.T) {
+	var bodies []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("解析请求体失败: %v", err)
+		}
+		bodies = append(bodies, body)
+
+		// 模拟不支持 stream_options 的兼容服务
+		if _, ok := body["stream_options"]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"Unrecognized request argument supplied: stream_options","type":"invalid_request_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"夜色", "渐深。"} {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	adapter := newOpenAIAdapter(srv.URL, "gpt-test", defaultHTTPClients())
+	var chunks []string
+	resp, err := adapter.StreamChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "写一句"}}, ChatOptions{
+		APIKey:         "test-key",
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONObject},
+	}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("流式调用失败: %v", err)
+	}
+	if strings.Join(chunks, "|") != "夜色|渐深。" || resp.Content != "夜色渐深。" || resp.FinishReason != "stop" {
+		t.Fatalf("响应不符合预期: chunks=%q resp=%+v", chunks, resp)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("应在 400 后重试一次，实际请求 %d 次", len(bodies))
+	}
+	if _, ok := bodies[0]["response_format"]; !ok {
+		t.Fatalf("第一次请求应带 response_format: %v", bodies[0])
+	}
+	for _, key := range []string{"stream_options", "response_format"} {
+		if _, ok := bodies[1][key]; ok {
+			t.Fatalf("重试请求不应带 %s", key)
+		}
+	}
+}
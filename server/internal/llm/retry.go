@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次），<=1 表示不重试
+	BaseDelay   time.Duration // 首次重试的基础等待时间，之后指数增长
+	MaxDelay    time.Duration // 单次等待上限，Retry-After 超过该值时放弃重试
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// RetryAdapter 为适配器增加临时性错误的自动重试
+type RetryAdapter struct {
+	LLMAdapter
+	policy RetryPolicy
+}
+
+// NewRetryAdapter 创建带重试的适配器
+func NewRetryAdapter(adapter LLMAdapter, policy RetryPolicy) *RetryAdapter {
+	return &RetryAdapter{LLMAdapter: adapter, policy: policy}
+}
+
+// ChatCompletion 聊天补全，临时性错误按退避策略重试
+func (a *RetryAdapter) ChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := a.do(ctx, func() error {
+		var err error
+		resp, err = a.LLMAdapter.ChatCompletion(ctx, messages, options)
+		return err
+	}, nil)
+	return resp, err
+}
+
+// StreamChatCompletion 流式聊天补全，仅在尚未输出任何内容时重试，避免重复推送
+func (a *RetryAdapter) StreamChatCompletion(ctx context.Context, messages []ChatMessage, options ChatOptions, callback StreamCallback) (*ChatResponse, error) {
+	var resp *ChatResponse
+	started := false
+	err := a.do(ctx, func() error {
+		var err error
+		resp, err = a.LLMAdapter.StreamChatCompletion(ctx, messages, options, func(chunk string) error {
+			started = true
+			return callback(chunk)
+		})
+		return err
+	}, func() bool { return !started })
+	return resp, err
+}
+
+// Embed 向量嵌入，临时性错误按退避策略重试
+func (a *RetryAdapter) Embed(ctx context.Context, texts []string, options EmbeddingOptions) (*EmbeddingResponse, error) {
+	var resp *EmbeddingResponse
+	err := a.do(ctx, func() error {
+		var err error
+		resp, err = a.LLMAdapter.Embed(ctx, texts, options)
+		return err
+	}, nil)
+	return resp, err
+}
+
+// do 执行调用，canRetry 为空时总是允许重试
+func (a *RetryAdapter) do(ctx context.Context, call func() error, canRetry func() bool) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() {
+			return err
+		}
+		if attempt >= a.policy.MaxAttempts || ctx.Err() != nil || (canRetry != nil && !canRetry()) {
+			return err
+		}
+
+		delay := a.backoff(attempt)
+		if apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > a.policy.MaxDelay {
+				return err
+			}
+			delay = apiErr.RetryAfter
+		}
+
+		logger.Warn("LLM 调用失败，准备重试",
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 指数退避，带 50% 抖动
+func (a *RetryAdapter) backoff(attempt int) time.Duration {
+	delay := a.policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > a.policy.MaxDelay {
+		delay = a.policy.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
@@ -0,0 +1,251 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ResponseFormatType 结构化输出类型
+type ResponseFormatType string
+
+const (
+	ResponseFormatJSONObject ResponseFormatType = "json_object" // 任意 JSON 对象
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema" // 符合指定 Schema 的 JSON
+)
+
+// ResponseFormat 结构化输出要求，各适配器按上游能力映射（response_format / responseMimeType / format），
+// 不支持的上游改为在系统提示词中声明
+type ResponseFormat struct {
+	Type   ResponseFormatType
+	Name   string  // Schema 名称，仅 json_schema 使用
+	Schema *Schema // 仅 json_schema 使用
+}
+
+// JSONSchemaFormat 根据 Go 结构体生成 json_schema 输出格式
+func JSONSchemaFormat(name string, v interface{}) *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: SchemaOf(v)}
+}
+
+// Instruction 提示词形式的输出要求，用于不支持原生结构化输出的上游
+func (f *ResponseFormat) Instruction() string {
+	if f.Type == ResponseFormatJSONSchema && f.Schema != nil {
+		schema, _ := json.Marshal(f.Schema)
+		return "只输出一个符合以下 JSON Schema 的 JSON 对象，不要输出代码块标记或任何解释文字：\n" + string(schema)
+	}
+	return "只输出一个合法的 JSON 对象，不要输出代码块标记或任何解释文字。"
+}
+
+// Schema JSON Schema 的子集，足以描述服务层的结果结构体
+type Schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Description          string             `json:"description,omitempty"` // 字段说明，主要用于工具参数
+	Enum                 []string           `json:"enum,omitempty"`        // 可选值，仅作说明，不参与校验
+
+	order []string // 属性声明顺序，用于稳定的校验顺序
+}
+
+// SchemaOf 通过反射生成结构体的 Schema：
+// 字段名取 json 标签；带 omitempty 或 schema:"optional" 的字段为可选；schema:"-" 的字段（如服务端计算的统计值）不出现在 Schema 中
+func SchemaOf(v interface{}) *Schema {
+	return schemaOfType(reflect.TypeOf(v))
+}
+
+func schemaOfType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("schema") == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			s.Properties[name] = schemaOfType(field.Type)
+			s.order = append(s.order, name)
+			if !strings.Contains(opts, "omitempty") && field.Tag.Get("schema") != "optional" {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOfType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOfType(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// Sample 生成符合 Schema 的示例值（数组含一个元素），供模拟提供商使用
+func (s *Schema) Sample() interface{} {
+	switch s.Type {
+	case "object":
+		obj := map[string]interface{}{}
+		for _, name := range s.order {
+			obj[name] = s.Properties[name].Sample()
+		}
+		return obj
+	case "array":
+		if s.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{s.Items.Sample()}
+	case "string":
+		return "示例"
+	case "boolean":
+		return false
+	case "integer", "number":
+		return 1
+	default:
+		return nil
+	}
+}
+
+// Validate 校验 JSON 文本是否符合 Schema，返回第一个不符合项
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("不是合法的 JSON: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("JSON 之后还有多余内容")
+	}
+	return s.validate("$", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	// 可选字段允许为 null
+	if v == nil {
+		return nil
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+		for _, name := range s.Required {
+			if val, ok := obj[name]; !ok || val == nil {
+				return fmt.Errorf("%s: 缺少必填字段 %q", path, name)
+			}
+		}
+		for _, name := range s.order {
+			if val, ok := obj[name]; ok {
+				if err := s.Properties[name].validate(path+"."+name, val); err != nil {
+					return err
+				}
+			}
+		}
+		if s.AdditionalProperties != nil {
+			for key, val := range obj {
+				if err := s.AdditionalProperties.validate(path+"."+key, val); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+		if f, err := n.Float64(); err != nil || f != math.Trunc(f) {
+			return typeMismatch(path, s.Type, v)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return typeMismatch(path, s.Type, v)
+		}
+	}
+	return nil
+}
+
+func typeMismatch(path, want string, v interface{}) error {
+	return fmt.Errorf("%s: 应为 %s，实际为 %s", path, want, jsonTypeName(v))
+}
+
+func jsonTypeName(v interface{}) string {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number " + x.String()
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// ExtractJSON 从模型输出中提取 JSON：去除 markdown 代码块标记及前后的说明文字
+func ExtractJSON(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	s = strings.TrimSpace(s)
+
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return s
+	}
+	closer := "}"
+	if s[start] == '[' {
+		closer = "]"
+	}
+	if end := strings.LastIndex(s, closer); end > start {
+		return s[start : end+1]
+	}
+	return s[start:]
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// readSSE 逐条读取 text/event-stream，handle 返回 true 表示结束读取
+func readSSE(r io.Reader, handle func(event string, data []byte) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var event string
+	var data bytes.Buffer
+
+	dispatch := func() (bool, error) {
+		if data.Len() == 0 {
+			event = ""
+			return false, nil
+		}
+		done, err := handle(event, data.Bytes())
+		event = ""
+		data.Reset()
+		return done, err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if done, err := dispatch(); done || err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err := dispatch()
+	return err
+}
@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Tool 可供模型调用的工具（函数）定义
+type Tool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters"` // 参数的 JSON Schema，必须为 object
+}
+
+// params 未声明参数时按无参数对象处理
+func (t Tool) params() *Schema {
+	if t.Parameters == nil {
+		return NoParams()
+	}
+	return t.Parameters
+}
+
+// ToolCall 模型发起的一次工具调用
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON 对象字符串
+}
+
+// 工具调用相关的消息角色
+const (
+	RoleTool = "tool" // 工具执行结果，ToolCallID 对应 assistant 消息中的 ToolCall.ID
+)
+
+// NoParams 无参数工具的 Schema
+func NoParams() *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{}}
+}
+
+// ObjectParams 按属性构建工具参数 Schema，required 为必填属性
+func ObjectParams(properties map[string]*Schema, required ...string) *Schema {
+	s := &Schema{Type: "object", Properties: properties, Required: required}
+	for name := range properties {
+		s.order = append(s.order, name)
+	}
+	sort.Strings(s.order)
+	return s
+}
+
+// ToolFunc 工具的执行函数，arguments 为模型给出的 JSON 参数，返回值作为工具结果发回模型
+type ToolFunc func(ctx context.Context, arguments string) (string, error)
+
+// Toolbox 一组工具定义及其执行函数
+type Toolbox struct {
+	tools []Tool
+	funcs map[string]ToolFunc
+}
+
+// NewToolbox 创建工具集合
+func NewToolbox() *Toolbox {
+	return &Toolbox{funcs: make(map[string]ToolFunc)}
+}
+
+// Register 注册工具
+func (t *Toolbox) Register(tool Tool, fn ToolFunc) {
+	t.tools = append(t.tools, tool)
+	t.funcs[tool.Name] = fn
+}
+
+// Tools 获取工具定义列表
+func (t *Toolbox) Tools() []Tool {
+	return t.tools
+}
+
+// Call 执行一次工具调用；未知工具或执行出错时把错误作为结果返回，由模型决定如何继续
+func (t *Toolbox) Call(ctx context.Context, call ToolCall) string {
+	fn, ok := t.funcs[call.Name]
+	if !ok {
+		return "错误：未知工具 " + call.Name
+	}
+	result, err := fn(ctx, toolArguments(call.Arguments))
+	if err != nil {
+		logger.Warn("工具调用失败",
+			zap.String("tool", call.Name),
+			zap.String("arguments", call.Arguments),
+			zap.Error(err),
+		)
+		return "错误：" + err.Error()
+	}
+	return result
+}
+
+// toolArguments 空参数按空对象处理，部分上游要求 arguments/input 必须为对象
+func toolArguments(args string) string {
+	if strings.TrimSpace(args) == "" {
+		return "{}"
+	}
+	return args
+}
+
+// flattenToolMessages 将工具调用与结果改写为普通文本消息，
+// 供不支持工具调用的上游（或故障切换到此类上游时）继续对话
+func flattenToolMessages(messages []ChatMessage) []ChatMessage {
+	out := make([]ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		switch {
+		case m.Role == RoleTool:
+			out = append(out, ChatMessage{Role: "user", Content: fmt.Sprintf("[工具结果 %s]\n%s", m.ToolCallID, m.Content)})
+		case len(m.ToolCalls) > 0:
+			var b strings.Builder
+			b.WriteString(m.Content)
+			for _, call := range m.ToolCalls {
+				if b.Len() > 0 {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(&b, "[调用工具 %s %s] %s", call.ID, call.Name, toolArguments(call.Arguments))
+			}
+			out = append(out, ChatMessage{Role: m.Role, Content: b.String()})
+		default:
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// toolCallBuilder 按序号拼接流式返回的工具调用片段
+type toolCallBuilder struct {
+	calls map[int]*ToolCall
+	args  map[int]*strings.Builder
+}
+
+func newToolCallBuilder() *toolCallBuilder {
+	return &toolCallBuilder{calls: map[int]*ToolCall{}, args: map[int]*strings.Builder{}}
+}
+
+// add 合并一个片段：ID 与名称首次出现时记录，参数逐段追加
+func (b *toolCallBuilder) add(index int, id, name, argsDelta string) {
+	call, ok := b.calls[index]
+	if !ok {
+		call = &ToolCall{}
+		b.calls[index] = call
+		b.args[index] = &strings.Builder{}
+	}
+	if id != "" {
+		call.ID = id
+	}
+	if name != "" {
+		call.Name = name
+	}
+	b.args[index].WriteString(argsDelta)
+}
+
+// build 按序号返回完整的工具调用
+func (b *toolCallBuilder) build() []ToolCall {
+	if len(b.calls) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(b.calls))
+	for i := range b.calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	out := make([]ToolCall, 0, len(indexes))
+	for _, i := range indexes {
+		call := *b.calls[i]
+		call.Arguments = toolArguments(b.args[i].String())
+		out = append(out, call)
+	}
+	return out
+}
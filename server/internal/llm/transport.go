@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HTTPConfig 访问上游使用的 HTTP 连接配置
+type HTTPConfig struct {
+	Timeout             time.Duration // 非流式请求的总超时
+	StreamTimeout       time.Duration // 流式请求的总超时，0 表示不限
+	ConnectTimeout      time.Duration // 建立连接（含 TLS 握手）的超时
+	IdleConnTimeout     time.Duration // 空闲连接保留时间
+	MaxIdleConnsPerHost int           // 每个上游保留的空闲连接数
+	Proxy               string        // 代理地址，为空时使用 HTTP_PROXY / HTTPS_PROXY 环境变量
+}
+
+// DefaultHTTPConfig 默认 HTTP 连接配置
+var DefaultHTTPConfig = HTTPConfig{
+	Timeout:             120 * time.Second,
+	StreamTimeout:       10 * time.Minute,
+	ConnectTimeout:      10 * time.Second,
+	IdleConnTimeout:     90 * time.Second,
+	MaxIdleConnsPerHost: 16,
+}
+
+// ollamaMinTimeout 本地模型首次加载较慢，超时时间不低于该值
+const ollamaMinTimeout = 300 * time.Second
+
+// HTTPClients 共享同一个 Transport（连接池）的非流式与流式客户端
+type HTTPClients struct {
+	client       *http.Client
+	streamClient *http.Client
+}
+
+// NewHTTPClients 按配置创建 HTTP 客户端
+func NewHTTPClients(cfg HTTPConfig) (*HTTPClients, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址无效: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &HTTPClients{
+		client:       &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		streamClient: &http.Client{Transport: transport, Timeout: cfg.StreamTimeout},
+	}, nil
+}
+
+// get 按是否流式选择客户端
+func (c *HTTPClients) get(stream bool) *http.Client {
+	if stream {
+		return c.streamClient
+	}
+	return c.client
+}
+
+// withMinTimeout 返回总超时不低于 min 的客户端，仍共享同一个 Transport
+func (c *HTTPClients) withMinTimeout(min time.Duration) *HTTPClients {
+	out := &HTTPClients{client: c.client, streamClient: c.streamClient}
+	if c.client.Timeout > 0 && c.client.Timeout < min {
+		client := *c.client
+		client.Timeout = min
+		out.client = &client
+	}
+	if c.streamClient.Timeout > 0 && c.streamClient.Timeout < min {
+		client := *c.streamClient
+		client.Timeout = min
+		out.streamClient = &client
+	}
+	return out
+}
+
+var (
+	defaultClientsOnce sync.Once
+	defaultClients     *HTTPClients
+)
+
+// defaultHTTPClients 未通过 Manager 配置时使用的进程级共享客户端
+func defaultHTTPClients() *HTTPClients {
+	defaultClientsOnce.Do(func() {
+		// 默认配置不含代理地址，不会出错
+		defaultClients, _ = NewHTTPClients(DefaultHTTPConfig)
+	})
+	return defaultClients
+}
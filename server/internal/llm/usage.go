@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Add 累加用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// normalize 补全 TotalTokens
+func (u *Usage) normalize() {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+}
+
+// IsZero 上游是否未返回任何用量
+func (u Usage) IsZero() bool {
+	return u.PromptTokens == 0 && u.CompletionTokens == 0 && u.TotalTokens == 0
+}
+
+// EstimateTokens 粗略估算 token 数：汉字约 1 token/字，其余字符约 4 字符/token
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			cjk++
+		} else if !unicode.IsSpace(r) {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// EstimateUsage 在上游未返回用量时按消息与回复估算
+func EstimateUsage(messages []ChatMessage, content string) Usage {
+	var prompt strings.Builder
+	for _, m := range messages {
+		prompt.WriteString(m.Content)
+	}
+	u := Usage{
+		PromptTokens:     EstimateTokens(prompt.String()),
+		CompletionTokens: EstimateTokens(content),
+		Estimated:        true,
+	}
+	u.normalize()
+	return u
+}
+
+// ModelPrice 模型单价（每百万 token）
+type ModelPrice struct {
+	Model  string  // 模型名，精确匹配优先，其次按最长前缀匹配
+	Input  float64 // 输入单价
+	Output float64 // 输出单价
+}
+
+// PriceTable 模型价格表
+type PriceTable struct {
+	Currency string
+	prices   []ModelPrice
+}
+
+// NewPriceTable 创建价格表
+func NewPriceTable(currency string, prices []ModelPrice) *PriceTable {
+	return &PriceTable{Currency: currency, prices: prices}
+}
+
+// Lookup 查找模型单价
+func (t *PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if t == nil {
+		return ModelPrice{}, false
+	}
+	var best ModelPrice
+	found := false
+	for _, p := range t.prices {
+		if p.Model == model {
+			return p, true
+		}
+		if strings.HasPrefix(model, p.Model) && len(p.Model) > len(best.Model) {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Cost 计算一次调用的费用，未配置价格的模型返回 0
+func (t *PriceTable) Cost(model string, usage Usage) float64 {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+}
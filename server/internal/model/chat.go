@@ -37,6 +37,7 @@ type Message struct {
 	ConversationID uuid.UUID `gorm:"type:uuid;not null;index" json:"conversation_id"`
 	Role           string    `gorm:"size:20;not null" json:"role"` // user, assistant
 	Content        string    `gorm:"type:text;not null" json:"content"`
+	Synthetic      bool      `gorm:"default:false" json:"synthetic"` // 由模拟提供商生成
 	CreatedAt      time.Time `json:"created_at"`
 }
 
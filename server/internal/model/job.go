@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 后台任务类型
+const (
+	JobTypeArchitecture     = "architecture"
+	JobTypeArchitectureStep = "architecture_step"
+	JobTypeBlueprint        = "blueprint"
+	JobTypeChapter          = "chapter"
+	JobTypeChapterBatch     = "chapter_batch"
+	JobTypeChapterDrafts    = "chapter_drafts"
+	JobTypeGraph            = "graph"
+)
+
+// 后台任务状态
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// Job 后台生成任务，记录参数、进度与结果，服务重启后未完成的任务会重新执行
+type Job struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DeviceID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_job_device_created;uniqueIndex:idx_job_active" json:"device_id"`
+	ProjectID *uuid.UUID `gorm:"type:uuid;index" json:"project_id,omitempty"`
+	Type      string     `gorm:"size:50;not null;uniqueIndex:idx_job_active" json:"type"`
+	// 同一对象（如项目的某一章）同时只允许一个未完成的同类任务，由部分唯一索引保证
+	Target string `gorm:"size:100;uniqueIndex:idx_job_active,where:status = 'pending' OR status = 'running'" json:"target,omitempty"`
+	Status string `gorm:"size:20;not null;index" json:"status"`
+	// 请求参数（JSON）
+	Params  string `gorm:"type:text" json:"params,omitempty"`
+	NoCache bool   `gorm:"not null;default:false" json:"no_cache"` // 跳过 LLM 响应缓存
+
+	// 进度：当前步骤及序号（从 1 开始）
+	Step       string `gorm:"size:100" json:"step,omitempty"`
+	StepIndex  int    `gorm:"not null;default:0" json:"step_index"`
+	TotalSteps int    `gorm:"not null;default:0" json:"total_steps"`
+
+	// 执行结果（JSON）或错误信息
+	Result    string `gorm:"type:text" json:"result,omitempty"`
+	Error     string `gorm:"type:text" json:"error,omitempty"`
+	Synthetic bool   `gorm:"not null;default:false" json:"synthetic"` // 结果由模拟提供商生成
+	Attempts  int    `gorm:"not null;default:0" json:"attempts"`      // 已开始执行的次数
+
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"index:idx_job_device_created" json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	switch j.Status {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCanceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// LLMCacheEntry LLM 响应缓存条目，Key 为请求内容的 SHA-256
+type LLMCacheEntry struct {
+	Key       string    `gorm:"size:64;primary_key" json:"key"`
+	Response  string    `gorm:"type:text;not null" json:"response"` // JSON 序列化的 llm.ChatResponse
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (LLMCacheEntry) TableName() string {
+	return "llm_cache"
+}
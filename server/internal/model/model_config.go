@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,7 +14,7 @@ type ModelProvider struct {
 	Name        string    `gorm:"size:50;uniqueIndex;not null" json:"name"` // openai, anthropic, google, etc.
 	DisplayName string    `gorm:"size:100;not null" json:"display_name"`
 	BaseURL     string    `gorm:"size:500" json:"base_url"`
-	AuthType    string    `gorm:"size:20;default:api_key" json:"auth_type"` // api_key, oauth, custom
+	AuthType    string    `gorm:"size:20;default:api_key" json:"auth_type"` // api_key, none, oauth, custom
 	IsActive    bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 
@@ -40,7 +41,7 @@ type ModelConfig struct {
 
 	// 关联
 	Device   *Device        `gorm:"-" json:"device,omitempty"`
-	Provider *ModelProvider `gorm:"-" json:"provider,omitempty"`
+	Provider *ModelProvider `gorm:"foreignKey:ProviderID" json:"provider,omitempty"`
 }
 
 func (ModelConfig) TableName() string {
@@ -59,13 +60,79 @@ func (mc *ModelConfig) BeforeCreate(tx *gorm.DB) error {
 type ModelBinding struct {
 	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	DeviceID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_device_purpose" json:"device_id"`
-	Purpose       string    `gorm:"size:50;not null;uniqueIndex:idx_device_purpose" json:"purpose"` // architecture, chapter, writing, review, general
+	Purpose       string    `gorm:"size:50;not null;uniqueIndex:idx_device_purpose" json:"purpose"` // architecture, chapter, writing, review, general, embedding
 	ModelConfigID uuid.UUID `gorm:"type:uuid;not null" json:"model_config_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	// 备用模型配置 ID 列表（JSON 数组），主模型失败时按顺序切换
+	FallbackConfigIDs string `gorm:"type:text" json:"fallback_config_ids"`
+	// 生成参数（JSON），覆盖各功能的默认值
+	Params    string    `gorm:"type:text" json:"params"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	// 关联
-	ModelConfig *ModelConfig `gorm:"foreignKey:ModelConfigID" json:"model_config,omitempty"`
+	ModelConfig     *ModelConfig   `gorm:"foreignKey:ModelConfigID" json:"model_config,omitempty"`
+	FallbackConfigs []*ModelConfig `gorm:"-" json:"fallback_configs,omitempty"`
+}
+
+// GetFallbackConfigIDs 解析备用模型配置 ID 列表
+func (mb *ModelBinding) GetFallbackConfigIDs() []uuid.UUID {
+	if mb.FallbackConfigIDs == "" {
+		return nil
+	}
+	var raw []string
+	if err := json.Unmarshal([]byte(mb.FallbackConfigIDs), &raw); err != nil {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(raw))
+	for _, s := range raw {
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SetFallbackConfigIDs 序列化备用模型配置 ID 列表
+func (mb *ModelBinding) SetFallbackConfigIDs(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		mb.FallbackConfigIDs = ""
+		return
+	}
+	raw := make([]string, len(ids))
+	for i, id := range ids {
+		raw[i] = id.String()
+	}
+	data, _ := json.Marshal(raw)
+	mb.FallbackConfigIDs = string(data)
+}
+
+// GenerationParams 功能绑定的生成参数，未设置的字段使用各功能的默认值
+type GenerationParams struct {
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+}
+
+// GetParams 解析生成参数
+func (mb *ModelBinding) GetParams() GenerationParams {
+	var params GenerationParams
+	if mb.Params != "" {
+		_ = json.Unmarshal([]byte(mb.Params), &params)
+	}
+	return params
+}
+
+// SetParams 序列化生成参数，全部未设置时清空
+func (mb *ModelBinding) SetParams(params GenerationParams) {
+	data, _ := json.Marshal(params)
+	if string(data) == "{}" {
+		mb.Params = ""
+		return
+	}
+	mb.Params = string(data)
 }
 
 func (ModelBinding) TableName() string {
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,13 +26,21 @@ type Project struct {
 	PlotArchitecture    string `gorm:"type:text" json:"plot_architecture,omitempty"`
 	CharacterState      string `gorm:"type:text" json:"character_state,omitempty"`
 	ArchitectureGenerated bool   `gorm:"default:false" json:"architecture_generated"`
+	ArchitectureSynthetic bool   `gorm:"default:false" json:"architecture_synthetic"` // 由模拟提供商生成
+	// 上游步骤重新生成后需要更新的步骤（JSON 数组）
+	ArchitectureStale     string `gorm:"type:text" json:"architecture_stale,omitempty"`
 
 	// 大纲数据
 	ChapterBlueprint      string `gorm:"type:text" json:"chapter_blueprint,omitempty"`
 	BlueprintGenerated    bool   `gorm:"default:false" json:"blueprint_generated"`
+	BlueprintSynthetic    bool   `gorm:"default:false" json:"blueprint_synthetic"` // 由模拟提供商生成
 
 	// 上下文数据
+	// 全局摘要：前情概要 + 之后已定稿章节的摘要，由定稿时自动维护
 	GlobalSummary string `gorm:"type:text" json:"global_summary,omitempty"`
+	// 前情概要：超出 token 预算时将较早章节的摘要压缩到这里，SummaryRecapThrough 为覆盖到的章节号
+	SummaryRecap        string `gorm:"type:text" json:"summary_recap,omitempty"`
+	SummaryRecapThrough int    `gorm:"default:0" json:"summary_recap_through"`
 
 	// 关系图谱数据
 	GraphData     string `gorm:"type:text" json:"graph_data,omitempty"` // 存储 JSON 字符串
@@ -50,6 +59,99 @@ func (Project) TableName() string {
 	return "projects"
 }
 
+// 架构生成步骤，与 Project 中的字段和数据库列名一致
+const (
+	ArchitectureStepCoreSeed          = "core_seed"
+	ArchitectureStepCharacterDynamics = "character_dynamics"
+	ArchitectureStepWorldBuilding     = "world_building"
+	ArchitectureStepPlotArchitecture  = "plot_architecture"
+	ArchitectureStepCharacterState    = "character_state"
+)
+
+// ArchitectureSteps 架构生成步骤（按执行顺序）
+var ArchitectureSteps = []string{
+	ArchitectureStepCoreSeed,
+	ArchitectureStepCharacterDynamics,
+	ArchitectureStepWorldBuilding,
+	ArchitectureStepPlotArchitecture,
+	ArchitectureStepCharacterState,
+}
+
+// ArchitectureStep 获取架构步骤内容，未知步骤返回空字符串
+func (p *Project) ArchitectureStep(step string) string {
+	switch step {
+	case ArchitectureStepCoreSeed:
+		return p.CoreSeed
+	case ArchitectureStepCharacterDynamics:
+		return p.CharacterDynamics
+	case ArchitectureStepWorldBuilding:
+		return p.WorldBuilding
+	case ArchitectureStepPlotArchitecture:
+		return p.PlotArchitecture
+	case ArchitectureStepCharacterState:
+		return p.CharacterState
+	}
+	return ""
+}
+
+// SetArchitectureStep 设置架构步骤内容
+func (p *Project) SetArchitectureStep(step, content string) {
+	switch step {
+	case ArchitectureStepCoreSeed:
+		p.CoreSeed = content
+	case ArchitectureStepCharacterDynamics:
+		p.CharacterDynamics = content
+	case ArchitectureStepWorldBuilding:
+		p.WorldBuilding = content
+	case ArchitectureStepPlotArchitecture:
+		p.PlotArchitecture = content
+	case ArchitectureStepCharacterState:
+		p.CharacterState = content
+	}
+}
+
+// ArchitectureComplete 是否所有架构步骤都已生成
+func (p *Project) ArchitectureComplete() bool {
+	for _, step := range ArchitectureSteps {
+		if p.ArchitectureStep(step) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// GetArchitectureStale 解析需要更新的架构步骤
+func (p *Project) GetArchitectureStale() []string {
+	if p.ArchitectureStale == "" {
+		return nil
+	}
+	var steps []string
+	if err := json.Unmarshal([]byte(p.ArchitectureStale), &steps); err != nil {
+		return nil
+	}
+	return steps
+}
+
+// SetArchitectureStale 序列化需要更新的架构步骤，按执行顺序排列并去重
+func (p *Project) SetArchitectureStale(steps []string) {
+	marked := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		marked[step] = true
+	}
+	ordered := make([]string, 0, len(marked))
+	for _, step := range ArchitectureSteps {
+		if marked[step] {
+			ordered = append(ordered, step)
+		}
+	}
+	if len(ordered) == 0 {
+		p.ArchitectureStale = ""
+		return
+	}
+	data, _ := json.Marshal(ordered)
+	p.ArchitectureStale = string(data)
+}
+
 // BeforeCreate GORM hook
 func (p *Project) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
@@ -73,9 +175,15 @@ type Chapter struct {
 	BlueprintTwistLevel  string `gorm:"size:20" json:"blueprint_twist_level,omitempty"`
 	BlueprintSummary     string `gorm:"type:text" json:"blueprint_summary,omitempty"`
 
+	// 定稿时根据正文生成的摘要（区别于大纲中计划的 BlueprintSummary）
+	Summary string `gorm:"type:text" json:"summary,omitempty"`
+
 	// 章节内容
 	Content    string `gorm:"type:text" json:"content,omitempty"`
 	WordCount  int    `gorm:"default:0" json:"word_count"`
+	Synthetic  bool   `gorm:"default:false" json:"synthetic"` // 正文由模拟提供商生成
+	// 流式生成中已输出的部分内容，生成完成后清空；中断时保留，避免覆盖已有正文
+	PartialContent string `gorm:"type:text" json:"partial_content,omitempty"`
 
 	// 状态
 	Status     string `gorm:"size:20;default:not_started" json:"status"` // not_started, draft, completed
@@ -92,6 +200,9 @@ type Chapter struct {
 
 	// 关联
 	Project    *Project `gorm:"-" json:"project,omitempty"`
+
+	// 本次生成的提示（如上一章未定稿），不保存
+	GenerationWarnings []string `gorm:"-" json:"-"`
 }
 
 func (Chapter) TableName() string {
@@ -105,3 +216,61 @@ func (c *Chapter) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// CharacterStateVersion 角色状态历史版本：ChapterNumber 章定稿后的角色状态，
+// ChapterNumber 为 0 时是架构阶段生成的初始状态
+type CharacterStateVersion struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProjectID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_project_character_state" json:"project_id"`
+	ChapterNumber int       `gorm:"not null;uniqueIndex:idx_project_character_state" json:"chapter_number"`
+	Content       string    `gorm:"type:text" json:"content"`
+	Synthetic     bool      `gorm:"default:false" json:"synthetic"` // 由模拟提供商生成
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (CharacterStateVersion) TableName() string {
+	return "character_state_versions"
+}
+
+// BeforeCreate GORM hook
+func (v *CharacterStateVersion) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// 候选稿来源
+const (
+	DraftSourceGenerated = "generated" // 生成的候选稿
+	DraftSourceReplaced  = "replaced"  // 选用候选稿前的章节正文
+)
+
+// ChapterDraft 章节候选稿：一次生成多个版本供比较，选中的写入章节正文，其余保留备用
+type ChapterDraft struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProjectID     uuid.UUID  `gorm:"type:uuid;not null;index:idx_project_chapter_draft" json:"project_id"`
+	ChapterNumber int        `gorm:"not null;index:idx_project_chapter_draft" json:"chapter_number"`
+	Content       string     `gorm:"type:text" json:"content"`
+	WordCount     int        `gorm:"default:0" json:"word_count"`
+	Source        string     `gorm:"size:20;default:generated" json:"source"` // generated, replaced
+	ModelConfigID *uuid.UUID `gorm:"type:uuid" json:"model_config_id,omitempty"`
+	Model         string     `gorm:"size:100" json:"model,omitempty"`
+	Synthetic     bool       `gorm:"default:false" json:"synthetic"` // 由模拟提供商生成
+	PromotedAt    *time.Time `json:"promoted_at,omitempty"`          // 最近一次选用为章节正文的时间
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (ChapterDraft) TableName() string {
+	return "chapter_drafts"
+}
+
+// BeforeCreate GORM hook
+func (d *ChapterDraft) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LLMUsage 模型调用用量记录
+type LLMUsage struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DeviceID         uuid.UUID  `gorm:"type:uuid;not null;index:idx_llm_usage_device_created" json:"device_id"`
+	ProjectID        *uuid.UUID `gorm:"type:uuid;index" json:"project_id,omitempty"`
+	Purpose          string     `gorm:"size:50;not null" json:"purpose"`
+	ModelConfigID    uuid.UUID  `gorm:"type:uuid" json:"model_config_id"`
+	Provider         string     `gorm:"size:50" json:"provider"`
+	Model            string     `gorm:"size:100;not null" json:"model"`
+	PromptTokens     int        `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int        `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int        `gorm:"not null;default:0" json:"total_tokens"`
+	Cost             float64    `gorm:"not null;default:0" json:"cost"`
+	Estimated        bool       `gorm:"not null;default:false" json:"estimated"` // 上游未返回用量时按字数估算
+	CreatedAt        time.Time  `gorm:"index:idx_llm_usage_device_created" json:"created_at"`
+}
+
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
+
+func (u *LLMUsage) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
+// UsageQuota 月度用量配额，ProjectID 为空表示设备级配额；限额为 0 表示不限
+type UsageQuota struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DeviceID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"device_id"`
+	ProjectID     *uuid.UUID `gorm:"type:uuid;index" json:"project_id,omitempty"`
+	MonthlyTokens int64      `gorm:"not null;default:0" json:"monthly_tokens"`
+	MonthlyCost   float64    `gorm:"not null;default:0" json:"monthly_cost"`
+	WarnRatio     float64    `gorm:"not null;default:0" json:"warn_ratio"` // 0 表示使用全局默认值
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (UsageQuota) TableName() string {
+	return "usage_quotas"
+}
+
+func (q *UsageQuota) BeforeCreate(tx *gorm.DB) error {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return nil
+}
@@ -85,6 +85,32 @@ func (r *ChapterRepository) UpdateContent(ctx context.Context, id string, conten
 		}).Error
 }
 
+// UpdatePartialContent 保存流式生成中的部分内容
+func (r *ChapterRepository) UpdatePartialContent(ctx context.Context, id string, content string) error {
+	return r.db.WithContext(ctx).Model(&model.Chapter{}).
+		Where("id = ?", id).
+		Update("partial_content", content).Error
+}
+
+// UpdateSummary 更新章节摘要
+func (r *ChapterRepository) UpdateSummary(ctx context.Context, id string, summary string) error {
+	return r.db.WithContext(ctx).Model(&model.Chapter{}).
+		Where("id = ?", id).
+		Update("summary", summary).Error
+}
+
+// LatestCompletedNumber 已完成章节中最大的章节号，没有时返回 0
+func (r *ChapterRepository) LatestCompletedNumber(ctx context.Context, projectID string) (int, error) {
+	var result struct {
+		Latest int
+	}
+	err := r.db.WithContext(ctx).Model(&model.Chapter{}).
+		Select("COALESCE(MAX(chapter_number), 0) as latest").
+		Where("project_id = ? AND status = ?", projectID, "completed").
+		Scan(&result).Error
+	return result.Latest, err
+}
+
 // UpdateStatus 更新章节状态
 func (r *ChapterRepository) UpdateStatus(ctx context.Context, id string, status string) error {
 	return r.db.WithContext(ctx).Model(&model.Chapter{}).
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"x-novel/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ChapterDraftRepository 章节候选稿仓储
+type ChapterDraftRepository struct {
+	db *gorm.DB
+}
+
+// NewChapterDraftRepository 创建章节候选稿仓储
+func NewChapterDraftRepository(db *gorm.DB) *ChapterDraftRepository {
+	return &ChapterDraftRepository{db: db}
+}
+
+// Create 保存候选稿
+func (r *ChapterDraftRepository) Create(ctx context.Context, draft *model.ChapterDraft) error {
+	return r.db.WithContext(ctx).Create(draft).Error
+}
+
+// ListByChapter 获取某一章的候选稿，按创建时间升序
+func (r *ChapterDraftRepository) ListByChapter(ctx context.Context, projectID string, chapterNumber int) ([]*model.ChapterDraft, error) {
+	var drafts []*model.ChapterDraft
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND chapter_number = ?", projectID, chapterNumber).
+		Order("created_at ASC").
+		Find(&drafts).Error
+	return drafts, err
+}
+
+// GetByChapter 获取某一章的指定候选稿
+func (r *ChapterDraftRepository) GetByChapter(ctx context.Context, projectID string, chapterNumber int, id string) (*model.ChapterDraft, error) {
+	var draft model.ChapterDraft
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND project_id = ? AND chapter_number = ?", id, projectID, chapterNumber).
+		First(&draft).Error
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// ExistsContent 某一章是否已有内容相同的候选稿
+func (r *ChapterDraftRepository) ExistsContent(ctx context.Context, projectID string, chapterNumber int, content string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ChapterDraft{}).
+		Where("project_id = ? AND chapter_number = ? AND content = ?", projectID, chapterNumber, content).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// MarkPromoted 记录候选稿被选用为章节正文的时间
+func (r *ChapterDraftRepository) MarkPromoted(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.ChapterDraft{}).
+		Where("id = ?", id).
+		Update("promoted_at", at).Error
+}
+
+// Delete 删除候选稿
+func (r *ChapterDraftRepository) Delete(ctx context.Context, projectID string, chapterNumber int, id string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND project_id = ? AND chapter_number = ?", id, projectID, chapterNumber).
+		Delete(&model.ChapterDraft{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"x-novel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CharacterStateRepository 角色状态历史仓储
+type CharacterStateRepository struct {
+	db *gorm.DB
+}
+
+// NewCharacterStateRepository 创建角色状态历史仓储
+func NewCharacterStateRepository(db *gorm.DB) *CharacterStateRepository {
+	return &CharacterStateRepository{db: db}
+}
+
+// Save 保存某一章之后的角色状态，同一章重复定稿时覆盖
+func (r *CharacterStateRepository) Save(ctx context.Context, version *model.CharacterStateVersion) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "chapter_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "synthetic", "updated_at"}),
+	}).Create(version).Error
+}
+
+// ListByProject 获取项目的角色状态历史，按章节号升序
+func (r *CharacterStateRepository) ListByProject(ctx context.Context, projectID string) ([]*model.CharacterStateVersion, error) {
+	var versions []*model.CharacterStateVersion
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("chapter_number ASC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetByChapter 获取某一章之后的角色状态
+func (r *CharacterStateRepository) GetByChapter(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error) {
+	var version model.CharacterStateVersion
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND chapter_number = ?", projectID, chapterNumber).
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// LatestBefore 获取章节号小于 chapterNumber 的最新版本，没有时返回 nil
+func (r *CharacterStateRepository) LatestBefore(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error) {
+	var version model.CharacterStateVersion
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND chapter_number < ?", projectID, chapterNumber).
+		Order("chapter_number DESC").
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// DeleteByChapter 删除某一章之后的角色状态
+func (r *CharacterStateRepository) DeleteByChapter(ctx context.Context, projectID string, chapterNumber int) error {
+	return r.db.WithContext(ctx).
+		Where("project_id = ? AND chapter_number = ?", projectID, chapterNumber).
+		Delete(&model.CharacterStateVersion{}).Error
+}
+
+// DeleteAfter 删除章节号大于 chapterNumber 的版本，chapterNumber 为 -1 时删除全部版本
+func (r *CharacterStateRepository) DeleteAfter(ctx context.Context, projectID string, chapterNumber int) error {
+	return r.db.WithContext(ctx).
+		Where("project_id = ? AND chapter_number > ?", projectID, chapterNumber).
+		Delete(&model.CharacterStateVersion{}).Error
+}
+
+// LatestNumber 最新版本的章节号，没有任何版本时返回 -1
+func (r *CharacterStateRepository) LatestNumber(ctx context.Context, projectID string) (int, error) {
+	var result struct {
+		Latest int
+	}
+	err := r.db.WithContext(ctx).Model(&model.CharacterStateVersion{}).
+		Select("COALESCE(MAX(chapter_number), -1) as latest").
+		Where("project_id = ?", projectID).
+		Scan(&result).Error
+	return result.Latest, err
+}
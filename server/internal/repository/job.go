@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"x-novel/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// ErrActiveJobExists 同一对象上已有未结束的同类任务（并发提交时由唯一索引拒绝）
+var ErrActiveJobExists = errors.New("已有未完成的同类任务")
+
+// pgUniqueViolation PostgreSQL 唯一约束冲突的错误码
+const pgUniqueViolation = "23505"
+
+// unfinishedJobStatuses 未结束的任务状态
+var unfinishedJobStatuses = []string{model.JobStatusPending, model.JobStatusRunning}
+
+// Create 创建任务；同一对象上已有未结束的同类任务时返回 ErrActiveJobExists
+func (r *JobRepository) Create(ctx context.Context, job *model.Job) error {
+	err := r.db.WithContext(ctx).Create(job).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return ErrActiveJobExists
+	}
+	return err
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id string) (*model.Job, error) {
+	var job model.Job
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindActive 查找同一对象上未结束的同类任务，不存在时返回 nil
+func (r *JobRepository) FindActive(ctx context.Context, deviceID uuid.UUID, jobType, target string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.WithContext(ctx).
+		Where("device_id = ? AND type = ? AND target = ? AND status IN ?", deviceID, jobType, target, unfinishedJobStatuses).
+		Order("created_at DESC").
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// JobFilter 任务查询条件，零值字段不参与过滤
+type JobFilter struct {
+	DeviceID  uuid.UUID
+	ProjectID *uuid.UUID
+	Type      string
+	Status    string
+}
+
+// List 按创建时间倒序查询任务
+func (r *JobRepository) List(ctx context.Context, filter JobFilter, offset, limit int) ([]*model.Job, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Job{}).Where("device_id = ?", filter.DeviceID)
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []*model.Job
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// ListUnfinished 查询所有未结束的任务（服务启动时恢复执行）
+func (r *JobRepository) ListUnfinished(ctx context.Context) ([]*model.Job, error) {
+	var jobs []*model.Job
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", unfinishedJobStatuses).
+		Order("created_at ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// MarkRunning 开始执行：仅当任务仍未结束时生效，返回是否成功
+func (r *JobRepository) MarkRunning(ctx context.Context, id uuid.UUID) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, unfinishedJobStatuses).
+		Updates(map[string]interface{}{
+			"status":     model.JobStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+			"error":      "",
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateProgress 更新当前步骤
+func (r *JobRepository) UpdateProgress(ctx context.Context, id uuid.UUID, step string, index, total int) error {
+	return r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusRunning).
+		Updates(map[string]interface{}{
+			"step":        step,
+			"step_index":  index,
+			"total_steps": total,
+		}).Error
+}
+
+// UpdateResult 保存执行中的阶段性结果
+func (r *JobRepository) UpdateResult(ctx context.Context, id uuid.UUID, result string) error {
+	return r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusRunning).
+		Update("result", result).Error
+}
+
+// Finish 结束任务；任务已被取消时不覆盖，返回是否成功。
+// result 为空时保留已保存的阶段性结果
+func (r *JobRepository) Finish(ctx context.Context, id uuid.UUID, status, result, errMsg string, synthetic bool) (bool, error) {
+	updates := map[string]interface{}{
+		"status":      status,
+		"error":       errMsg,
+		"synthetic":   synthetic,
+		"finished_at": time.Now(),
+	}
+	if result != "" {
+		updates["result"] = result
+	}
+	res := r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, unfinishedJobStatuses).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Requeue 将运行中的任务放回队列（服务关闭时中断的任务）
+func (r *JobRepository) Requeue(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusRunning).
+		Update("status", model.JobStatusPending).Error
+}
+
+// Cancel 取消未结束的任务，返回是否成功
+func (r *JobRepository) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status IN ?", id, unfinishedJobStatuses).
+		Updates(map[string]interface{}{
+			"status":      model.JobStatusCanceled,
+			"finished_at": time.Now(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LLMCacheRepository 基于 Postgres 的 LLM 响应缓存，实现 llm.CacheStore
+type LLMCacheRepository struct {
+	db *gorm.DB
+}
+
+func NewLLMCacheRepository(db *gorm.DB) *LLMCacheRepository {
+	return &LLMCacheRepository{db: db}
+}
+
+// Get 读取未过期的缓存
+func (r *LLMCacheRepository) Get(ctx context.Context, key string) (*llm.ChatResponse, bool, error) {
+	var entry model.LLMCacheEntry
+	err := r.db.WithContext(ctx).
+		Where("key = ? AND expires_at > ?", key, time.Now()).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var resp llm.ChatResponse
+	if err := json.Unmarshal([]byte(entry.Response), &resp); err != nil {
+		return nil, false, err
+	}
+	return &resp, true, nil
+}
+
+// Set 写入或覆盖缓存
+func (r *LLMCacheRepository) Set(ctx context.Context, key string, resp *llm.ChatResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	entry := &model.LLMCacheEntry{
+		Key:       key,
+		Response:  string(data),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"response", "expires_at", "created_at"}),
+	}).Create(entry).Error
+}
+
+// DeleteExpired 清理过期缓存
+func (r *LLMCacheRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&model.LLMCacheEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"x-novel/internal/model"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -68,8 +69,8 @@ func (r *ModelConfigRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.ModelConfig{}).Error
 }
 
-// GetByPurpose 根据用途获取配置（通过 model_bindings 表间接查找）
-func (r *ModelConfigRepository) GetByPurpose(ctx context.Context, deviceID string, purpose string) (*model.ModelConfig, error) {
+// getBinding 获取用途绑定，未绑定时回退到 general
+func (r *ModelConfigRepository) getBinding(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, error) {
 	var binding model.ModelBinding
 	err := r.db.WithContext(ctx).
 		Where("device_id = ? AND purpose = ?", deviceID, purpose).
@@ -85,11 +86,20 @@ func (r *ModelConfigRepository) GetByPurpose(ctx context.Context, deviceID strin
 			return nil, err
 		}
 	}
+	return &binding, nil
+}
+
+// GetByPurpose 根据用途获取配置（通过 model_bindings 表间接查找）
+func (r *ModelConfigRepository) GetByPurpose(ctx context.Context, deviceID string, purpose string) (*model.ModelConfig, error) {
+	binding, err := r.getBinding(ctx, deviceID, purpose)
+	if err != nil {
+		return nil, err
+	}
 
 	var config model.ModelConfig
 	err = r.db.WithContext(ctx).
 		Preload("Provider").
-		Where("id = ? AND is_active = ?", binding.ModelConfigID, true).
+		Where("id = ? AND device_id = ? AND is_active = ?", binding.ModelConfigID, deviceID, true).
 		First(&config).Error
 	if err != nil {
 		return nil, err
@@ -97,6 +107,53 @@ func (r *ModelConfigRepository) GetByPurpose(ctx context.Context, deviceID strin
 	return &config, nil
 }
 
+// GetChainByPurpose 获取用途绑定及其模型链（主模型 + 备用模型，按顺序，跳过已停用或已删除的配置）
+func (r *ModelConfigRepository) GetChainByPurpose(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, []*model.ModelConfig, error) {
+	binding, err := r.getBinding(ctx, deviceID, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := append([]uuid.UUID{binding.ModelConfigID}, binding.GetFallbackConfigIDs()...)
+	configs, err := r.listByIDs(ctx, deviceID, ids, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(configs) == 0 {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return binding, configs, nil
+}
+
+// listByIDs 按给定 ID 顺序获取设备下的配置，不属于该设备的 ID 被跳过
+func (r *ModelConfigRepository) listByIDs(ctx context.Context, deviceID string, ids []uuid.UUID, activeOnly bool) ([]*model.ModelConfig, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var found []*model.ModelConfig
+	query := r.db.WithContext(ctx).Preload("Provider").Where("id IN ? AND device_id = ?", ids, deviceID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*model.ModelConfig, len(found))
+	for _, c := range found {
+		byID[c.ID] = c
+	}
+	configs := make([]*model.ModelConfig, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			configs = append(configs, c)
+			delete(byID, id)
+		}
+	}
+	return configs, nil
+}
+
 // ========== ModelBinding CRUD ==========
 
 // ListBindings 获取设备的所有功能绑定
@@ -108,7 +165,32 @@ func (r *ModelConfigRepository) ListBindings(ctx context.Context, deviceID strin
 		Where("device_id = ?", deviceID).
 		Order("purpose ASC").
 		Find(&bindings).Error
-	return bindings, err
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range bindings {
+		if b.FallbackConfigIDs == "" {
+			continue
+		}
+		b.FallbackConfigs, err = r.listByIDs(ctx, deviceID, b.GetFallbackConfigIDs(), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bindings, nil
+}
+
+// GetBinding 获取指定用途的功能绑定（不回退）
+func (r *ModelConfigRepository) GetBinding(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, error) {
+	var binding model.ModelBinding
+	err := r.db.WithContext(ctx).
+		Where("device_id = ? AND purpose = ?", deviceID, purpose).
+		First(&binding).Error
+	if err != nil {
+		return nil, err
+	}
+	return &binding, nil
 }
 
 // UpsertBinding 创建或更新功能绑定（同一 device + purpose 只能有一条）
@@ -121,7 +203,11 @@ func (r *ModelConfigRepository) UpsertBinding(ctx context.Context, binding *mode
 	if err == nil {
 		return r.db.WithContext(ctx).
 			Model(&existing).
-			Update("model_config_id", binding.ModelConfigID).Error
+			Updates(map[string]interface{}{
+				"model_config_id":     binding.ModelConfigID,
+				"fallback_config_ids": binding.FallbackConfigIDs,
+				"params":              binding.Params,
+			}).Error
 	}
 	return r.db.WithContext(ctx).Create(binding).Error
 }
@@ -133,11 +219,39 @@ func (r *ModelConfigRepository) DeleteBinding(ctx context.Context, deviceID stri
 		Delete(&model.ModelBinding{}).Error
 }
 
-// DeleteBindingsByConfigID 删除某个配置关联的所有绑定
+// DeleteBindingsByConfigID 删除某个配置关联的所有绑定，并将其从其他绑定的备用列表中移除
 func (r *ModelConfigRepository) DeleteBindingsByConfigID(ctx context.Context, configID string) error {
-	return r.db.WithContext(ctx).
+	err := r.db.WithContext(ctx).
 		Where("model_config_id = ?", configID).
 		Delete(&model.ModelBinding{}).Error
+	if err != nil {
+		return err
+	}
+
+	var bindings []*model.ModelBinding
+	err = r.db.WithContext(ctx).
+		Where("fallback_config_ids LIKE ?", "%"+configID+"%").
+		Find(&bindings).Error
+	if err != nil {
+		return err
+	}
+	for _, b := range bindings {
+		ids := b.GetFallbackConfigIDs()
+		kept := ids[:0]
+		for _, id := range ids {
+			if id.String() != configID {
+				kept = append(kept, id)
+			}
+		}
+		b.SetFallbackConfigIDs(kept)
+		err = r.db.WithContext(ctx).
+			Model(b).
+			Update("fallback_config_ids", b.FallbackConfigIDs).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetProviderByID 根据 ID 获取提供商
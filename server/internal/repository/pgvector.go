@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// pgvectorMaxIndexDimensions HNSW 索引支持的最大维度
+const pgvectorMaxIndexDimensions = 2000
+
+// PgVectorStore 基于 pgvector 扩展的向量索引：向量列固定为配置的维度，
+// 并建立余弦距离的 HNSW 索引，检索不需要扫描全表
+type PgVectorStore struct {
+	db *gorm.DB
+	// iterativeScan pgvector 0.8 起支持的迭代扫描：按项目与模型过滤后结果不足时继续搜索索引
+	iterativeScan bool
+}
+
+// NewPgVectorStore 创建 pgvector 向量索引：启用扩展并创建片段表，数据库未安装 pgvector 时返回错误。
+// 已有的片段表维度与配置不一致时删除维度不符的片段（需要重建索引）并修改列类型
+func NewPgVectorStore(db *gorm.DB, dimensions int) (*PgVectorStore, error) {
+	if dimensions <= 0 {
+		return nil, fmt.Errorf("向量维度必须大于 0")
+	}
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS chapter_chunks (
+			id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+			project_id uuid NOT NULL,
+			chapter_number integer NOT NULL,
+			chunk_index integer NOT NULL,
+			content text NOT NULL,
+			model varchar(100) NOT NULL,
+			embedding vector(%d) NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now()
+		)`, dimensions),
+		`CREATE INDEX IF NOT EXISTS idx_chapter_chunks_project ON chapter_chunks (project_id, chapter_number)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := migrateEmbeddingDimensions(db, dimensions); err != nil {
+		return nil, err
+	}
+
+	store := &PgVectorStore{db: db}
+	if dimensions > pgvectorMaxIndexDimensions {
+		logger.Warn("向量维度超过 HNSW 索引上限，检索将扫描项目内全部片段",
+			zap.Int("dimensions", dimensions),
+			zap.Int("max", pgvectorMaxIndexDimensions),
+		)
+		return store, nil
+	}
+	err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_chapter_chunks_embedding ON chapter_chunks USING hnsw (embedding vector_cosine_ops)`).Error
+	if err != nil {
+		// pgvector 0.5 之前不支持 HNSW
+		logger.Warn("创建 HNSW 索引失败，检索将扫描项目内全部片段", zap.Error(err))
+		return store, nil
+	}
+
+	var version string
+	db.Raw(`SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(&version)
+	store.iterativeScan = versionAtLeast(version, 0, 8)
+	return store, nil
+}
+
+// migrateEmbeddingDimensions 将已有片段表的向量列修改为配置的维度
+func migrateEmbeddingDimensions(db *gorm.DB, dimensions int) error {
+	// vector 列的 atttypmod 即维度，未限定维度时为 -1
+	var current int
+	err := db.Raw(`SELECT atttypmod FROM pg_attribute WHERE attrelid = 'chapter_chunks'::regclass AND attname = 'embedding'`).
+		Scan(&current).Error
+	if err != nil || current == dimensions {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec(`DELETE FROM chapter_chunks WHERE vector_dims(embedding) <> ?`, dimensions)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			logger.Warn("已删除与配置维度不一致的向量片段，需要重建前文检索索引",
+				zap.Int("dimensions", dimensions),
+				zap.Int64("chunks", res.RowsAffected),
+			)
+		}
+		// 修改维度后重新创建 HNSW 索引
+		if err := tx.Exec(`DROP INDEX IF EXISTS idx_chapter_chunks_embedding`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf(`ALTER TABLE chapter_chunks ALTER COLUMN embedding TYPE vector(%d)`, dimensions)).Error
+	})
+}
+
+// ReplaceChapter 在同一事务中删除该章旧片段并写入新片段
+func (s *PgVectorStore) ReplaceChapter(ctx context.Context, projectID string, chapterNumber int, chunks []VectorChunk) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM chapter_chunks WHERE project_id = ? AND chapter_number = ?`, projectID, chapterNumber).Error; err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			err := tx.Exec(`INSERT INTO chapter_chunks (project_id, chapter_number, chunk_index, content, model, embedding) VALUES (?, ?, ?, ?, ?, ?::vector)`,
+				projectID, chapterNumber, chunk.ChunkIndex, chunk.Content, chunk.Model, vectorLiteral(chunk.Embedding)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search 按余弦距离检索；启用迭代扫描时在事务内设置，过滤后的结果不足时继续搜索索引
+func (s *PgVectorStore) Search(ctx context.Context, projectID, model string, query []float32, beforeChapter, limit int) ([]VectorMatch, error) {
+	literal := vectorLiteral(query)
+	var matches []VectorMatch
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if s.iterativeScan {
+			if err := tx.Exec(`SET LOCAL hnsw.iterative_scan = relaxed_order`).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Raw(`SELECT chapter_number, chunk_index, content, 1 - (embedding <=> ?::vector) AS score
+			FROM chapter_chunks
+			WHERE project_id = ? AND chapter_number < ? AND model = ?
+			ORDER BY embedding <=> ?::vector
+			LIMIT ?`,
+			literal, projectID, beforeChapter, model, literal, limit).
+			Scan(&matches).Error
+	})
+	return matches, err
+}
+
+// IndexedChapters 已建立索引的章节号
+func (s *PgVectorStore) IndexedChapters(ctx context.Context, projectID string) ([]int, error) {
+	var numbers []int
+	err := s.db.WithContext(ctx).Raw(`SELECT DISTINCT chapter_number FROM chapter_chunks WHERE project_id = ? ORDER BY chapter_number`, projectID).
+		Scan(&numbers).Error
+	return numbers, err
+}
+
+// DeleteProject 删除项目的全部片段
+func (s *PgVectorStore) DeleteProject(ctx context.Context, projectID string) error {
+	return s.db.WithContext(ctx).Exec(`DELETE FROM chapter_chunks WHERE project_id = ?`, projectID).Error
+}
+
+// vectorLiteral 转换为 pgvector 的文本格式 [1,2,3]
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.Grow(len(v) * 10)
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// versionAtLeast 扩展版本号（如 0.8.0）是否不低于 major.minor
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err1 := strconv.Atoi(parts[0])
+	gotMinor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
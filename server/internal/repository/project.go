@@ -66,7 +66,7 @@ func (r *ProjectRepository) Delete(ctx context.Context, id string) error {
 }
 
 // UpdateArchitecture 更新架构数据
-func (r *ProjectRepository) UpdateArchitecture(ctx context.Context, id string, architecture map[string]string) error {
+func (r *ProjectRepository) UpdateArchitecture(ctx context.Context, id string, architecture map[string]interface{}) error {
 	return r.db.WithContext(ctx).Model(&model.Project{}).
 		Where("id = ?", id).
 		Updates(architecture).Error
@@ -88,3 +88,14 @@ func (r *ProjectRepository) UpdateGlobalSummary(ctx context.Context, id string,
 		Where("id = ?", id).
 		Update("global_summary", summary).Error
 }
+
+// UpdateSummaryState 更新全局摘要与前情概要
+func (r *ProjectRepository) UpdateSummaryState(ctx context.Context, id string, globalSummary, recap string, recapThrough int) error {
+	return r.db.WithContext(ctx).Model(&model.Project{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"global_summary":        globalSummary,
+			"summary_recap":         recap,
+			"summary_recap_through": recapThrough,
+		}).Error
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"x-novel/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UsageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// UsageFilter 用量查询条件，零值字段不参与过滤
+type UsageFilter struct {
+	DeviceID  uuid.UUID
+	ProjectID *uuid.UUID
+	From      time.Time // 含
+	To        time.Time // 不含
+}
+
+// UsageSum 用量汇总
+type UsageSum struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	Cost             float64
+	Calls            int64
+}
+
+// DailyUsage 按天汇总
+type DailyUsage struct {
+	Day string
+	UsageSum
+}
+
+// ProjectUsage 按项目汇总，ProjectID 为空表示未关联项目的调用
+type ProjectUsage struct {
+	ProjectID    *uuid.UUID
+	ProjectTitle string
+	UsageSum
+}
+
+const usageSumColumns = "COALESCE(SUM(llm_usage.prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(llm_usage.completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(llm_usage.total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(llm_usage.cost), 0) AS cost, " +
+	"COUNT(*) AS calls"
+
+func (r *UsageRepository) Create(ctx context.Context, usage *model.LLMUsage) error {
+	return r.db.WithContext(ctx).Create(usage).Error
+}
+
+func (r *UsageRepository) query(ctx context.Context, filter UsageFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.LLMUsage{}).Where("llm_usage.device_id = ?", filter.DeviceID)
+	if filter.ProjectID != nil {
+		query = query.Where("llm_usage.project_id = ?", *filter.ProjectID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("llm_usage.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("llm_usage.created_at < ?", filter.To)
+	}
+	return query
+}
+
+// Sum 汇总总用量
+func (r *UsageRepository) Sum(ctx context.Context, filter UsageFilter) (*UsageSum, error) {
+	var sum UsageSum
+	err := r.query(ctx, filter).Select(usageSumColumns).Scan(&sum).Error
+	return &sum, err
+}
+
+// SumByDay 按天汇总
+func (r *UsageRepository) SumByDay(ctx context.Context, filter UsageFilter) ([]*DailyUsage, error) {
+	var rows []*DailyUsage
+	err := r.query(ctx, filter).
+		Select("TO_CHAR(llm_usage.created_at, 'YYYY-MM-DD') AS day, " + usageSumColumns).
+		Group("day").
+		Order("day ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// SumByProject 按项目汇总
+func (r *UsageRepository) SumByProject(ctx context.Context, filter UsageFilter) ([]*ProjectUsage, error) {
+	var rows []*ProjectUsage
+	err := r.query(ctx, filter).
+		Select("llm_usage.project_id, COALESCE(MAX(projects.title), '') AS project_title, " + usageSumColumns).
+		Joins("LEFT JOIN projects ON projects.id = llm_usage.project_id").
+		Group("llm_usage.project_id").
+		Order("cost DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ========== Quota ==========
+
+func (r *UsageRepository) quotaQuery(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID) *gorm.DB {
+	query := r.db.WithContext(ctx).Where("device_id = ?", deviceID)
+	if projectID == nil {
+		return query.Where("project_id IS NULL")
+	}
+	return query.Where("project_id = ?", *projectID)
+}
+
+// GetQuota 获取配额，projectID 为空时获取设备级配额
+func (r *UsageRepository) GetQuota(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID) (*model.UsageQuota, error) {
+	var quota model.UsageQuota
+	err := r.quotaQuery(ctx, deviceID, projectID).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// ListQuotas 获取设备下的所有配额
+func (r *UsageRepository) ListQuotas(ctx context.Context, deviceID uuid.UUID) ([]*model.UsageQuota, error) {
+	var quotas []*model.UsageQuota
+	err := r.db.WithContext(ctx).
+		Where("device_id = ?", deviceID).
+		Order("project_id NULLS FIRST, created_at ASC").
+		Find(&quotas).Error
+	return quotas, err
+}
+
+// UpsertQuota 创建或更新配额
+func (r *UsageRepository) UpsertQuota(ctx context.Context, quota *model.UsageQuota) error {
+	existing, err := r.GetQuota(ctx, quota.DeviceID, quota.ProjectID)
+	if err == nil {
+		quota.ID = existing.ID
+		quota.CreatedAt = existing.CreatedAt
+		return r.db.WithContext(ctx).
+			Model(existing).
+			Updates(map[string]interface{}{
+				"monthly_tokens": quota.MonthlyTokens,
+				"monthly_cost":   quota.MonthlyCost,
+				"warn_ratio":     quota.WarnRatio,
+			}).Error
+	}
+	return r.db.WithContext(ctx).Create(quota).Error
+}
+
+// DeleteQuota 删除配额
+func (r *UsageRepository) DeleteQuota(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID) error {
+	return r.quotaQuery(ctx, deviceID, projectID).Delete(&model.UsageQuota{}).Error
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"x-novel/internal/llm"
+)
+
+// VectorChunk 章节正文切分后的片段及其向量
+type VectorChunk struct {
+	ChapterNumber int
+	ChunkIndex    int
+	Content       string
+	Model         string // 计算向量所用的嵌入模型，检索时只比较同一模型的向量
+	Embedding     []float32
+}
+
+// VectorMatch 检索命中的片段
+type VectorMatch struct {
+	ChapterNumber int
+	ChunkIndex    int
+	Content       string
+	Score         float32 // 余弦相似度
+}
+
+// VectorStore 章节片段的向量索引
+type VectorStore interface {
+	// ReplaceChapter 替换某一章的全部片段，chunks 为空时删除该章的索引
+	ReplaceChapter(ctx context.Context, projectID string, chapterNumber int, chunks []VectorChunk) error
+	// Search 在章节号小于 beforeChapter 的片段中检索与 query 最相似的 limit 个
+	Search(ctx context.Context, projectID, model string, query []float32, beforeChapter, limit int) ([]VectorMatch, error)
+	// IndexedChapters 已建立索引的章节号（升序）
+	IndexedChapters(ctx context.Context, projectID string) ([]int, error)
+	// DeleteProject 删除项目的全部片段
+	DeleteProject(ctx context.Context, projectID string) error
+}
+
+// MemoryVectorStore 进程内的向量索引，服务重启后需要重建；用于调试或未安装 pgvector 的环境
+type MemoryVectorStore struct {
+	mu     sync.RWMutex
+	chunks map[string]map[int][]VectorChunk // 项目 ID -> 章节号 -> 片段
+}
+
+// NewMemoryVectorStore 创建进程内向量索引
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{chunks: make(map[string]map[int][]VectorChunk)}
+}
+
+// ReplaceChapter 替换某一章的全部片段
+func (s *MemoryVectorStore) ReplaceChapter(ctx context.Context, projectID string, chapterNumber int, chunks []VectorChunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	project, ok := s.chunks[projectID]
+	if !ok {
+		project = make(map[int][]VectorChunk)
+		s.chunks[projectID] = project
+	}
+	if len(chunks) == 0 {
+		delete(project, chapterNumber)
+		return nil
+	}
+	project[chapterNumber] = append([]VectorChunk(nil), chunks...)
+	return nil
+}
+
+// Search 逐一计算余弦相似度
+func (s *MemoryVectorStore) Search(ctx context.Context, projectID, model string, query []float32, beforeChapter, limit int) ([]VectorMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matches []VectorMatch
+	for number, chunks := range s.chunks[projectID] {
+		if number >= beforeChapter {
+			continue
+		}
+		for _, chunk := range chunks {
+			if chunk.Model != model || len(chunk.Embedding) != len(query) {
+				continue
+			}
+			matches = append(matches, VectorMatch{
+				ChapterNumber: chunk.ChapterNumber,
+				ChunkIndex:    chunk.ChunkIndex,
+				Content:       chunk.Content,
+				Score:         llm.CosineSimilarity(query, chunk.Embedding),
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// DeleteProject 删除项目的全部片段
+func (s *MemoryVectorStore) DeleteProject(ctx context.Context, projectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chunks, projectID)
+	return nil
+}
+
+// IndexedChapters 已建立索引的章节号
+func (s *MemoryVectorStore) IndexedChapters(ctx context.Context, projectID string) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	numbers := make([]int, 0, len(s.chunks[projectID]))
+	for number := range s.chunks[projectID] {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
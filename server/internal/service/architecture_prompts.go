@@ -207,3 +207,20 @@ func GetArchitecturePrompt(step string, params ArchitecturePromptParams) string
 		return ""
 	}
 }
+
+// BuildArchitectureRevisionPrompt 单步重新生成的提示词：有修改要求时附上当前版本，要求按意见修订
+func BuildArchitectureRevisionPrompt(basePrompt, current, instruction string) string {
+	if strings.TrimSpace(instruction) == "" {
+		return basePrompt
+	}
+	if current == "" {
+		return fmt.Sprintf("%s\n\n补充要求：\n%s", basePrompt, instruction)
+	}
+	return fmt.Sprintf(`%s
+
+以下是当前版本：
+%s
+
+请根据以下修改意见重新编写（保持与上游设定一致，仍只返回正文，不要解释修改了什么）：
+%s`, basePrompt, current, instruction)
+}
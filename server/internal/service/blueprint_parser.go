@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlueprintChapter 从章节大纲中解析出的单章信息
+type BlueprintChapter struct {
+	Number        int
+	Title         string
+	Position      string // 本章定位
+	Purpose       string // 核心作用
+	Suspense      string // 悬念密度 / 情感强度
+	Foreshadowing string // 伏笔操作
+	TwistLevel    string // 情节张力 / 认知颠覆
+	Summary       string // 本章简述
+}
+
+// BlueprintParseIssue 未能解析的章节
+type BlueprintParseIssue struct {
+	ChapterNumber int
+	Line          int // 在大纲中的行号（从 1 开始），缺失的章节为 0
+	Text          string
+	Reason        string
+}
+
+var (
+	// 第n章 - [标题]，兼容 Markdown 标题、加粗与中文数字
+	blueprintHeadingRe = regexp.MustCompile(`^[#*>\s]*第\s*([0-9０-９一二三四五六七八九十百千零〇两]+)\s*章(.*)$`)
+	// 本章定位：xxx，兼容列表符号、加粗与半角冒号
+	blueprintFieldRe = regexp.MustCompile(`^[-*•>\s]*\**\s*(本章定位|核心作用|悬念密度|情感强度|伏笔操作|认知颠覆|情节张力|本章简述)\s*\**\s*[：:]\s*\**\s*(.*)$`)
+)
+
+// ParseBlueprint 解析"第n章 - [标题] / 本章定位 / 核心作用 / 伏笔操作 / 情节张力"格式的章节大纲。
+// chapterCount 大于 0 时，超出范围的章节与缺失的章节也会作为问题返回
+func ParseBlueprint(text string, chapterCount int) ([]BlueprintChapter, []BlueprintParseIssue) {
+	var (
+		chapters []BlueprintChapter
+		issues   []BlueprintParseIssue
+		current  *BlueprintChapter
+		fields   int    // 当前章节已识别的字段数
+		heading  string // 当前章节标题行
+		line     int    // 当前章节标题行号
+		lastSet  func(string)
+		seen     = make(map[int]bool)
+	)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		switch {
+		case fields == 0:
+			issues = append(issues, BlueprintParseIssue{ChapterNumber: current.Number, Line: line, Text: heading, Reason: "未识别到大纲字段"})
+		case seen[current.Number]:
+			issues = append(issues, BlueprintParseIssue{ChapterNumber: current.Number, Line: line, Text: heading, Reason: "章节号重复，已忽略"})
+		case chapterCount > 0 && current.Number > chapterCount:
+			issues = append(issues, BlueprintParseIssue{ChapterNumber: current.Number, Line: line, Text: heading, Reason: fmt.Sprintf("超出项目章节数（%d）", chapterCount)})
+		default:
+			// 换行拼接的字段值需要再次去掉外层括号
+			for _, field := range []*string{&current.Position, &current.Purpose, &current.Suspense, &current.Foreshadowing, &current.TwistLevel, &current.Summary} {
+				*field = cleanBlueprintValue(*field)
+			}
+			seen[current.Number] = true
+			chapters = append(chapters, *current)
+		}
+		current = nil
+		lastSet = nil
+	}
+
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+
+		if m := blueprintHeadingRe.FindStringSubmatch(trimmed); m != nil && !blueprintFieldRe.MatchString(trimmed) {
+			flush()
+			heading, line = trimmed, i+1
+			number, ok := parseChapterNumber(m[1])
+			if !ok || number <= 0 {
+				issues = append(issues, BlueprintParseIssue{Line: line, Text: heading, Reason: "无法识别章节号"})
+				continue
+			}
+			current = &BlueprintChapter{Number: number, Title: cleanBlueprintTitle(m[2])}
+			fields = 0
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if m := blueprintFieldRe.FindStringSubmatch(trimmed); m != nil {
+			target := blueprintField(current, m[1])
+			value := cleanBlueprintValue(m[2])
+			// 同一含义的字段出现多次时保留第一个（如同时有悬念密度与情感强度）
+			if *target == "" {
+				*target = value
+				lastSet = func(more string) { *target += more }
+			} else {
+				lastSet = nil
+			}
+			fields++
+			continue
+		}
+
+		// 字段值换行时拼接到上一个字段
+		if lastSet != nil {
+			lastSet(cleanBlueprintValue(trimmed))
+		}
+	}
+	flush()
+
+	if chapterCount > 0 {
+		for n := 1; n <= chapterCount; n++ {
+			if !seen[n] && !hasIssue(issues, n) {
+				issues = append(issues, BlueprintParseIssue{ChapterNumber: n, Reason: "大纲中缺少该章"})
+			}
+		}
+	}
+
+	return chapters, issues
+}
+
+// blueprintField 字段名对应的章节字段
+func blueprintField(ch *BlueprintChapter, label string) *string {
+	switch label {
+	case "本章定位":
+		return &ch.Position
+	case "核心作用":
+		return &ch.Purpose
+	case "悬念密度", "情感强度":
+		return &ch.Suspense
+	case "伏笔操作":
+		return &ch.Foreshadowing
+	case "情节张力", "认知颠覆":
+		return &ch.TwistLevel
+	default:
+		return &ch.Summary
+	}
+}
+
+// cleanBlueprintTitle 去掉标题前的分隔符、加粗与外层括号
+func cleanBlueprintTitle(s string) string {
+	s = strings.TrimSpace(strings.Trim(s, "*"))
+	s = strings.TrimLeft(s, "-—–:：、. \t")
+	return cleanBlueprintValue(s)
+}
+
+// cleanBlueprintValue 去掉加粗标记与整体包裹的括号
+func cleanBlueprintValue(s string) string {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "*"))
+	for _, pair := range [][2]string{{"[", "]"}, {"【", "】"}, {"《", "》"}} {
+		if strings.HasPrefix(s, pair[0]) && strings.HasSuffix(s, pair[1]) && strings.Count(s, pair[0]) == 1 {
+			s = strings.TrimSpace(s[len(pair[0]) : len(s)-len(pair[1])])
+		}
+	}
+	return s
+}
+
+func hasIssue(issues []BlueprintParseIssue, number int) bool {
+	for _, issue := range issues {
+		if issue.ChapterNumber == number {
+			return true
+		}
+	}
+	return false
+}
+
+var chineseDigits = map[rune]int{
+	'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+var chineseUnits = map[rune]int{'十': 10, '百': 100, '千': 1000}
+
+// parseChapterNumber 解析阿拉伯数字（含全角）或中文数字章节号
+func parseChapterNumber(s string) (int, bool) {
+	s = strings.Map(func(r rune) rune {
+		if r >= '０' && r <= '９' {
+			return r - '０' + '0'
+		}
+		return r
+	}, s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+
+	total, digit := 0, 0
+	for _, r := range s {
+		if d, ok := chineseDigits[r]; ok {
+			digit = d
+			continue
+		}
+		unit, ok := chineseUnits[r]
+		if !ok {
+			return 0, false
+		}
+		// "十二" 中省略的一
+		if digit == 0 {
+			digit = 1
+		}
+		total += digit * unit
+		digit = 0
+	}
+	return total + digit, true
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"x-novel/internal/dto"
@@ -18,29 +19,66 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrChapterHasContent 章节已有内容且未设置覆盖
+	ErrChapterHasContent = errors.New("章节已有内容，如需重新生成请设置 overwrite=true")
+	// ErrChapterEmpty 章节还没有正文
+	ErrChapterEmpty = errors.New("章节内容为空")
+	// ErrChapterJobActive 章节正在后台生成
+	ErrChapterJobActive = errors.New("章节正在后台生成，请等待任务结束或取消后再试")
+)
+
 // ChapterService 章节服务
 type ChapterService struct {
-	projectRepo *repository.ProjectRepository
-	chapterRepo *repository.ChapterRepository
-	modelRepo   *repository.ModelConfigRepository
-	llmManager  *llm.Manager
+	projectRepo        projectStore
+	chapterRepo        chapterStore
+	characterStateRepo characterStateStore
+	draftRepo          chapterDraftStore
+	invoker            *ModelInvoker
+	retriever          *Retriever
+	opts               ChapterOptions
+}
+
+// ChapterOptions 章节生成配置
+type ChapterOptions struct {
+	SummaryTokenBudget int // 全局摘要的 token 预算，超出时压缩较早章节的摘要
+	PreviousParagraphs int // 生成时附带的上一章结尾段落数
+	PreviousMaxChars   int // 上一章结尾原文的最大字数
 }
 
 // NewChapterService 创建章节服务
 func NewChapterService(
 	projectRepo *repository.ProjectRepository,
 	chapterRepo *repository.ChapterRepository,
-	modelRepo *repository.ModelConfigRepository,
-	llmManager *llm.Manager,
+	characterStateRepo *repository.CharacterStateRepository,
+	draftRepo *repository.ChapterDraftRepository,
+	invoker *ModelInvoker,
+	opts ChapterOptions,
 ) *ChapterService {
+	if opts.SummaryTokenBudget <= 0 {
+		opts.SummaryTokenBudget = defaultSummaryTokenBudget
+	}
+	if opts.PreviousParagraphs <= 0 {
+		opts.PreviousParagraphs = defaultPreviousParagraphs
+	}
+	if opts.PreviousMaxChars <= 0 {
+		opts.PreviousMaxChars = defaultPreviousMaxChars
+	}
 	return &ChapterService{
-		projectRepo: projectRepo,
-		chapterRepo: chapterRepo,
-		modelRepo:   modelRepo,
-		llmManager:  llmManager,
+		projectRepo:        projectRepo,
+		chapterRepo:        chapterRepo,
+		characterStateRepo: characterStateRepo,
+		draftRepo:          draftRepo,
+		invoker:            invoker,
+		opts:               opts,
 	}
 }
 
+// SetRetriever 设置前文检索，为 nil 时不检索也不建立索引
+func (s *ChapterService) SetRetriever(retriever *Retriever) {
+	s.retriever = retriever
+}
+
 // Create 创建章节
 func (s *ChapterService) Create(ctx context.Context, projectID string, req *dto.CreateChapterRequest) (*model.Chapter, error) {
 	// 检查项目是否存在
@@ -153,6 +191,9 @@ func (s *ChapterService) Update(ctx context.Context, id string, req *dto.UpdateC
 	if req.BlueprintSummary != nil {
 		chapter.BlueprintSummary = *req.BlueprintSummary
 	}
+	if req.Summary != nil {
+		chapter.Summary = *req.Summary
+	}
 
 	if err := s.chapterRepo.Update(ctx, chapter); err != nil {
 		logger.Error("更新章节失败",
@@ -179,14 +220,64 @@ func (s *ChapterService) Delete(ctx context.Context, id string) error {
 
 // GenerateChapterContent 生成章节内容
 func (s *ChapterService) GenerateChapterContent(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.GenerateChapterRequest) (*model.Chapter, error) {
-	chapter, err := s.chapterRepo.GetByID(ctx, chapterID)
+	chapter, invokeReq, err := s.prepareChapterGeneration(ctx, deviceID, projectID, chapterID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调用 LLM
+	ctx = llm.TrackSynthetic(ctx)
+	content, err := s.invoker.Chat(ctx, invokeReq)
+	if err != nil {
+		logger.Error("LLM 调用失败", zap.Error(err))
+		return nil, fmt.Errorf("生成章节内容失败: %w", err)
+	}
+
+	return s.saveGeneratedContent(ctx, chapter, content)
+}
+
+// ChapterStream 已通过前置检查、尚未调用模型的流式生成；
+// 调用方可以在检查失败时直接返回错误，检查通过后再开始推送
+type ChapterStream struct {
+	run func(ctx context.Context, callback llm.StreamCallback) (*model.Chapter, error)
+}
+
+// Run 调用模型并逐段回调，返回保存后的章节；中断时返回的章节包含已保存的部分内容
+func (cs *ChapterStream) Run(ctx context.Context, callback llm.StreamCallback) (*model.Chapter, error) {
+	return cs.run(ctx, callback)
+}
+
+// PrepareChapterContentStream 检查前置条件并准备流式生成章节内容：逐段回调并定期保存已生成的部分，
+// 中断（包括客户端断开）时保留已生成的内容
+func (s *ChapterService) PrepareChapterContentStream(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.GenerateChapterRequest) (*ChapterStream, error) {
+	chapter, invokeReq, err := s.prepareChapterGeneration(ctx, deviceID, projectID, chapterID, req)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.invoker.Precheck(ctx, invokeReq); err != nil {
+		return nil, err
+	}
+
+	return &ChapterStream{run: func(ctx context.Context, callback llm.StreamCallback) (*model.Chapter, error) {
+		ctx = llm.TrackSynthetic(ctx)
+		content, err := s.streamContent(ctx, chapter, invokeReq, callback)
+		if err != nil {
+			logger.Error("LLM 流式调用失败", zap.Error(err))
+			return chapter, fmt.Errorf("生成章节内容失败: %w", err)
+		}
+		return s.saveGeneratedContent(ctx, chapter, content)
+	}}, nil
+}
+
+// prepareChapterGeneration 检查前置条件并构建章节生成的模型调用
+func (s *ChapterService) prepareChapterGeneration(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.GenerateChapterRequest) (*model.Chapter, *InvokeRequest, error) {
+	chapter, err := s.chapterRepo.GetByID(ctx, chapterID)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// 检查是否已有内容
-	if chapter.Content != "" && !req.Overwrite {
-		return nil, errors.New("章节已有内容，如需重新生成请设置 overwrite=true")
+	if err := checkChapterGenerate(chapter, req.Overwrite); err != nil {
+		return nil, nil, err
 	}
 
 	logger.Info("开始生成章节内容",
@@ -198,17 +289,7 @@ func (s *ChapterService) GenerateChapterContent(ctx context.Context, deviceID uu
 	// 获取项目信息
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
-		return nil, err
-	}
-
-	// 获取用于章节生成的模型配置
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "chapter")
-	if err != nil {
-		logger.Error("获取章节生成模型配置失败", zap.Error(err))
-
-		// 开发模式：返回模拟数据
-		logger.Info("使用模拟模式生成章节内容")
-		return s.generateMockChapterContent(ctx, project, chapter, req)
+		return nil, nil, err
 	}
 
 	// 解析 Genre
@@ -219,8 +300,49 @@ func (s *ChapterService) GenerateChapterContent(ctx context.Context, deviceID uu
 		}
 	}
 
+	// 使用上一章定稿后的角色状态
+	characterState, err := s.characterStateBefore(ctx, project, chapter.ChapterNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 上一章的摘要与结尾原文，衔接本章开头
+	previousSummary, previousTail, warnings := s.previousChapterContext(ctx, projectID, chapter)
+	chapter.GenerationWarnings = warnings
+
+	// 与本章大纲及出场角色相关的更早章节片段
+	passages := s.retrievePassages(ctx, deviceID, project, chapter)
+
 	// 构建提示词参数
-	params := ChapterPromptParams{
+	params := chapterPromptParams(project, genres, chapter, characterState)
+	params.PreviousSummary = previousSummary
+	params.PreviousTail = previousTail
+	params.RetrievedPassages = passages
+	if passages != "" {
+		// 更早章节由检索片段提供细节，全局摘要只保留前情概要与最近几章
+		summary, err := s.retrievalGlobalSummary(ctx, project, chapter.ChapterNumber)
+		if err != nil {
+			logger.Warn("精简全局摘要失败，使用完整的全局摘要", zap.String("project_id", projectID), zap.Error(err))
+		} else {
+			params.GlobalSummary = summary
+		}
+	}
+
+	// 获取提示词
+	prompt := GetChapterPrompt(chapter.ChapterNumber, params)
+	reportProgress(ctx, "生成章节正文", 1, 1)
+
+	return chapter, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: project.ID.String(),
+		Purpose:   PurposeChapter,
+		Messages:  userMessages(prompt),
+	}, nil
+}
+
+// chapterPromptParams 由项目与章节信息构建章节生成的提示词参数，上一章衔接与检索片段由调用方补充
+func chapterPromptParams(project *model.Project, genres []string, chapter *model.Chapter, characterState string) ChapterPromptParams {
+	return ChapterPromptParams{
 		Title:             project.Title,
 		Topic:             project.Topic,
 		Genre:             genres,
@@ -230,48 +352,20 @@ func (s *ChapterService) GenerateChapterContent(ctx context.Context, deviceID uu
 		CharacterDynamics: project.CharacterDynamics,
 		WorldBuilding:     project.WorldBuilding,
 		PlotArchitecture:  project.PlotArchitecture,
-		CharacterState:    project.CharacterState,
+		CharacterState:    characterState,
 		ChapterNumber:     chapter.ChapterNumber,
 		ChapterTitle:      chapter.Title,
 		BlueprintSummary:  chapter.BlueprintSummary,
 		GlobalSummary:     project.GlobalSummary,
 	}
+}
 
-	// 获取提示词
-	prompt := GetChapterPrompt(chapter.ChapterNumber, params)
-
-	// 构建消息
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: prompt},
-	}
-
-	// 调用 LLM
-	options := llm.ChatOptions{
-		Temperature: 0.8,
-		MaxTokens:   8000,
-		APIKey:      modelConfig.APIKey,
-	}
-
-	var content string
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		content, err = adapter.ChatCompletion(ctx, messages, options)
-	} else {
-		provider := "openai"
-		if modelConfig.Provider != nil {
-			provider = modelConfig.Provider.Name
-		}
-		content, err = s.llmManager.ChatCompletion(ctx, provider, messages, options)
-	}
-
-	if err != nil {
-		logger.Error("LLM 调用失败", zap.Error(err))
-		return nil, fmt.Errorf("生成章节内容失败: %w", err)
-	}
-
-	// 更新章节
+// saveGeneratedContent 保存新生成的章节正文
+func (s *ChapterService) saveGeneratedContent(ctx context.Context, chapter *model.Chapter, content string) (*model.Chapter, error) {
 	chapter.Content = content
 	chapter.WordCount = utf8.RuneCountInString(content)
+	chapter.Synthetic = llm.IsSynthetic(ctx)
+	chapter.PartialContent = ""
 	chapter.Status = "draft"
 
 	if err := s.chapterRepo.Update(ctx, chapter); err != nil {
@@ -280,26 +374,6 @@ func (s *ChapterService) GenerateChapterContent(ctx context.Context, deviceID uu
 	}
 
 	logger.Info("章节内容生成完成",
-		zap.String("chapter_id", chapterID),
-		zap.Int("word_count", chapter.WordCount),
-	)
-
-	return chapter, nil
-}
-
-// generateMockChapterContent 生成模拟章节内容
-func (s *ChapterService) generateMockChapterContent(ctx context.Context, project *model.Project, chapter *model.Chapter, req *dto.GenerateChapterRequest) (*model.Chapter, error) {
-	// 生成模拟章节内容
-	chapter.Content = s.generateMockChapterText(project, chapter.ChapterNumber)
-	chapter.WordCount = utf8.RuneCountInString(chapter.Content)
-	chapter.Status = "draft"
-
-	if err := s.chapterRepo.Update(ctx, chapter); err != nil {
-		logger.Error("保存模拟章节内容失败", zap.Error(err))
-		return nil, err
-	}
-
-	logger.Info("模拟章节内容生成完成",
 		zap.String("chapter_id", chapter.ID.String()),
 		zap.Int("word_count", chapter.WordCount),
 	)
@@ -307,69 +381,64 @@ func (s *ChapterService) generateMockChapterContent(ctx context.Context, project
 	return chapter, nil
 }
 
-// generateMockChapterText 生成模拟章节文本
-func (s *ChapterService) generateMockChapterText(project *model.Project, chapterNumber int) string {
-	// 根据章节号生成不同内容
-	if chapterNumber == 1 {
-		return `深夜的办公室里，只剩下李明一个人还在加班。显示器的蓝光映在他疲惫的脸上，键盘的敲击声在空荡的办公室里回荡。
-
-"终于找到这个bug了。"李明长舒一口气，靠在椅背上。
-
-就在他准备关电脑下班的时候，一段奇怪的代码引起了他的注意。这段代码像是凭空出现的，隐藏在一个不起眼的工具函数里。李明好奇地把这段代码复制到编辑器里，仔细查看起来。
-
-代码的逻辑很简单，但却透着一股诡异的美感。它像是一个神经网络，又像是一种加密算法。李明鬼使神差地按下了运行键。
-
-就在那一瞬间，世界变了。
-
-李明眼前的景象开始扭曲，所有的物体都变成了流动的数据流。他看到电脑里的数据像潮水一样涌出，在空中编织成复杂的图案。桌上的咖啡杯分解成无数个粒子，然后又重新组合。
-
-"这...这是怎么回事？"李明惊恐地看着自己的双手，它们也在散发着淡淡的光芒。
-
-就在这时，办公室的门被推开了。一个穿着黑色西装的男人走了进来，他的眼神冰冷，仿佛在看一个死人。
-
-"你动了不该动的东西，"男人说道，声音里没有一丝感情。`
+// chapterStreamSaveInterval 流式生成时保存部分内容的间隔
+const chapterStreamSaveInterval = 5 * time.Second
+
+// streamContent 流式调用模型，已输出的内容定期保存到 PartialContent；
+// 出错或中断时保存已输出的部分：章节原本没有正文时直接作为草稿正文，否则保留在 PartialContent 中
+func (s *ChapterService) streamContent(ctx context.Context, chapter *model.Chapter, req *InvokeRequest, callback llm.StreamCallback) (string, error) {
+	chapterID := chapter.ID.String()
+	var buf strings.Builder
+	lastSave := time.Now()
+
+	content, err := s.invoker.Stream(ctx, req, func(chunk string) error {
+		// 先记录再回调，客户端写入失败时这段内容同样会被保存
+		buf.WriteString(chunk)
+		if time.Since(lastSave) >= chapterStreamSaveInterval {
+			lastSave = time.Now()
+			if err := s.chapterRepo.UpdatePartialContent(ctx, chapterID, buf.String()); err != nil {
+				logger.Warn("保存部分内容失败", zap.String("chapter_id", chapterID), zap.Error(err))
+			}
+		}
+		return callback(chunk)
+	})
+	if err == nil {
+		return content, nil
 	}
 
-	if chapterNumber == 2 {
-		return `李明本能地向后退去，但发现自己的身体像是被什么东西束缚住了。那个黑衣男人抬起手，李明就感觉一股强大的力量将自己提了起来，悬在半空中。
-
-"放下我！"李明挣扎着，但那股力量纹丝不动。
-
-"你以为这是什么？儿童玩具吗？"黑衣男人冷笑着，"这是改变世界的钥匙，而你，刚好打开了它。"
-
-李明的心跳加速，他意识到自己可能卷入了一个巨大的麻烦。他闭上眼睛，试图理解刚才发生的一切。那些流动的数据，那些变化的景象...难道自己获得了超能力？
-
-就在这个念头闪过的瞬间，李明感觉到周围的一切都慢了下来。他看到了空气中流动的数据粒子，看到了黑衣男人身上发出的能量波动。
-
-"放开他！"
-
-一个清脆的女声打破了沉默。李明睁开眼睛，看到一个年轻女子冲了进来。她留着短发，眼神锐利，动作敏捷。
-
-黑衣男人皱了皱眉，"苏小雨，你背叛组织了？"
-
-"我只是不想看到无辜的人被伤害，"苏小雨说道，同时向李明使了个眼色，"快走！"
-
-李明不知道自己哪来的勇气，但他感觉到一股力量在体内涌动。他猛地挣脱了束缚，向门口冲去。黑衣男人想要追赶，但被苏小雨拦住了。
-
-"走！"苏小雨喊道。
-
-李明冲出了办公室，在走廊里狂奔。他的心脏剧烈地跳动着，脑海里充满了问号。这到底是怎么回事？自己为什么会卷入这种事情？那个叫苏小雨的女人又是谁？`
+	partial := buf.String()
+	if partial == "" {
+		return "", err
 	}
 
-	// 通用章节内容
-	return fmt.Sprintf(`第%d章的内容正在生成中...
-
-李明走在空荡的街道上，夜风吹过，带来一丝凉意。他还在思考着发生的一切，那些超能力的真相，那个神秘的组织，还有苏小雨...
-
-"这一切都太不真实了，"李明自言自语道。
-
-但他知道，这一切都是真实的。从那一刻他运行了那段代码，他的人生就彻底改变了。现在，他必须面对这个全新的世界，学会使用自己的能力，并且找到活下去的方法。
-
-街道尽头的路灯闪烁着，像是某种信号。李明停下脚步，感觉到了什么。有人在跟踪他。
-
-"出来吧，"李明转过身，"我知道你在那里。"
+	// 客户端断开时请求上下文已取消，保存不应随之失败
+	saveCtx := context.WithoutCancel(ctx)
+	if chapter.Content == "" {
+		chapter.Content = partial
+		chapter.WordCount = utf8.RuneCountInString(partial)
+		chapter.Synthetic = llm.IsSynthetic(ctx)
+		chapter.PartialContent = ""
+		chapter.Status = "draft"
+	} else {
+		chapter.PartialContent = partial
+	}
+	if saveErr := s.chapterRepo.Update(saveCtx, chapter); saveErr != nil {
+		logger.Error("保存中断的章节内容失败", zap.String("chapter_id", chapterID), zap.Error(saveErr))
+	} else {
+		logger.Info("流式生成中断，已保存部分内容",
+			zap.String("chapter_id", chapterID),
+			zap.Int("partial_words", utf8.RuneCountInString(partial)),
+		)
+	}
+	return "", err
+}
 
-黑暗中，一个身影缓缓走了出来...`, chapterNumber)
+// checkChapterGenerate 检查能否生成章节内容
+func checkChapterGenerate(chapter *model.Chapter, overwrite bool) error {
+	if chapter.Content != "" && !overwrite {
+		return ErrChapterHasContent
+	}
+	return nil
 }
 
 // FinalizeChapter 定稿章节
@@ -391,53 +460,100 @@ func (s *ChapterService) FinalizeChapter(ctx context.Context, deviceID uuid.UUID
 	if err := s.chapterRepo.SetFinalized(ctx, chapterID, true); err != nil {
 		return nil, err
 	}
+	// 前文摘要按已完成的章节生成
+	if err := s.chapterRepo.UpdateStatus(ctx, chapterID, chapter.Status); err != nil {
+		return nil, err
+	}
 
 	logger.Info("章节定稿完成",
 		zap.String("chapter_id", chapterID),
 		zap.Int("word_count", chapter.WordCount),
 	)
 
-	// 如果需要更新全局摘要
+	// 生成本章摘要并更新全局摘要，失败不影响定稿
 	if req.UpdateSummary {
-		// 获取所有已定稿的章节
-		chapters, err := s.chapterRepo.ListCompleted(ctx, projectID, 0)
-		if err == nil && len(chapters) > 0 {
-			// 生成全局摘要
-			globalSummary := s.generateGlobalSummary(chapters)
-			if err := s.projectRepo.UpdateGlobalSummary(ctx, projectID, globalSummary); err != nil {
-				logger.Warn("更新全局摘要失败", zap.Error(err))
-			}
+		summary, err := s.summarizeChapter(ctx, deviceID, projectID, chapter)
+		if err != nil {
+			logger.Warn("生成章节摘要失败", zap.String("chapter_id", chapterID), zap.Error(err))
+		} else if err := s.chapterRepo.UpdateSummary(ctx, chapterID, summary); err != nil {
+			logger.Warn("保存章节摘要失败", zap.String("chapter_id", chapterID), zap.Error(err))
+		} else {
+			chapter.Summary = summary
 		}
-	}
 
-	return chapter, nil
-}
+		if err := s.refreshGlobalSummary(ctx, deviceID, projectID, chapter.ChapterNumber); err != nil {
+			logger.Warn("更新全局摘要失败", zap.String("project_id", projectID), zap.Error(err))
+		}
+	}
 
-// generateGlobalSummary 生成全局摘要
-func (s *ChapterService) generateGlobalSummary(chapters []*model.Chapter) string {
-	var summary strings.Builder
-	summary.WriteString("// 全局剧情摘要\n\n")
+	// 根据本章内容更新角色状态，下一章基于更新后的状态生成；失败不影响定稿
+	if req.UpdateCharacterState {
+		if _, err := s.updateCharacterState(ctx, deviceID, projectID, chapter); err != nil {
+			logger.Warn("更新角色状态失败", zap.String("chapter_id", chapterID), zap.Error(err))
+		}
+	}
 
-	for _, chapter := range chapters {
-		summary.WriteString(fmt.Sprintf("第%d章：%s\n", chapter.ChapterNumber, chapter.Title))
-		if chapter.BlueprintSummary != "" {
-			summary.WriteString(fmt.Sprintf("摘要：%s\n\n", chapter.BlueprintSummary))
+	// 建立本章的检索索引，供后续章节检索；失败不影响定稿
+	if s.retriever != nil {
+		if _, err := s.retriever.IndexChapter(ctx, deviceID, chapter); errors.Is(err, ErrEmbeddingNotConfigured) {
+			logger.Debug("未绑定嵌入模型，跳过章节索引", zap.String("chapter_id", chapterID))
+		} else if err != nil {
+			logger.Warn("建立章节索引失败", zap.String("chapter_id", chapterID), zap.Error(err))
 		}
 	}
 
-	return summary.String()
+	return chapter, nil
 }
 
 // EnrichChapter 扩写章节
 func (s *ChapterService) EnrichChapter(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.EnrichChapterRequest) (*model.Chapter, error) {
-	chapter, err := s.chapterRepo.GetByID(ctx, chapterID)
+	chapter, invokeReq, err := s.prepareEnrich(ctx, deviceID, projectID, chapterID, req)
 	if err != nil {
 		return nil, err
 	}
 
+	// 调用 LLM
+	ctx = llm.TrackSynthetic(ctx)
+	content, err := s.invoker.Chat(ctx, invokeReq)
+	if err != nil {
+		logger.Error("LLM 调用失败", zap.Error(err))
+		return nil, fmt.Errorf("扩写章节内容失败: %w", err)
+	}
+
+	return s.saveEnrichedContent(ctx, chapter, content)
+}
+
+// PrepareEnrichStream 检查前置条件并准备流式扩写章节，中断时扩写结果保留在 PartialContent 中，不覆盖原正文
+func (s *ChapterService) PrepareEnrichStream(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.EnrichChapterRequest) (*ChapterStream, error) {
+	chapter, invokeReq, err := s.prepareEnrich(ctx, deviceID, projectID, chapterID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.invoker.Precheck(ctx, invokeReq); err != nil {
+		return nil, err
+	}
+
+	return &ChapterStream{run: func(ctx context.Context, callback llm.StreamCallback) (*model.Chapter, error) {
+		ctx = llm.TrackSynthetic(ctx)
+		content, err := s.streamContent(ctx, chapter, invokeReq, callback)
+		if err != nil {
+			logger.Error("LLM 流式调用失败", zap.Error(err))
+			return chapter, fmt.Errorf("扩写章节内容失败: %w", err)
+		}
+		return s.saveEnrichedContent(ctx, chapter, content)
+	}}, nil
+}
+
+// prepareEnrich 检查前置条件并构建扩写的模型调用
+func (s *ChapterService) prepareEnrich(ctx context.Context, deviceID uuid.UUID, projectID, chapterID string, req *dto.EnrichChapterRequest) (*model.Chapter, *InvokeRequest, error) {
+	chapter, err := s.chapterRepo.GetByID(ctx, chapterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// 检查章节是否有内容
 	if chapter.Content == "" {
-		return nil, errors.New("章节内容为空，无法扩写")
+		return nil, nil, fmt.Errorf("%w，无法扩写", ErrChapterEmpty)
 	}
 
 	logger.Info("开始扩写章节内容",
@@ -446,20 +562,10 @@ func (s *ChapterService) EnrichChapter(ctx context.Context, deviceID uuid.UUID,
 		zap.Int("target_words", req.TargetWords),
 	)
 
-	// 获取用于章节生成的模型配置
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "chapter")
-	if err != nil {
-		logger.Error("获取章节生成模型配置失败", zap.Error(err))
-
-		// 开发模式：返回模拟数据
-		logger.Info("使用模拟模式扩写章节内容")
-		return s.enrichMockChapter(ctx, chapter, req.TargetWords)
-	}
-
 	// 获取项目信息
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 解析 Genre
@@ -486,38 +592,20 @@ func (s *ChapterService) EnrichChapter(ctx context.Context, deviceID uuid.UUID,
 	// 获取扩写提示词
 	prompt := GetEnrichPrompt(params)
 
-	// 构建消息
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: prompt},
-	}
-
-	// 调用 LLM
-	options := llm.ChatOptions{
-		Temperature: 0.7,
-		MaxTokens:   10000,
-		APIKey:      modelConfig.APIKey,
-	}
-
-	var content string
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		content, err = adapter.ChatCompletion(ctx, messages, options)
-	} else {
-		provider := "openai"
-		if modelConfig.Provider != nil {
-			provider = modelConfig.Provider.Name
-		}
-		content, err = s.llmManager.ChatCompletion(ctx, provider, messages, options)
-	}
-
-	if err != nil {
-		logger.Error("LLM 调用失败", zap.Error(err))
-		return nil, fmt.Errorf("扩写章节内容失败: %w", err)
-	}
+	return chapter, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   PurposeEnrich,
+		Messages:  userMessages(prompt),
+	}, nil
+}
 
-	// 更新章节
+// saveEnrichedContent 保存扩写后的章节正文
+func (s *ChapterService) saveEnrichedContent(ctx context.Context, chapter *model.Chapter, content string) (*model.Chapter, error) {
 	chapter.Content = content
 	chapter.WordCount = utf8.RuneCountInString(content)
+	chapter.Synthetic = llm.IsSynthetic(ctx)
+	chapter.PartialContent = ""
 
 	if err := s.chapterRepo.Update(ctx, chapter); err != nil {
 		logger.Error("保存扩写章节内容失败", zap.Error(err))
@@ -525,37 +613,8 @@ func (s *ChapterService) EnrichChapter(ctx context.Context, deviceID uuid.UUID,
 	}
 
 	logger.Info("章节扩写完成",
-		zap.String("chapter_id", chapterID),
-		zap.Int("word_count", chapter.WordCount),
-	)
-
-	return chapter, nil
-}
-
-// enrichMockChapter 模拟扩写章节
-func (s *ChapterService) enrichMockChapter(ctx context.Context, chapter *model.Chapter, targetWords int) (*model.Chapter, error) {
-	// 简单模拟：在原文基础上添加一些描述性文字
-	enrichedContent := chapter.Content + `
-
-李明深深吸了一口气，空气中弥漫着夜晚特有的清冷。街道两旁的路灯投下昏黄的光影，将他的影子拉得很长。他能听到远处传来的车流声，那是城市在这个时间点唯一的声响。
-
-这一刻，李明突然意识到，自己再也无法回到过去那个平凡的生活了。那些数据流，那些超能力，都已经成为了他生命的一部分。无论是好是坏，他都必须接受这个事实。
-
-他握紧了拳头，感受着体内涌动的力量。这力量既让他感到强大，也让他感到恐惧。他不知道该如何控制它，也不知道它最终会将他带向何方。
-
-但有一点是确定的：他不能放弃。无论前方等待他的是什么，他都必须勇敢地面对。`
-
-	chapter.Content = enrichedContent
-	chapter.WordCount = utf8.RuneCountInString(enrichedContent)
-
-	if err := s.chapterRepo.Update(ctx, chapter); err != nil {
-		logger.Error("保存扩写章节内容失败", zap.Error(err))
-		return nil, err
-	}
-
-	logger.Info("模拟章节扩写完成",
 		zap.String("chapter_id", chapter.ID.String()),
-		zap.Int("new_word_count", chapter.WordCount),
+		zap.Int("word_count", chapter.WordCount),
 	)
 
 	return chapter, nil
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"x-novel/internal/dto"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxChapterBatchSize 单次批量生成的最大章节数
+const maxChapterBatchSize = 200
+
+// ErrNothingToResume 上一次批量生成没有需要继续的章节
+var ErrNothingToResume = errors.New("上一次批量生成没有需要继续的章节")
+
+// checkChapterBatch 检查批量生成的章节范围
+func checkChapterBatch(req *dto.GenerateChapterBatchRequest) error {
+	if req.From < 1 || req.To < req.From {
+		return fmt.Errorf("章节范围无效：%d-%d", req.From, req.To)
+	}
+	if req.To-req.From+1 > maxChapterBatchSize {
+		return fmt.Errorf("单次最多批量生成 %d 章", maxChapterBatchSize)
+	}
+	return nil
+}
+
+// GenerateChapterBatch 按顺序生成一个范围内的章节：后一章依赖前一章，
+// 每章生成后定稿并更新全局摘要与角色状态（keep_draft 时保留为草稿），再生成下一章。
+// prev 为上一次执行的结果，其中已生成或已跳过的章节不再处理；
+// 每处理完一章保存一次结果，任务中断后可据此继续
+func (s *ChapterService) GenerateChapterBatch(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateChapterBatchRequest, prev *dto.ChapterBatchResponse) (*dto.ChapterBatchResponse, error) {
+	if err := checkChapterBatch(req); err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]dto.ChapterBatchItem)
+	if prev != nil {
+		for _, item := range prev.Chapters {
+			if item.Status == dto.ChapterBatchSucceeded || item.Status == dto.ChapterBatchSkipped {
+				done[item.ChapterNumber] = item
+			}
+		}
+	}
+
+	result := &dto.ChapterBatchResponse{From: req.From, To: req.To}
+	for n := req.From; n <= req.To; n++ {
+		item, ok := done[n]
+		if !ok {
+			item = dto.ChapterBatchItem{ChapterNumber: n, Status: dto.ChapterBatchPending}
+		}
+		result.Chapters = append(result.Chapters, item)
+	}
+	result.Tally()
+	reportResult(ctx, result)
+
+	logger.Info("开始批量生成章节",
+		zap.String("project_id", projectID),
+		zap.Int("from", req.From),
+		zap.Int("to", req.To),
+		zap.Int("done", len(done)),
+	)
+
+	total := len(result.Chapters)
+	for i := range result.Chapters {
+		item := &result.Chapters[i]
+		if item.Status != dto.ChapterBatchPending && item.Status != dto.ChapterBatchFailed {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		reportProgress(ctx, fmt.Sprintf("生成第 %d 章", item.ChapterNumber), i+1, total)
+		err := s.generateBatchChapter(ctx, deviceID, projectID, req, item)
+		if err != nil && ctx.Err() != nil {
+			// 任务被取消或服务关闭，本章保持待生成，继续时重新生成
+			return result, err
+		}
+		result.Tally()
+		reportResult(ctx, result)
+
+		if err != nil && req.StopOnError {
+			return result, fmt.Errorf("第 %d 章生成失败，已停止: %w", item.ChapterNumber, err)
+		}
+	}
+
+	logger.Info("批量生成章节完成",
+		zap.String("project_id", projectID),
+		zap.Int("succeeded", result.Succeeded),
+		zap.Int("skipped", result.Skipped),
+		zap.Int("failed", result.Failed),
+	)
+	return result, nil
+}
+
+// generateBatchChapter 生成批量任务中的一章，结果写入 item
+func (s *ChapterService) generateBatchChapter(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateChapterBatchRequest, item *dto.ChapterBatchItem) error {
+	fail := func(err error) error {
+		item.Status = dto.ChapterBatchFailed
+		item.Error = err.Error()
+		logger.Warn("批量生成章节失败", zap.Int("chapter_number", item.ChapterNumber), zap.Error(err))
+		return err
+	}
+
+	chapter, err := s.chapterRepo.GetByProjectAndNumber(ctx, projectID, item.ChapterNumber)
+	if err != nil {
+		return fail(errors.New("章节不存在，请先解析章节大纲"))
+	}
+	if chapter.Content != "" && req.SkipExisting {
+		item.Status = dto.ChapterBatchSkipped
+		item.Error = ""
+		item.WordCount = chapter.WordCount
+		return nil
+	}
+
+	chapter, err = s.GenerateChapterContent(ctx, deviceID, projectID, chapter.ID.String(), &dto.GenerateChapterRequest{
+		ChapterNumber: item.ChapterNumber,
+		Overwrite:     req.Overwrite,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	// 定稿并更新摘要与角色状态，下一章基于本章之后的剧情生成
+	if !req.KeepDraft {
+		if _, err := s.FinalizeChapter(ctx, deviceID, projectID, chapter.ID.String(), &dto.FinalizeChapterRequest{
+			UpdateSummary:        true,
+			UpdateCharacterState: true,
+		}); err != nil {
+			return fail(fmt.Errorf("章节已生成，但定稿失败: %w", err))
+		}
+	}
+
+	item.Status = dto.ChapterBatchSucceeded
+	item.Error = ""
+	item.Warnings = chapter.GenerationWarnings
+	item.WordCount = chapter.WordCount
+	item.Synthetic = chapter.Synthetic
+	return nil
+}
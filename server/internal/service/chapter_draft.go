@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/model"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxChapterDrafts 单次最多生成的候选稿数
+	maxChapterDrafts = 5
+	// defaultChapterDrafts 未指定数量与模型时生成的候选稿数
+	defaultChapterDrafts = 2
+	// currentDraftID 比较时表示章节当前正文
+	currentDraftID = "current"
+)
+
+var (
+	// ErrChapterDraftNotFound 候选稿不存在
+	ErrChapterDraftNotFound = errors.New("候选稿不存在")
+	// ErrChapterNotFound 章节不存在
+	ErrChapterNotFound = errors.New("章节不存在")
+)
+
+// checkChapterDrafts 检查候选稿数量并补全默认值
+func checkChapterDrafts(req *dto.GenerateChapterDraftsRequest) error {
+	if req.Count <= 0 {
+		req.Count = len(req.ModelConfigIDs)
+	}
+	if req.Count <= 0 {
+		req.Count = defaultChapterDrafts
+	}
+	if req.Count > maxChapterDrafts || len(req.ModelConfigIDs) > maxChapterDrafts {
+		return fmt.Errorf("单次最多生成 %d 个候选稿", maxChapterDrafts)
+	}
+	return nil
+}
+
+// CheckDraftModels 检查指定的模型配置是否可用
+func (s *ChapterService) CheckDraftModels(ctx context.Context, deviceID uuid.UUID, req *dto.GenerateChapterDraftsRequest) error {
+	for _, id := range req.ModelConfigIDs {
+		configID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("无效的模型配置 ID：%s", id)
+		}
+		if _, err := s.invoker.ResolveConfig(ctx, deviceID, configID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateChapterDrafts 为一章生成多个候选稿：使用同一提示词，指定了模型时依次轮流使用。
+// 候选稿单独保存，不改动章节正文；单稿失败时继续生成其余候选稿，全部失败时返回错误
+func (s *ChapterService) GenerateChapterDrafts(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateChapterDraftsRequest) (*dto.ChapterDraftsResponse, error) {
+	if err := checkChapterDrafts(req); err != nil {
+		return nil, err
+	}
+	chapter, err := s.chapterRepo.GetByProjectAndNumber(ctx, projectID, req.ChapterNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// 候选稿不覆盖正文，无需检查章节是否已有内容
+	chapter, invokeReq, err := s.prepareChapterGeneration(ctx, deviceID, projectID, chapter.ID.String(), &dto.GenerateChapterRequest{
+		ChapterNumber: req.ChapterNumber,
+		Overwrite:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.ChapterDraftsResponse{ChapterNumber: req.ChapterNumber}
+	for i := 0; i < req.Count; i++ {
+		item := dto.ChapterDraftItem{Index: i + 1, Status: dto.ChapterBatchPending}
+		if len(req.ModelConfigIDs) > 0 {
+			item.ModelConfigID = req.ModelConfigIDs[i%len(req.ModelConfigIDs)]
+		}
+		result.Drafts = append(result.Drafts, item)
+	}
+	reportResult(ctx, result)
+
+	logger.Info("开始生成章节候选稿",
+		zap.String("project_id", projectID),
+		zap.Int("chapter_number", req.ChapterNumber),
+		zap.Int("count", req.Count),
+	)
+
+	var firstErr error
+	for i := range result.Drafts {
+		item := &result.Drafts[i]
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		reportProgress(ctx, fmt.Sprintf("生成第 %d 个候选稿", item.Index), item.Index, len(result.Drafts))
+		draft, err := s.generateDraft(ctx, chapter, *invokeReq, item.ModelConfigID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			logger.Warn("生成候选稿失败",
+				zap.String("project_id", projectID),
+				zap.Int("chapter_number", req.ChapterNumber),
+				zap.Int("index", item.Index),
+				zap.Error(err),
+			)
+			item.Status = dto.ChapterBatchFailed
+			item.Error = err.Error()
+			result.Failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			item.Status = dto.ChapterBatchSucceeded
+			item.Draft = dto.ChapterDraftFromModel(draft, chapter.Content, false)
+			result.Succeeded++
+		}
+		reportResult(ctx, result)
+	}
+
+	if result.Succeeded == 0 {
+		return result, fmt.Errorf("候选稿全部生成失败: %w", firstErr)
+	}
+	logger.Info("章节候选稿生成完成",
+		zap.String("project_id", projectID),
+		zap.Int("chapter_number", req.ChapterNumber),
+		zap.Int("succeeded", result.Succeeded),
+		zap.Int("failed", result.Failed),
+	)
+	return result, nil
+}
+
+// generateDraft 生成并保存一个候选稿，configID 为空时使用章节生成绑定的模型
+func (s *ChapterService) generateDraft(ctx context.Context, chapter *model.Chapter, req InvokeRequest, configID string) (*model.ChapterDraft, error) {
+	// 同一提示词的多次生成不能命中缓存
+	req.NoCache = true
+	if configID != "" {
+		id, err := uuid.Parse(configID)
+		if err != nil {
+			return nil, fmt.Errorf("无效的模型配置 ID：%s", configID)
+		}
+		req.ModelConfigID = &id
+	}
+
+	resp, err := s.invoker.ChatDetailed(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(resp.Content) == "" {
+		return nil, errors.New("模型返回的内容为空")
+	}
+
+	draft := &model.ChapterDraft{
+		ProjectID:     chapter.ProjectID,
+		ChapterNumber: chapter.ChapterNumber,
+		Content:       resp.Content,
+		WordCount:     utf8.RuneCountInString(resp.Content),
+		Source:        model.DraftSourceGenerated,
+		ModelConfigID: &resp.ModelConfig.ID,
+		Model:         resp.ModelConfig.ModelName,
+		Synthetic:     resp.Synthetic,
+	}
+	if err := s.draftRepo.Create(ctx, draft); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// ListChapterDrafts 获取某一章的候选稿
+func (s *ChapterService) ListChapterDrafts(ctx context.Context, projectID string, chapterNumber int) ([]*dto.ChapterDraftResponse, error) {
+	chapter, err := s.draftChapter(ctx, projectID, chapterNumber)
+	if err != nil {
+		return nil, err
+	}
+	drafts, err := s.draftRepo.ListByChapter(ctx, projectID, chapterNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*dto.ChapterDraftResponse, 0, len(drafts))
+	for _, draft := range drafts {
+		resp = append(resp, dto.ChapterDraftFromModel(draft, chapter.Content, false))
+	}
+	return resp, nil
+}
+
+// CompareChapterDrafts 返回多个候选稿的全文，ID 为 current 时表示章节当前正文；
+// 恰好两个版本时附带第二个相对第一个的逐段差异
+func (s *ChapterService) CompareChapterDrafts(ctx context.Context, projectID string, chapterNumber int, ids []string) (*dto.ChapterDraftCompareResponse, error) {
+	chapter, err := s.draftChapter(ctx, projectID, chapterNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ChapterDraftCompareResponse{}
+	var contents []string
+	for _, id := range ids {
+		if id == currentDraftID {
+			resp.Drafts = append(resp.Drafts, *currentDraft(chapter))
+			contents = append(contents, chapter.Content)
+			continue
+		}
+		draft, err := s.getDraft(ctx, projectID, chapterNumber, id)
+		if err != nil {
+			return nil, err
+		}
+		resp.Drafts = append(resp.Drafts, *dto.ChapterDraftFromModel(draft, chapter.Content, true))
+		contents = append(contents, draft.Content)
+	}
+
+	if len(contents) == 2 {
+		diff := &dto.ChapterDraftDiff{Lines: diffLines(paragraphText(contents[0]), paragraphText(contents[1]))}
+		for _, line := range diff.Lines {
+			switch line.Op {
+			case dto.DiffAdd:
+				diff.Added++
+			case dto.DiffRemove:
+				diff.Removed++
+			}
+		}
+		resp.Diff = diff
+	}
+	return resp, nil
+}
+
+// PromoteChapterDraft 将候选稿写入章节正文。章节原有正文与已有候选稿都不相同时另存为候选稿，
+// 不会丢失；正文变化后章节回到草稿状态，需要重新定稿，由原正文得到的摘要、角色状态与检索索引一并移除
+func (s *ChapterService) PromoteChapterDraft(ctx context.Context, deviceID uuid.UUID, projectID string, chapterNumber int, draftID string) (*model.Chapter, error) {
+	chapter, err := s.draftChapter(ctx, projectID, chapterNumber)
+	if err != nil {
+		return nil, err
+	}
+	draft, err := s.getDraft(ctx, projectID, chapterNumber, draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chapter.Content != "" && chapter.Content != draft.Content {
+		exists, err := s.draftRepo.ExistsContent(ctx, projectID, chapterNumber, chapter.Content)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			replaced := &model.ChapterDraft{
+				ProjectID:     chapter.ProjectID,
+				ChapterNumber: chapterNumber,
+				Content:       chapter.Content,
+				WordCount:     utf8.RuneCountInString(chapter.Content),
+				Source:        model.DraftSourceReplaced,
+				Synthetic:     chapter.Synthetic,
+			}
+			if err := s.draftRepo.Create(ctx, replaced); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if chapter.Content != draft.Content {
+		wasCompleted := chapter.Status == "completed"
+		chapter.Content = draft.Content
+		chapter.WordCount = draft.WordCount
+		chapter.Synthetic = draft.Synthetic
+		chapter.PartialContent = ""
+		chapter.Summary = ""
+		chapter.Status = "draft"
+		chapter.IsFinalized = false
+		if err := s.chapterRepo.Update(ctx, chapter); err != nil {
+			logger.Error("保存选用的候选稿失败", zap.String("draft_id", draftID), zap.Error(err))
+			return nil, err
+		}
+		s.discardChapterState(ctx, deviceID, projectID, chapterNumber, wasCompleted)
+	}
+	if err := s.draftRepo.MarkPromoted(ctx, draftID, time.Now()); err != nil {
+		logger.Warn("记录候选稿选用时间失败", zap.String("draft_id", draftID), zap.Error(err))
+	}
+
+	logger.Info("已选用候选稿",
+		zap.String("project_id", projectID),
+		zap.Int("chapter_number", chapterNumber),
+		zap.String("draft_id", draftID),
+		zap.Int("word_count", chapter.WordCount),
+	)
+	return chapter, nil
+}
+
+// discardChapterState 章节正文被替换后移除由原正文得到的状态：该章的角色状态版本、检索索引，
+// 以及全局摘要中该章的摘要（章节已不是完成状态，重建时不再包含）。失败只记录日志，重新定稿时会重新生成
+func (s *ChapterService) discardChapterState(ctx context.Context, deviceID uuid.UUID, projectID string, chapterNumber int, wasCompleted bool) {
+	fields := []zap.Field{zap.String("project_id", projectID), zap.Int("chapter_number", chapterNumber)}
+
+	if err := s.discardCharacterState(ctx, projectID, chapterNumber); err != nil {
+		logger.Warn("删除章节的角色状态失败", append(fields, zap.Error(err))...)
+	}
+	if s.retriever != nil {
+		if err := s.retriever.DeleteChapter(ctx, projectID, chapterNumber); err != nil {
+			logger.Warn("删除章节的检索索引失败", append(fields, zap.Error(err))...)
+		}
+	}
+	if wasCompleted {
+		if err := s.refreshGlobalSummary(ctx, deviceID, projectID, chapterNumber); err != nil {
+			logger.Warn("更新全局摘要失败", append(fields, zap.Error(err))...)
+		}
+	}
+}
+
+// discardCharacterState 删除某一章的角色状态版本；该章是最新版本且项目当前的角色状态未被手动修改时，
+// 项目当前的角色状态回退到上一版本
+func (s *ChapterService) discardCharacterState(ctx context.Context, projectID string, chapterNumber int) error {
+	version, err := s.characterStateRepo.GetByChapter(ctx, projectID, chapterNumber)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	latest, err := s.characterStateRepo.LatestNumber(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := s.characterStateRepo.DeleteByChapter(ctx, projectID, chapterNumber); err != nil {
+		return err
+	}
+	if latest != chapterNumber {
+		return nil
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	previous, err := s.characterStateRepo.LatestBefore(ctx, projectID, chapterNumber)
+	if err != nil || previous == nil || project.CharacterState != version.Content {
+		return err
+	}
+	return s.projectRepo.UpdateArchitecture(ctx, projectID, map[string]interface{}{
+		"character_state": previous.Content,
+	})
+}
+
+// DeleteChapterDraft 删除候选稿
+func (s *ChapterService) DeleteChapterDraft(ctx context.Context, projectID string, chapterNumber int, draftID string) error {
+	if _, err := uuid.Parse(draftID); err != nil {
+		return ErrChapterDraftNotFound
+	}
+	deleted, err := s.draftRepo.Delete(ctx, projectID, chapterNumber, draftID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrChapterDraftNotFound
+	}
+	return nil
+}
+
+// draftChapter 获取候选稿所属的章节，不存在时返回 ErrChapterNotFound
+func (s *ChapterService) draftChapter(ctx context.Context, projectID string, chapterNumber int) (*model.Chapter, error) {
+	chapter, err := s.chapterRepo.GetByProjectAndNumber(ctx, projectID, chapterNumber)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChapterNotFound
+	}
+	return chapter, err
+}
+
+// getDraft 获取某一章的候选稿，不存在时返回 ErrChapterDraftNotFound
+func (s *ChapterService) getDraft(ctx context.Context, projectID string, chapterNumber int, id string) (*model.ChapterDraft, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrChapterDraftNotFound
+	}
+	draft, err := s.draftRepo.GetByChapter(ctx, projectID, chapterNumber, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChapterDraftNotFound
+	}
+	return draft, err
+}
+
+// currentDraft 将章节当前正文表示为候选稿，用于比较
+func currentDraft(chapter *model.Chapter) *dto.ChapterDraftResponse {
+	resp := dto.ChapterDraftFromModel(&model.ChapterDraft{
+		ChapterNumber: chapter.ChapterNumber,
+		Content:       chapter.Content,
+		WordCount:     chapter.WordCount,
+		Source:        currentDraftID,
+		Synthetic:     chapter.Synthetic,
+		CreatedAt:     chapter.UpdatedAt,
+	}, chapter.Content, true)
+	resp.ID = currentDraftID
+	return resp
+}
+
+// paragraphText 去掉空行与段首尾空白，按段落逐行比较
+func paragraphText(content string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
@@ -29,6 +29,8 @@ type ChapterPromptParams struct {
 	// 上下文
 	GlobalSummary     string
 	PreviousSummary   string // 前一章摘要
+	PreviousTail      string // 前一章结尾原文
+	RetrievedPassages string // 检索到的更早章节相关片段
 
 	// 当前章节内容（用于扩写）
 	CurrentContent    string
@@ -105,7 +107,7 @@ func GetNextDraftPrompt(params ChapterPromptParams) string {
 
 ## 前文摘要
 %s
-
+%s%s
 ## 本章大纲
 章节号：第 %d 章
 章节标题：%s
@@ -113,7 +115,7 @@ func GetNextDraftPrompt(params ChapterPromptParams) string {
 
 ## 写作要求
 1. **严格遵循【%s】类型的写作风格和情感基调**
-2. 承接上一章的剧情，保持故事连贯性
+2. 承接上一章的剧情，从上一章结尾处自然衔接场景、时间与人物状态，不要重复上一章已经写过的内容
 3. 按照本章大纲推进剧情
 4. 保持人物性格和行为的一致性
 5. 目标字数约 %d 字
@@ -126,11 +128,31 @@ func GetNextDraftPrompt(params ChapterPromptParams) string {
 		genreStr, params.ChapterNumber,
 		params.Title, genreStr, params.WordsPerChapter,
 		params.CoreSeed, params.CharacterState,
-		params.GlobalSummary,
+		params.GlobalSummary, retrievedPassagesSection(params), previousChapterSection(params),
 		params.ChapterNumber, params.ChapterTitle, params.BlueprintSummary,
 		genreStr, params.WordsPerChapter)
 }
 
+// previousChapterSection 上一章摘要与结尾原文，没有时为空
+func previousChapterSection(params ChapterPromptParams) string {
+	var b strings.Builder
+	if params.PreviousSummary != "" {
+		fmt.Fprintf(&b, "\n## 上一章（第 %d 章）摘要\n%s\n", params.ChapterNumber-1, params.PreviousSummary)
+	}
+	if params.PreviousTail != "" {
+		fmt.Fprintf(&b, "\n## 上一章结尾原文（本章从这里接着写）\n%s\n", params.PreviousTail)
+	}
+	return b.String()
+}
+
+// retrievedPassagesSection 检索到的更早章节相关片段，没有时为空
+func retrievedPassagesSection(params ChapterPromptParams) string {
+	if params.RetrievedPassages == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n## 相关前文片段（更早章节的原文，用于保持细节、设定与伏笔前后一致）\n%s\n", params.RetrievedPassages)
+}
+
 // GetEnrichPrompt 获取扩写提示词
 func GetEnrichPrompt(params ChapterPromptParams) string {
 	genreStr := strings.Join(params.Genre, "、")
@@ -189,6 +211,30 @@ func GetSummaryPrompt(chapterContent string, chapterNumber int) string {
 		chapterNumber, chapterContent)
 }
 
+// GetCompressSummaryPrompt 获取压缩前文摘要的提示词：把已有的前情概要与较早章节的摘要合并为一份更短的概要
+func GetCompressSummaryPrompt(recap string, chapterSummaries string, maxChars int) string {
+	if recap == "" {
+		recap = "（无）"
+	}
+	return fmt.Sprintf(`请把以下小说的前情概要和后续章节摘要合并压缩为一份新的前情概要，供后续章节写作时回顾剧情。
+
+## 已有的前情概要
+%s
+
+## 后续章节摘要
+%s
+
+## 压缩要求
+1. 按时间顺序概括主线剧情，合并次要细节
+2. 保留主要人物的现状、关系变化和重要抉择
+3. 保留尚未回收的伏笔和悬念
+4. 控制在 %d 字以内
+
+## 输出要求
+直接输出压缩后的前情概要，不要添加标题或说明。`,
+		recap, chapterSummaries, maxChars)
+}
+
 // GetUpdateCharacterStatePrompt 获取更新角色状态的提示词
 func GetUpdateCharacterStatePrompt(currentState string, chapterContent string, chapterNumber int) string {
 	return fmt.Sprintf(`根据第 %d 章的内容，更新角色状态文档。
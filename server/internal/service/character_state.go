@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCharacterStateNotGenerated 项目尚未生成角色状态
+	ErrCharacterStateNotGenerated = errors.New("项目尚未生成角色状态，请先生成架构")
+	// ErrCharacterStateNotFound 该章没有角色状态记录
+	ErrCharacterStateNotFound = errors.New("该章没有角色状态记录")
+)
+
+// characterStateBefore 生成第 n 章时使用的角色状态，即第 n 章之前最近一次更新后的状态。
+// 之后的章节还没有更新过角色状态时直接使用项目当前的角色状态（包含手动修改）
+func (s *ChapterService) characterStateBefore(ctx context.Context, project *model.Project, n int) (string, error) {
+	latest, err := s.characterStateRepo.LatestNumber(ctx, project.ID.String())
+	if err != nil {
+		return "", err
+	}
+	if latest < n {
+		return project.CharacterState, nil
+	}
+
+	version, err := s.characterStateRepo.LatestBefore(ctx, project.ID.String(), n)
+	if err != nil {
+		return "", err
+	}
+	if version == nil {
+		return project.CharacterState, nil
+	}
+	return version.Content, nil
+}
+
+// updateCharacterState 根据章节正文更新角色状态并保存为该章的版本。
+// 第一次更新前把架构阶段的角色状态保存为第 0 章的版本；
+// 更新的是最新的章节时同步更新项目当前的角色状态
+func (s *ChapterService) updateCharacterState(ctx context.Context, deviceID uuid.UUID, projectID string, chapter *model.Chapter) (*model.CharacterStateVersion, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.CharacterState == "" {
+		return nil, ErrCharacterStateNotGenerated
+	}
+
+	latest, err := s.characterStateRepo.LatestNumber(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if latest < 0 {
+		if err := s.characterStateRepo.Save(ctx, &model.CharacterStateVersion{
+			ProjectID:     project.ID,
+			ChapterNumber: 0,
+			Content:       project.CharacterState,
+			Synthetic:     project.ArchitectureSynthetic,
+		}); err != nil {
+			return nil, err
+		}
+		latest = 0
+	}
+
+	current, err := s.characterStateBefore(ctx, project, chapter.ChapterNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = llm.TrackSynthetic(ctx)
+	content, err := s.invoker.Chat(ctx, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   PurposeCharacterState,
+		Messages:  userMessages(GetUpdateCharacterStatePrompt(current, chapter.Content, chapter.ChapterNumber)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新角色状态失败: %w", err)
+	}
+
+	version := &model.CharacterStateVersion{
+		ProjectID:     project.ID,
+		ChapterNumber: chapter.ChapterNumber,
+		Content:       strings.TrimSpace(content),
+		Synthetic:     llm.IsSynthetic(ctx),
+	}
+	if err := s.characterStateRepo.Save(ctx, version); err != nil {
+		return nil, err
+	}
+
+	// 重新定稿较早的章节时不改动项目当前的角色状态，之后章节的版本也保持不变
+	if chapter.ChapterNumber >= latest {
+		if err := s.projectRepo.UpdateArchitecture(ctx, projectID, map[string]interface{}{
+			"character_state": version.Content,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("角色状态已更新",
+		zap.String("project_id", projectID),
+		zap.Int("chapter_number", chapter.ChapterNumber),
+	)
+	return version, nil
+}
+
+// ListCharacterStates 获取项目的角色状态历史
+func (s *ChapterService) ListCharacterStates(ctx context.Context, projectID string) ([]*model.CharacterStateVersion, error) {
+	return s.characterStateRepo.ListByProject(ctx, projectID)
+}
+
+// DiffCharacterState 比较第 n 章定稿后的角色状态与上一版本
+func (s *ChapterService) DiffCharacterState(ctx context.Context, projectID string, n int) (*dto.CharacterStateDiffResponse, error) {
+	version, err := s.characterStateRepo.GetByChapter(ctx, projectID, n)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCharacterStateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.characterStateRepo.LatestBefore(ctx, projectID, n)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.CharacterStateDiffResponse{ChapterNumber: n}
+	before := ""
+	if previous != nil {
+		before = previous.Content
+		resp.PreviousChapterNumber = &previous.ChapterNumber
+	}
+	resp.Lines = diffLines(before, version.Content)
+	for _, line := range resp.Lines {
+		switch line.Op {
+		case dto.DiffAdd:
+			resp.Added++
+		case dto.DiffRemove:
+			resp.Removed++
+		}
+	}
+	return resp, nil
+}
+
+// diffLines 按行比较两段文本（最长公共子序列），先去掉相同的首尾行以减少计算量
+func diffLines(a, b string) []dto.DiffLine {
+	x, y := splitLines(a), splitLines(b)
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]dto.DiffLine, 0, len(x)+len(y))
+	for _, text := range x[:prefix] {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: text})
+	}
+
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	// lcs[i][j] 为 mx[i:] 与 my[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && mx[i] == my[j]:
+			lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: mx[i]})
+			i++
+			j++
+		case j < len(my) && (i == len(mx) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, dto.DiffLine{Op: dto.DiffAdd, Text: my[j]})
+			j++
+		default:
+			lines = append(lines, dto.DiffLine{Op: dto.DiffRemove, Text: mx[i]})
+			i++
+		}
+	}
+
+	for _, text := range x[len(x)-suffix:] {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: text})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
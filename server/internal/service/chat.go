@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"unicode/utf8"
 
 	"x-novel/internal/llm"
 	"x-novel/internal/model"
@@ -18,21 +17,21 @@ import (
 type ChatService struct {
 	chatRepo    *repository.ChatRepository
 	projectRepo *repository.ProjectRepository
-	modelRepo   *repository.ModelConfigRepository
-	llmManager  *llm.Manager
+	chapterRepo *repository.ChapterRepository
+	invoker     *ModelInvoker
 }
 
 func NewChatService(
 	chatRepo *repository.ChatRepository,
 	projectRepo *repository.ProjectRepository,
-	modelRepo *repository.ModelConfigRepository,
-	llmManager *llm.Manager,
+	chapterRepo *repository.ChapterRepository,
+	invoker *ModelInvoker,
 ) *ChatService {
 	return &ChatService{
 		chatRepo:    chatRepo,
 		projectRepo: projectRepo,
-		modelRepo:   modelRepo,
-		llmManager:  llmManager,
+		chapterRepo: chapterRepo,
+		invoker:     invoker,
 	}
 }
 
@@ -120,10 +119,11 @@ func (s *ChatService) SendMessage(ctx context.Context, deviceID uuid.UUID, conve
 	}
 
 	// 调用 LLM
-	replyContent, err := s.callLLM(ctx, deviceID, llmMessages)
+	ctx = llm.TrackSynthetic(ctx)
+	replyContent, err := s.callLLM(ctx, deviceID, conv, llmMessages)
 	if err != nil {
 		logger.Error("LLM 对话失败", zap.Error(err))
-		replyContent = s.getMockReply(ChatMode(conv.Mode), content)
+		return nil, nil, err
 	}
 
 	// 保存 AI 回复
@@ -131,6 +131,7 @@ func (s *ChatService) SendMessage(ctx context.Context, deviceID uuid.UUID, conve
 		ConversationID: conv.ID,
 		Role:           "assistant",
 		Content:        replyContent,
+		Synthetic:      llm.IsSynthetic(ctx),
 	}
 	if err := s.chatRepo.CreateMessage(ctx, assistantMsg); err != nil {
 		return nil, nil, err
@@ -169,19 +170,18 @@ func (s *ChatService) SendMessageStream(ctx context.Context, deviceID uuid.UUID,
 		return nil, nil, err
 	}
 
-	replyContent, err := s.callLLMStream(ctx, deviceID, llmMessages, callback)
+	ctx = llm.TrackSynthetic(ctx)
+	replyContent, err := s.callLLMStream(ctx, deviceID, conv, llmMessages, callback)
 	if err != nil {
 		logger.Error("LLM 流式对话失败", zap.Error(err))
-		replyContent = s.getMockReply(ChatMode(conv.Mode), content)
-		if cbErr := callback(replyContent); cbErr != nil {
-			return nil, nil, cbErr
-		}
+		return nil, nil, err
 	}
 
 	assistantMsg := &model.Message{
 		ConversationID: conv.ID,
 		Role:           "assistant",
 		Content:        replyContent,
+		Synthetic:      llm.IsSynthetic(ctx),
 	}
 	if err := s.chatRepo.CreateMessage(ctx, assistantMsg); err != nil {
 		return nil, nil, err
@@ -215,6 +215,7 @@ func (s *ChatService) buildLLMMessages(ctx context.Context, conv *model.Conversa
 			if project.CoreSeed != "" {
 				projectContext += fmt.Sprintf("\n- 核心设定：%s", truncate(project.CoreSeed, 500))
 			}
+			projectContext += "\n\n需要了解章节内容、人物关系或架构设定时，请调用工具查询，不要凭空编造项目细节。"
 		}
 	}
 
@@ -237,52 +238,39 @@ func (s *ChatService) buildLLMMessages(ctx context.Context, conv *model.Conversa
 	return messages, nil
 }
 
-func (s *ChatService) callLLM(ctx context.Context, deviceID uuid.UUID, messages []llm.ChatMessage) (string, error) {
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "general")
-	if err != nil {
-		return "", fmt.Errorf("未配置通用模型: %w", err)
-	}
-
-	options := llm.ChatOptions{
-		Temperature: 0.85,
-		MaxTokens:   4096,
-		APIKey:      modelConfig.APIKey,
-	}
-
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		return adapter.ChatCompletion(ctx, messages, options)
+// callLLM 关联项目的对话可通过工具查询项目内容
+func (s *ChatService) callLLM(ctx context.Context, deviceID uuid.UUID, conv *model.Conversation, messages []llm.ChatMessage) (string, error) {
+	req := &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: conversationProjectID(conv),
+		Purpose:   PurposeChat,
+		Messages:  messages,
 	}
-
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
+	if conv.ProjectID != nil {
+		return s.invoker.ChatWithTools(ctx, req, s.projectToolbox(req.ProjectID))
 	}
-	return s.llmManager.ChatCompletion(ctx, provider, messages, options)
+	return s.invoker.Chat(ctx, req)
 }
 
-func (s *ChatService) callLLMStream(ctx context.Context, deviceID uuid.UUID, messages []llm.ChatMessage, callback llm.StreamCallback) (string, error) {
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "general")
-	if err != nil {
-		return "", fmt.Errorf("未配置通用模型: %w", err)
-	}
-
-	options := llm.ChatOptions{
-		Temperature: 0.85,
-		MaxTokens:   4096,
-		APIKey:      modelConfig.APIKey,
+func (s *ChatService) callLLMStream(ctx context.Context, deviceID uuid.UUID, conv *model.Conversation, messages []llm.ChatMessage, callback llm.StreamCallback) (string, error) {
+	req := &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: conversationProjectID(conv),
+		Purpose:   PurposeChat,
+		Messages:  messages,
 	}
-
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		return adapter.StreamChatCompletion(ctx, messages, options, callback)
+	if conv.ProjectID != nil {
+		return s.invoker.StreamWithTools(ctx, req, s.projectToolbox(req.ProjectID), callback)
 	}
+	return s.invoker.Stream(ctx, req, callback)
+}
 
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
+// conversationProjectID 对话关联的项目 ID，未关联时为空
+func conversationProjectID(conv *model.Conversation) string {
+	if conv.ProjectID == nil {
+		return ""
 	}
-	return s.llmManager.StreamChatCompletion(ctx, provider, messages, options, callback)
+	return conv.ProjectID.String()
 }
 
 func (s *ChatService) generateTitle(firstMessage string) string {
@@ -293,40 +281,6 @@ func (s *ChatService) generateTitle(firstMessage string) string {
 	return firstMessage
 }
 
-func (s *ChatService) getMockReply(mode ChatMode, content string) string {
-	wordCount := utf8.RuneCountInString(content)
-	switch mode {
-	case ChatModeCreative:
-		return fmt.Sprintf("## 创意灵感\n\n基于你的想法「%s」，我提供以下几个创意方向：\n\n"+
-			"**方向一：反转设定**\n可以尝试将主角的核心特质反转，制造戏剧冲突...\n\n"+
-			"**方向二：多重视角**\n从配角或反派的视角重新审视故事，可能会发现新的叙事空间...\n\n"+
-			"**方向三：时间错位**\n尝试打破线性叙事，通过时间跳跃增加悬念...\n\n"+
-			"你觉得哪个方向更感兴趣？我可以深入展开。", truncateStr(content, 50))
-	case ChatModeBuilding:
-		return fmt.Sprintf("## 设定完善建议\n\n关于「%s」(%d字)，从世界观角度分析：\n\n"+
-			"1. **内部一致性**：需要确保这个设定与已有体系不冲突\n"+
-			"2. **延伸可能**：这个设定可以自然引申出更多有趣的子系统\n"+
-			"3. **读者体验**：建议通过具体场景来展示设定，而非直接说明\n\n"+
-			"需要我帮你细化其中某个方面吗？", truncateStr(content, 50), wordCount)
-	case ChatModeCharacter:
-		return fmt.Sprintf("## 角色分析\n\n关于「%s」，从角色塑造角度：\n\n"+
-			"**性格层次**\n- 表层特征：外在表现和社交面具\n- 深层动机：真正驱动角色的内在需求\n- 内在矛盾：让角色立体的关键冲突\n\n"+
-			"**成长弧线建议**\n角色需要经历一个从「不自知」到「自我觉醒」的过程，这会让读者产生共鸣。\n\n"+
-			"你想具体讨论哪个方面？", truncateStr(content, 50))
-	default:
-		return fmt.Sprintf("感谢你的提问！关于「%s」，这是一个很好的创作方向。\n\n"+
-			"从专业角度来看，我建议：\n"+
-			"1. 先梳理核心冲突和主题\n"+
-			"2. 确保角色动机清晰合理\n"+
-			"3. 注意节奏把控，张弛有度\n\n"+
-			"你可以选择以下模式获得更专业的帮助：\n"+
-			"- **创意启发**：激发灵感和新想法\n"+
-			"- **设定完善**：完善世界观和设定\n"+
-			"- **角色塑造**：深入人物刻画\n\n"+
-			"需要我从哪个角度深入分析？", truncateStr(content, 50))
-	}
-}
-
 func truncate(s string, maxLen int) string {
 	runes := []rune(s)
 	if len(runes) > maxLen {
@@ -334,11 +288,3 @@ func truncate(s string, maxLen int) string {
 	}
 	return s
 }
-
-func truncateStr(s string, maxLen int) string {
-	runes := []rune(s)
-	if len(runes) > maxLen {
-		return string(runes[:maxLen]) + "..."
-	}
-	return s
-}
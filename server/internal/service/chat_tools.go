@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"x-novel/internal/llm"
+)
+
+// chatToolMaxRunes 单次工具结果的字数上限，避免撑满上下文
+const chatToolMaxRunes = 8000
+
+// architectureSections 可查询的架构部分
+var architectureSections = []string{"core_seed", "character_dynamics", "world_building", "plot_architecture", "character_state"}
+
+// projectToolbox 关联项目的对话可按需查询章节、人物关系图谱与架构设定，而不是全部放入提示词
+func (s *ChatService) projectToolbox(projectID string) *llm.Toolbox {
+	toolbox := llm.NewToolbox()
+
+	toolbox.Register(llm.Tool{
+		Name:        "list_chapters",
+		Description: "列出项目的所有章节：编号、标题、状态、字数与大纲梗概",
+		Parameters:  llm.NoParams(),
+	}, func(ctx context.Context, _ string) (string, error) {
+		return s.toolListChapters(ctx, projectID)
+	})
+
+	toolbox.Register(llm.Tool{
+		Name:        "get_chapter",
+		Description: "获取指定章节的大纲信息与正文",
+		Parameters: llm.ObjectParams(map[string]*llm.Schema{
+			"chapter_number": {Type: "integer", Description: "章节编号，从 1 开始"},
+		}, "chapter_number"),
+	}, func(ctx context.Context, arguments string) (string, error) {
+		var args struct {
+			ChapterNumber int `json:"chapter_number"`
+		}
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("参数格式错误: %w", err)
+		}
+		return s.toolGetChapter(ctx, projectID, args.ChapterNumber)
+	})
+
+	toolbox.Register(llm.Tool{
+		Name:        "get_character_graph",
+		Description: "获取人物关系图谱：角色列表与角色之间的关系",
+		Parameters:  llm.NoParams(),
+	}, func(ctx context.Context, _ string) (string, error) {
+		return s.toolGetCharacterGraph(ctx, projectID)
+	})
+
+	toolbox.Register(llm.Tool{
+		Name:        "get_architecture",
+		Description: "获取小说架构的指定部分：核心种子、角色动力学、世界观、情节架构或角色状态",
+		Parameters: llm.ObjectParams(map[string]*llm.Schema{
+			"section": {Type: "string", Description: "架构部分", Enum: architectureSections},
+		}, "section"),
+	}, func(ctx context.Context, arguments string) (string, error) {
+		var args struct {
+			Section string `json:"section"`
+		}
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("参数格式错误: %w", err)
+		}
+		return s.toolGetArchitecture(ctx, projectID, args.Section)
+	})
+
+	return toolbox
+}
+
+func (s *ChatService) toolListChapters(ctx context.Context, projectID string) (string, error) {
+	chapters, err := s.chapterRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+	if len(chapters) == 0 {
+		return "项目还没有章节", nil
+	}
+
+	var b strings.Builder
+	for _, ch := range chapters {
+		fmt.Fprintf(&b, "第%d章《%s》 状态：%s 字数：%d", ch.ChapterNumber, ch.Title, ch.Status, ch.WordCount)
+		if ch.BlueprintSummary != "" {
+			fmt.Fprintf(&b, " 梗概：%s", truncate(ch.BlueprintSummary, 80))
+		}
+		b.WriteString("\n")
+	}
+	return truncate(b.String(), chatToolMaxRunes), nil
+}
+
+func (s *ChatService) toolGetChapter(ctx context.Context, projectID string, number int) (string, error) {
+	ch, err := s.chapterRepo.GetByProjectAndNumber(ctx, projectID, number)
+	if err != nil {
+		return "", fmt.Errorf("第%d章不存在", number)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "第%d章《%s》\n", ch.ChapterNumber, ch.Title)
+	for _, field := range []struct{ label, value string }{
+		{"定位", ch.BlueprintPosition},
+		{"作用", ch.BlueprintPurpose},
+		{"悬念", ch.BlueprintSuspense},
+		{"伏笔", ch.BlueprintForeshadowing},
+		{"梗概", ch.BlueprintSummary},
+		{"摘要", ch.Summary},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%s：%s\n", field.label, field.value)
+		}
+	}
+	if ch.Content == "" {
+		b.WriteString("\n（尚未生成正文）")
+	} else {
+		b.WriteString("\n正文：\n")
+		b.WriteString(ch.Content)
+	}
+	return truncate(b.String(), chatToolMaxRunes), nil
+}
+
+func (s *ChatService) toolGetCharacterGraph(ctx context.Context, projectID string) (string, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+	if project.GraphData == "" {
+		return "尚未生成人物关系图谱", nil
+	}
+	var graph GraphData
+	if err := json.Unmarshal([]byte(project.GraphData), &graph); err != nil {
+		return "", fmt.Errorf("图谱数据解析失败: %w", err)
+	}
+
+	names := make(map[string]string, len(graph.Nodes))
+	var b strings.Builder
+	b.WriteString("角色：\n")
+	for _, n := range graph.Nodes {
+		names[n.ID] = n.Name
+		fmt.Fprintf(&b, "- %s（%s）：%s\n", n.Name, n.Type, n.Description)
+	}
+	b.WriteString("关系：\n")
+	for _, e := range graph.Edges {
+		fmt.Fprintf(&b, "- %s → %s：%s", nodeName(names, e.Source), nodeName(names, e.Target), e.Relation)
+		if e.Description != "" {
+			fmt.Fprintf(&b, "（%s）", e.Description)
+		}
+		b.WriteString("\n")
+	}
+	return truncate(b.String(), chatToolMaxRunes), nil
+}
+
+// nodeName 边的端点一般为节点 ID，找不到时原样返回
+func nodeName(names map[string]string, id string) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return id
+}
+
+func (s *ChatService) toolGetArchitecture(ctx context.Context, projectID, section string) (string, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	switch section {
+	case "core_seed":
+		text = project.CoreSeed
+	case "character_dynamics":
+		text = project.CharacterDynamics
+	case "world_building":
+		text = project.WorldBuilding
+	case "plot_architecture":
+		text = project.PlotArchitecture
+	case "character_state":
+		text = project.CharacterState
+	default:
+		return "", fmt.Errorf("未知的架构部分 %q，可选值：%s", section, strings.Join(architectureSections, ", "))
+	}
+	if text == "" {
+		return "该部分尚未生成", nil
+	}
+	return truncate(text, chatToolMaxRunes), nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"x-novel/internal/model"
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPreviousParagraphs 未配置时附带的上一章结尾段落数
+	defaultPreviousParagraphs = 3
+	// defaultPreviousMaxChars 未配置时上一章结尾原文的最大字数
+	defaultPreviousMaxChars = 1500
+)
+
+// previousChapterContext 上一章的摘要与结尾原文，用于衔接本章开头。
+// 上一章不存在、没有正文或尚未定稿时返回提示，仍尽量使用已有的内容
+func (s *ChapterService) previousChapterContext(ctx context.Context, projectID string, chapter *model.Chapter) (summary, tail string, warnings []string) {
+	if chapter.ChapterNumber <= 1 {
+		return "", "", nil
+	}
+	n := chapter.ChapterNumber - 1
+	defer func() {
+		for _, warning := range warnings {
+			logger.Warn("上一章衔接内容不完整",
+				zap.String("project_id", projectID),
+				zap.Int("chapter_number", chapter.ChapterNumber),
+				zap.String("warning", warning),
+			)
+		}
+	}()
+
+	previous, err := s.chapterRepo.GetByProjectAndNumber(ctx, projectID, n)
+	if err != nil {
+		return "", "", []string{fmt.Sprintf("第 %d 章不存在，本章无法衔接上一章", n)}
+	}
+
+	summary = previous.Summary
+	if summary == "" {
+		summary = previous.BlueprintSummary
+	}
+
+	if previous.Content == "" {
+		return summary, "", []string{fmt.Sprintf("第 %d 章还没有正文，本章只能参考其大纲衔接", n)}
+	}
+	if !previous.IsFinalized {
+		warnings = append(warnings, fmt.Sprintf("第 %d 章尚未定稿，本章按其草稿衔接", n))
+	}
+
+	return summary, lastParagraphs(previous.Content, s.opts.PreviousParagraphs, s.opts.PreviousMaxChars), warnings
+}
+
+// lastParagraphs 取正文最后 n 个非空段落，总字数超过 maxChars 时从前面截断，至少保留最后一段的结尾
+func lastParagraphs(content string, n, maxChars int) string {
+	var paragraphs []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	if len(paragraphs) > n {
+		paragraphs = paragraphs[len(paragraphs)-n:]
+	}
+
+	total := 0
+	for i := len(paragraphs) - 1; i >= 0; i-- {
+		total += utf8.RuneCountInString(paragraphs[i])
+		if total <= maxChars {
+			continue
+		}
+		if i < len(paragraphs)-1 {
+			paragraphs = paragraphs[i+1:]
+			break
+		}
+		// 最后一段本身超长时只保留它的结尾
+		runes := []rune(paragraphs[i])
+		paragraphs = []string{"……" + string(runes[len(runes)-maxChars:])}
+		break
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
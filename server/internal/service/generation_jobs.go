@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// RegisterGenerationJobs 注册架构、大纲、章节与图谱生成任务
+func RegisterGenerationJobs(jobs *JobService, projectService *ProjectService, chapterService *ChapterService, graphService *GraphService) {
+	jobs.Register(model.JobTypeArchitecture, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.GenerateArchitectureRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			project, err := projectService.GetByID(ctx, job.Target)
+			if err != nil {
+				return err
+			}
+			return checkArchitectureGenerate(project, req.Overwrite)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.GenerateArchitectureRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			// 覆盖生成只在第一次执行前清空已有步骤；任务被中断后重新执行时保留已保存的步骤，从中断处继续
+			if req.Overwrite && job.Attempts <= 1 {
+				if err := projectService.ResetArchitecture(ctx, job.Target); err != nil {
+					return nil, err
+				}
+			}
+			project, err := projectService.GenerateArchitecture(ctx, job.DeviceID, job.Target)
+			if err != nil {
+				return nil, err
+			}
+			return dto.FromModel(project), nil
+		},
+	})
+
+	jobs.Register(model.JobTypeArchitectureStep, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.RegenerateArchitectureStepRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			project, err := projectService.GetByID(ctx, jobProjectID(job))
+			if err != nil {
+				return err
+			}
+			return checkArchitectureStepRegenerate(project, &req)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.RegenerateArchitectureStepRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			project, err := projectService.RegenerateArchitectureStep(ctx, job.DeviceID, jobProjectID(job), &req)
+			if err != nil {
+				return nil, err
+			}
+			return dto.FromModel(project), nil
+		},
+	})
+
+	jobs.Register(model.JobTypeBlueprint, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.GenerateBlueprintRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			project, err := projectService.GetByID(ctx, job.Target)
+			if err != nil {
+				return err
+			}
+			return checkBlueprintGenerate(project, req.Overwrite)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.GenerateBlueprintRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			project, chapters, err := projectService.GenerateBlueprint(ctx, job.DeviceID, job.Target, &req)
+			if err != nil {
+				return nil, err
+			}
+			return &dto.BlueprintGenerateResponse{Project: dto.FromModel(project), Chapters: chapters}, nil
+		},
+	})
+
+	jobs.Register(model.JobTypeChapter, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.GenerateChapterRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			chapter, err := chapterService.GetByProjectAndNumber(ctx, jobProjectID(job), req.ChapterNumber)
+			if err != nil {
+				return err
+			}
+			return checkChapterGenerate(chapter, req.Overwrite)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.GenerateChapterRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			projectID := jobProjectID(job)
+			chapter, err := chapterService.GetByProjectAndNumber(ctx, projectID, req.ChapterNumber)
+			if err != nil {
+				return nil, err
+			}
+			chapter, err = chapterService.GenerateChapterContent(ctx, job.DeviceID, projectID, chapter.ID.String(), &req)
+			if err != nil {
+				return nil, err
+			}
+			return dto.ChapterFromModel(chapter), nil
+		},
+	})
+
+	jobs.Register(model.JobTypeChapterBatch, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.GenerateChapterBatchRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			if _, err := projectService.GetByID(ctx, job.Target); err != nil {
+				return err
+			}
+			return checkChapterBatch(&req)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.GenerateChapterBatchRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			// 服务重启后按本任务已保存的结果继续，新提交的继续任务沿用上一次任务的结果
+			prev, err := previousBatchResult(ctx, jobs, job, req.ResumeFrom)
+			if err != nil {
+				return nil, err
+			}
+			return chapterService.GenerateChapterBatch(ctx, job.DeviceID, job.Target, &req, prev)
+		},
+	})
+
+	jobs.Register(model.JobTypeChapterDrafts, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			var req dto.GenerateChapterDraftsRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return err
+			}
+			if _, err := chapterService.GetByProjectAndNumber(ctx, jobProjectID(job), req.ChapterNumber); err != nil {
+				return err
+			}
+			if err := checkChapterDrafts(&req); err != nil {
+				return err
+			}
+			return chapterService.CheckDraftModels(ctx, job.DeviceID, &req)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			var req dto.GenerateChapterDraftsRequest
+			if err := decodeJobParams(job, &req); err != nil {
+				return nil, err
+			}
+			return chapterService.GenerateChapterDrafts(ctx, job.DeviceID, jobProjectID(job), &req)
+		},
+	})
+
+	jobs.Register(model.JobTypeGraph, JobHandler{
+		Validate: func(ctx context.Context, job *model.Job) error {
+			project, err := projectService.GetByID(ctx, job.Target)
+			if err != nil {
+				return err
+			}
+			return checkGraphGenerate(project)
+		},
+		Run: func(ctx context.Context, job *model.Job) (interface{}, error) {
+			return graphService.GenerateGraph(ctx, job.DeviceID, job.Target)
+		},
+	})
+}
+
+// ArchitectureJob 生成架构任务
+func ArchitectureJob(deviceID uuid.UUID, projectID string, req *dto.GenerateArchitectureRequest) JobSpec {
+	return JobSpec{DeviceID: deviceID, ProjectID: parseProjectID(projectID), Type: model.JobTypeArchitecture, Target: projectID, Params: req}
+}
+
+// ArchitectureStepJob 重新生成单个架构步骤任务
+func ArchitectureStepJob(deviceID uuid.UUID, projectID string, req *dto.RegenerateArchitectureStepRequest) JobSpec {
+	return JobSpec{
+		DeviceID:  deviceID,
+		ProjectID: parseProjectID(projectID),
+		Type:      model.JobTypeArchitectureStep,
+		Target:    projectID + "/" + req.Step,
+		Params:    req,
+	}
+}
+
+// BlueprintJob 生成大纲任务
+func BlueprintJob(deviceID uuid.UUID, projectID string, req *dto.GenerateBlueprintRequest) JobSpec {
+	return JobSpec{DeviceID: deviceID, ProjectID: parseProjectID(projectID), Type: model.JobTypeBlueprint, Target: projectID, Params: req}
+}
+
+// ChapterJob 生成章节内容任务，同一章节同时只有一个生成任务
+func ChapterJob(deviceID uuid.UUID, projectID string, req *dto.GenerateChapterRequest) JobSpec {
+	return JobSpec{
+		DeviceID:  deviceID,
+		ProjectID: parseProjectID(projectID),
+		Type:      model.JobTypeChapter,
+		Target:    fmt.Sprintf("%s/%d", projectID, req.ChapterNumber),
+		Params:    req,
+	}
+}
+
+// ChapterDraftsJob 生成章节候选稿任务，同一章节同时只有一个候选稿任务
+func ChapterDraftsJob(deviceID uuid.UUID, projectID string, req *dto.GenerateChapterDraftsRequest) JobSpec {
+	return JobSpec{
+		DeviceID:  deviceID,
+		ProjectID: parseProjectID(projectID),
+		Type:      model.JobTypeChapterDrafts,
+		Target:    fmt.Sprintf("%s/%d", projectID, req.ChapterNumber),
+		Params:    req,
+	}
+}
+
+// ChapterBatchJob 批量生成章节任务，同一项目同时只有一个批量任务
+func ChapterBatchJob(deviceID uuid.UUID, projectID string, req *dto.GenerateChapterBatchRequest) JobSpec {
+	return JobSpec{DeviceID: deviceID, ProjectID: parseProjectID(projectID), Type: model.JobTypeChapterBatch, Target: projectID, Params: req}
+}
+
+// ChapterBatchResumeJob 继续上一次批量生成：沿用原参数，已生成或跳过的章节不再处理
+func ChapterBatchResumeJob(prev *model.Job) (JobSpec, error) {
+	var req dto.GenerateChapterBatchRequest
+	if err := decodeJobParams(prev, &req); err != nil {
+		return JobSpec{}, err
+	}
+
+	if prev.Status == model.JobStatusSucceeded {
+		var result dto.ChapterBatchResponse
+		if err := json.Unmarshal([]byte(prev.Result), &result); err == nil && result.Failed == 0 {
+			return JobSpec{}, ErrNothingToResume
+		}
+	}
+
+	req.ResumeFrom = prev.ID.String()
+	return JobSpec{DeviceID: prev.DeviceID, ProjectID: prev.ProjectID, Type: model.JobTypeChapterBatch, Target: prev.Target, Params: &req}, nil
+}
+
+// previousBatchResult 批量任务已有的结果：优先使用本任务保存的阶段性结果，其次是继续的上一次任务的结果
+func previousBatchResult(ctx context.Context, jobs *JobService, job *model.Job, resumeFrom string) (*dto.ChapterBatchResponse, error) {
+	data := job.Result
+	if data == "" && resumeFrom != "" {
+		prev, err := jobs.Get(ctx, job.DeviceID, resumeFrom)
+		if err != nil {
+			return nil, fmt.Errorf("获取上一次批量任务失败: %w", err)
+		}
+		data = prev.Result
+	}
+	if data == "" {
+		return nil, nil
+	}
+
+	var result dto.ChapterBatchResponse
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, fmt.Errorf("解析批量任务结果失败: %w", err)
+	}
+	return &result, nil
+}
+
+// GraphJob 生成关系图谱任务
+func GraphJob(deviceID uuid.UUID, projectID string) JobSpec {
+	return JobSpec{DeviceID: deviceID, ProjectID: parseProjectID(projectID), Type: model.JobTypeGraph, Target: projectID}
+}
+
+// parseProjectID 解析项目 ID，格式错误时返回 nil（提交时的前置检查会因项目不存在而失败）
+func parseProjectID(projectID string) *uuid.UUID {
+	id, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// jobProjectID 任务所属项目 ID
+func jobProjectID(job *model.Job) string {
+	if job.ProjectID == nil {
+		return ""
+	}
+	return job.ProjectID.String()
+}
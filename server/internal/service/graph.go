@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 
 	"x-novel/internal/llm"
+	"x-novel/internal/model"
 	"x-novel/internal/repository"
 	"x-novel/pkg/logger"
 
@@ -37,7 +37,8 @@ type GraphEdge struct {
 type GraphData struct {
 	Nodes    []GraphNode    `json:"nodes"`
 	Edges    []GraphEdge    `json:"edges"`
-	Snapshots []GraphSnapshot `json:"snapshots,omitempty"`
+	Snapshots []GraphSnapshot `json:"snapshots,omitempty" schema:"-"`
+	Synthetic bool            `json:"synthetic,omitempty" schema:"-"` // 含模拟提供商生成的数据
 }
 
 // GraphSnapshot 章节快照
@@ -59,21 +60,18 @@ type ChapterDelta struct {
 type GraphService struct {
 	projectRepo *repository.ProjectRepository
 	chapterRepo *repository.ChapterRepository
-	modelRepo   *repository.ModelConfigRepository
-	llmManager  *llm.Manager
+	invoker     *ModelInvoker
 }
 
 func NewGraphService(
 	projectRepo *repository.ProjectRepository,
 	chapterRepo *repository.ChapterRepository,
-	modelRepo *repository.ModelConfigRepository,
-	llmManager *llm.Manager,
+	invoker *ModelInvoker,
 ) *GraphService {
 	return &GraphService{
 		projectRepo: projectRepo,
 		chapterRepo: chapterRepo,
-		modelRepo:   modelRepo,
-		llmManager:  llmManager,
+		invoker:     invoker,
 	}
 }
 
@@ -84,23 +82,26 @@ func (s *GraphService) GenerateGraph(ctx context.Context, deviceID uuid.UUID, pr
 		return nil, fmt.Errorf("项目不存在: %w", err)
 	}
 
-	if project.CoreSeed == "" {
-		return nil, fmt.Errorf("请先生成小说架构")
+	if err := checkGraphGenerate(project); err != nil {
+		return nil, err
 	}
 
+	reportProgress(ctx, "提取人物关系图谱", 1, 1)
 	prompt := GetExtractGraphPrompt(project.Title, project.CoreSeed, project.CharacterDynamics, project.WorldBuilding)
 
-	result, err := s.callLLM(ctx, deviceID, prompt)
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, projectID, prompt, "graph_data", &GraphData{})
 	if err != nil {
-		logger.Error("LLM 提取图谱失败，使用模拟数据", zap.Error(err))
-		result = s.getMockGraphJSON(project.Title)
+		logger.Error("LLM 提取图谱失败", zap.Error(err))
+		return nil, err
 	}
 
 	graphData, err := s.parseGraphData(result)
 	if err != nil {
-		logger.Error("解析图谱数据失败，使用模拟数据", zap.Error(err))
-		graphData = s.getMockGraph(project.Title)
+		logger.Error("解析图谱数据失败", zap.Error(err))
+		return nil, fmt.Errorf("解析图谱数据失败: %w", err)
 	}
+	graphData.Synthetic = llm.IsSynthetic(ctx)
 
 	// 保存到项目
 	graphJSON, _ := json.Marshal(graphData)
@@ -112,6 +113,14 @@ func (s *GraphService) GenerateGraph(ctx context.Context, deviceID uuid.UUID, pr
 	return graphData, nil
 }
 
+// checkGraphGenerate 检查能否生成图谱
+func checkGraphGenerate(project *model.Project) error {
+	if project.CoreSeed == "" {
+		return fmt.Errorf("请先生成小说架构")
+	}
+	return nil
+}
+
 // UpdateGraphFromChapter 从章节内容更新图谱（增量）
 func (s *GraphService) UpdateGraphFromChapter(ctx context.Context, deviceID uuid.UUID, projectID string, chapterNumber int) (*GraphData, error) {
 	project, err := s.projectRepo.GetByID(ctx, projectID)
@@ -137,19 +146,22 @@ func (s *GraphService) UpdateGraphFromChapter(ctx context.Context, deviceID uuid
 	existingJSON, _ := json.Marshal(graphData)
 	prompt := GetExtractChapterGraphPrompt(project.Title, chapterNumber, chapter.Content, string(existingJSON))
 
-	result, err := s.callLLM(ctx, deviceID, prompt)
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, projectID, prompt, "chapter_delta", &ChapterDelta{})
 	if err != nil {
 		logger.Error("LLM 提取章节增量失败", zap.Error(err))
-		return s.applyMockDelta(&graphData, chapterNumber), nil
+		return nil, err
 	}
 
 	delta, err := s.parseChapterDelta(result)
 	if err != nil {
 		logger.Error("解析章节增量失败", zap.Error(err))
-		return s.applyMockDelta(&graphData, chapterNumber), nil
+		return nil, fmt.Errorf("解析章节增量失败: %w", err)
 	}
 
 	s.applyDelta(&graphData, delta, chapterNumber)
+	// 增量合并后的图谱只要有一部分来自模拟输出即视为模拟数据
+	graphData.Synthetic = graphData.Synthetic || llm.IsSynthetic(ctx)
 
 	// 保存
 	graphJSON, _ := json.Marshal(graphData)
@@ -242,34 +254,14 @@ func (s *GraphService) applyDelta(graph *GraphData, delta *ChapterDelta, chapter
 	})
 }
 
-func (s *GraphService) callLLM(ctx context.Context, deviceID uuid.UUID, prompt string) (string, error) {
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "architecture")
-	if err != nil {
-		modelConfig, err = s.modelRepo.GetByPurpose(ctx, deviceID.String(), "general")
-		if err != nil {
-			return "", fmt.Errorf("未配置模型: %w", err)
-		}
-	}
-
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: prompt},
-	}
-	options := llm.ChatOptions{
-		Temperature: 0.3,
-		MaxTokens:   4096,
-		APIKey:      modelConfig.APIKey,
-	}
-
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		return adapter.ChatCompletion(ctx, messages, options)
-	}
-
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
-	}
-	return s.llmManager.ChatCompletion(ctx, provider, messages, options)
+// callLLM 以结构化输出模式调用模型，返回通过 target 结构校验的 JSON
+func (s *GraphService) callLLM(ctx context.Context, deviceID uuid.UUID, projectID, prompt, schemaName string, target interface{}) (string, error) {
+	return s.invoker.ChatJSON(ctx, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   PurposeGraph,
+		Messages:  userMessages(prompt),
+	}, schemaName, target)
 }
 
 func (s *GraphService) parseGraphData(raw string) (*GraphData, error) {
@@ -296,44 +288,7 @@ func (s *GraphService) parseChapterDelta(raw string) (*ChapterDelta, error) {
 	return &delta, nil
 }
 
-// cleanJSON 清理 LLM 返回中可能包含的 markdown 代码块标记
+// cleanJSON 清理 LLM 返回中可能包含的 markdown 代码块标记及多余文字
 func cleanJSON(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
-}
-
-func (s *GraphService) getMockGraphJSON(title string) string {
-	data := s.getMockGraph(title)
-	b, _ := json.Marshal(data)
-	return string(b)
-}
-
-func (s *GraphService) getMockGraph(title string) *GraphData {
-	return &GraphData{
-		Nodes: []GraphNode{
-			{ID: "liming", Name: "李明", Type: "protagonist", Description: "偶然获得超能力的程序员", Traits: []string{"聪明", "正义感强"}, Group: "主角阵营"},
-			{ID: "suxiaoyu", Name: "苏小雨", Type: "supporting", Description: "神秘的短发女子", Traits: []string{"敏捷", "果断"}, Group: "主角阵营"},
-			{ID: "heiyiren", Name: "黑衣人", Type: "antagonist", Description: "神秘组织的执行者", Traits: []string{"冷酷", "强大"}, Group: "神秘组织"},
-			{ID: "laoshi", Name: "陈教授", Type: "supporting", Description: "李明的导师", Traits: []string{"睿智", "慈祥"}, Group: "学术界"},
-		},
-		Edges: []GraphEdge{
-			{Source: "liming", Target: "suxiaoyu", Relation: "盟友", Description: "苏小雨救了李明，两人结为同盟", Weight: 8},
-			{Source: "liming", Target: "heiyiren", Relation: "敌对", Description: "黑衣人追杀李明", Weight: 9},
-			{Source: "suxiaoyu", Target: "heiyiren", Relation: "前同事", Description: "苏小雨曾是组织成员，后叛逃", Weight: 7},
-			{Source: "liming", Target: "laoshi", Relation: "师生", Description: "陈教授是李明的大学导师", Weight: 5},
-		},
-	}
-}
-
-func (s *GraphService) applyMockDelta(graph *GraphData, chapterNumber int) *GraphData {
-	graph.Snapshots = append(graph.Snapshots, GraphSnapshot{
-		ChapterNumber: chapterNumber,
-		Summary:       fmt.Sprintf("第%d章关系变化（模拟数据）", chapterNumber),
-		NodesCount:    len(graph.Nodes),
-		EdgesCount:    len(graph.Edges),
-	})
-	return graph
+	return llm.ExtractJSON(s)
 }
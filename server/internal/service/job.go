@@ -0,0 +1,405 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/internal/repository"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrJobNotFound 任务不存在或不属于当前设备
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrJobFinished 任务已结束，无法取消
+	ErrJobFinished = errors.New("任务已结束")
+)
+
+// JobHandler 一类后台任务的执行逻辑
+type JobHandler struct {
+	// Validate 提交前同步检查前置条件，失败时不创建任务，可为空
+	Validate func(ctx context.Context, job *model.Job) error
+	// Run 执行任务，返回值序列化为 JSON 作为任务结果
+	Run func(ctx context.Context, job *model.Job) (interface{}, error)
+}
+
+// JobOptions 任务执行配置
+type JobOptions struct {
+	Workers     int // 同时执行的任务数
+	MaxAttempts int // 任务被服务重启中断后最多执行的次数
+}
+
+// JobSpec 提交任务的参数
+type JobSpec struct {
+	DeviceID  uuid.UUID
+	ProjectID *uuid.UUID
+	Type      string
+	Target    string      // 同一对象同时只允许一个未完成的同类任务
+	Params    interface{} // 序列化后随任务保存，重启后按原参数重新执行
+	NoCache   bool
+}
+
+// JobService 后台任务服务：任务持久化到数据库，进程内按并发上限执行，
+// 服务关闭时中断的任务放回队列，启动时恢复执行
+type JobService struct {
+	jobRepo  *repository.JobRepository
+	handlers map[string]JobHandler
+	opts     JobOptions
+	slots    chan struct{}
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[uuid.UUID]context.CancelFunc
+}
+
+// NewJobService 创建后台任务服务
+func NewJobService(jobRepo *repository.JobRepository, opts JobOptions) *JobService {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &JobService{
+		jobRepo:  jobRepo,
+		handlers: make(map[string]JobHandler),
+		opts:     opts,
+		slots:    make(chan struct{}, opts.Workers),
+		ctx:      ctx,
+		stop:     stop,
+		running:  make(map[uuid.UUID]context.CancelFunc),
+	}
+}
+
+// Register 注册任务类型
+func (s *JobService) Register(jobType string, handler JobHandler) {
+	s.handlers[jobType] = handler
+}
+
+// Submit 提交任务并立即返回；同一对象已有未完成的同类任务时返回该任务，created 为 false
+func (s *JobService) Submit(ctx context.Context, spec JobSpec) (job *model.Job, created bool, err error) {
+	handler, ok := s.handlers[spec.Type]
+	if !ok {
+		return nil, false, fmt.Errorf("未知的任务类型: %s", spec.Type)
+	}
+
+	active, err := s.jobRepo.FindActive(ctx, spec.DeviceID, spec.Type, spec.Target)
+	if err != nil {
+		return nil, false, err
+	}
+	if active != nil {
+		return active, false, nil
+	}
+
+	params, err := json.Marshal(spec.Params)
+	if err != nil {
+		return nil, false, err
+	}
+	job = &model.Job{
+		DeviceID:  spec.DeviceID,
+		ProjectID: spec.ProjectID,
+		Type:      spec.Type,
+		Target:    spec.Target,
+		Status:    model.JobStatusPending,
+		Params:    string(params),
+		NoCache:   spec.NoCache,
+	}
+
+	if handler.Validate != nil {
+		if err := handler.Validate(ctx, job); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		// 并发提交时另一个请求已先创建了同类任务，返回该任务
+		if errors.Is(err, repository.ErrActiveJobExists) {
+			active, findErr := s.jobRepo.FindActive(ctx, spec.DeviceID, spec.Type, spec.Target)
+			if findErr != nil {
+				return nil, false, findErr
+			}
+			if active != nil {
+				return active, false, nil
+			}
+		}
+		return nil, false, err
+	}
+
+	logger.Info("已提交后台任务",
+		zap.String("job_id", job.ID.String()),
+		zap.String("type", job.Type),
+		zap.String("target", job.Target),
+	)
+	s.enqueue(job)
+	return job, true, nil
+}
+
+// Get 获取任务
+func (s *JobService) Get(ctx context.Context, deviceID uuid.UUID, id string) (*model.Job, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.DeviceID != deviceID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// List 查询任务列表
+func (s *JobService) List(ctx context.Context, filter repository.JobFilter, page, pageSize int) ([]*model.Job, int64, error) {
+	return s.jobRepo.List(ctx, filter, (page-1)*pageSize, pageSize)
+}
+
+// Latest 获取项目最近一次的某类任务
+func (s *JobService) Latest(ctx context.Context, deviceID, projectID uuid.UUID, jobType string) (*model.Job, error) {
+	jobs, _, err := s.jobRepo.List(ctx, repository.JobFilter{DeviceID: deviceID, ProjectID: &projectID, Type: jobType}, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return jobs[0], nil
+}
+
+// Active 获取与 spec 同一对象上未结束的同类任务，不存在时返回 nil
+func (s *JobService) Active(ctx context.Context, spec JobSpec) (*model.Job, error) {
+	return s.jobRepo.FindActive(ctx, spec.DeviceID, spec.Type, spec.Target)
+}
+
+// Cancel 取消未结束的任务，正在执行的任务会中断当前的模型调用
+func (s *JobService) Cancel(ctx context.Context, deviceID uuid.UUID, id string) (*model.Job, error) {
+	job, err := s.Get(ctx, deviceID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.jobRepo.Cancel(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJobFinished
+	}
+
+	s.mu.Lock()
+	if cancel, running := s.running[job.ID]; running {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	logger.Info("已取消后台任务", zap.String("job_id", job.ID.String()))
+	return s.jobRepo.GetByID(ctx, id)
+}
+
+// Resume 恢复执行上次服务关闭（或崩溃）时未完成的任务
+func (s *JobService) Resume(ctx context.Context) error {
+	jobs, err := s.jobRepo.ListUnfinished(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		s.enqueue(job)
+	}
+	if len(jobs) > 0 {
+		logger.Info("恢复未完成的后台任务", zap.Int("count", len(jobs)))
+	}
+	return nil
+}
+
+// Shutdown 中断正在执行的任务并放回队列，等待执行协程退出
+func (s *JobService) Shutdown(ctx context.Context) {
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("等待后台任务退出超时")
+	}
+}
+
+func (s *JobService) enqueue(job *model.Job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(job)
+	}()
+}
+
+// run 等待空闲的执行槽位后执行任务
+func (s *JobService) run(job *model.Job) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-s.ctx.Done():
+		return
+	}
+
+	handler, ok := s.handlers[job.Type]
+	if !ok {
+		s.finish(job, model.JobStatusFailed, "", "未知的任务类型: "+job.Type, false)
+		return
+	}
+	if job.Attempts >= s.opts.MaxAttempts {
+		logger.Warn("任务多次被中断，不再重试", jobFields(job, zap.Int("attempts", job.Attempts))...)
+		s.finish(job, model.JobStatusFailed, "", "任务多次被中断，已停止重试", false)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.mu.Lock()
+	s.running[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	started, err := s.jobRepo.MarkRunning(jobCtx, job.ID)
+	if err != nil {
+		logger.Error("更新任务状态失败", jobFields(job, zap.Error(err))...)
+		return
+	}
+	if !started {
+		// 排队期间已被取消
+		return
+	}
+	job.Status = model.JobStatusRunning
+	job.Attempts++
+
+	ctx := llm.TrackSynthetic(jobCtx)
+	if job.NoCache {
+		ctx = llm.WithCacheBypass(ctx)
+	}
+	ctx = withJobProgress(ctx, func(step string, index, total int) {
+		if err := s.jobRepo.UpdateProgress(context.Background(), job.ID, step, index, total); err != nil {
+			logger.Warn("更新任务进度失败", jobFields(job, zap.Error(err))...)
+		}
+	})
+	ctx = withJobResult(ctx, func(result interface{}) {
+		data, err := json.Marshal(result)
+		if err == nil {
+			err = s.jobRepo.UpdateResult(context.Background(), job.ID, string(data))
+		}
+		if err != nil {
+			logger.Warn("保存任务阶段性结果失败", jobFields(job, zap.Error(err))...)
+		}
+	})
+
+	logger.Info("开始执行后台任务", jobFields(job, zap.Int("attempt", job.Attempts))...)
+	result, err := runJobHandler(ctx, handler, job)
+
+	switch {
+	case err != nil && s.ctx.Err() != nil:
+		// 服务关闭导致中断，放回队列等待重启后继续
+		if err := s.jobRepo.Requeue(context.Background(), job.ID); err != nil {
+			logger.Error("任务放回队列失败", jobFields(job, zap.Error(err))...)
+		}
+		logger.Info("服务关闭，任务将在重启后继续执行", jobFields(job)...)
+	case err != nil && jobCtx.Err() != nil:
+		logger.Info("后台任务已取消", jobFields(job)...)
+	case err != nil:
+		logger.Error("后台任务失败", jobFields(job, zap.Error(err))...)
+		s.finish(job, model.JobStatusFailed, "", err.Error(), llm.IsSynthetic(ctx))
+	default:
+		data, err := json.Marshal(result)
+		if err != nil {
+			s.finish(job, model.JobStatusFailed, "", "序列化任务结果失败: "+err.Error(), llm.IsSynthetic(ctx))
+			return
+		}
+		s.finish(job, model.JobStatusSucceeded, string(data), "", llm.IsSynthetic(ctx))
+		logger.Info("后台任务完成", jobFields(job)...)
+	}
+}
+
+// runJobHandler 执行任务，panic 视为任务失败而不影响服务
+func runJobHandler(ctx context.Context, handler JobHandler, job *model.Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务执行异常: %v", r)
+		}
+	}()
+	return handler.Run(ctx, job)
+}
+
+// jobFields 任务相关的日志字段
+func jobFields(job *model.Job, fields ...zap.Field) []zap.Field {
+	return append([]zap.Field{
+		zap.String("job_id", job.ID.String()),
+		zap.String("type", job.Type),
+	}, fields...)
+}
+
+func (s *JobService) finish(job *model.Job, status, result, errMsg string, synthetic bool) {
+	if _, err := s.jobRepo.Finish(context.Background(), job.ID, status, result, errMsg, synthetic); err != nil {
+		logger.Error("更新任务状态失败", zap.String("job_id", job.ID.String()), zap.Error(err))
+	}
+}
+
+// jobProgressKey 任务进度回调在 context 中的键
+type jobProgressKey struct{}
+
+type jobProgressFunc func(step string, index, total int)
+
+func withJobProgress(ctx context.Context, fn jobProgressFunc) context.Context {
+	return context.WithValue(ctx, jobProgressKey{}, fn)
+}
+
+// reportProgress 上报当前步骤（index 从 1 开始），不在后台任务中执行时忽略
+func reportProgress(ctx context.Context, step string, index, total int) {
+	if fn, ok := ctx.Value(jobProgressKey{}).(jobProgressFunc); ok {
+		fn(step, index, total)
+	}
+}
+
+// jobResultKey 阶段性结果回调在 context 中的键
+type jobResultKey struct{}
+
+type jobResultFunc func(result interface{})
+
+func withJobResult(ctx context.Context, fn jobResultFunc) context.Context {
+	return context.WithValue(ctx, jobResultKey{}, fn)
+}
+
+// reportResult 保存阶段性结果（如批量生成中每一章的结果），任务失败或重启后仍可查看与继续，
+// 不在后台任务中执行时忽略
+func reportResult(ctx context.Context, result interface{}) {
+	if fn, ok := ctx.Value(jobResultKey{}).(jobResultFunc); ok {
+		fn(result)
+	}
+}
+
+// decodeJobParams 解析任务参数
+func decodeJobParams(job *model.Job, target interface{}) error {
+	if job.Params == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(job.Params), target); err != nil {
+		return fmt.Errorf("任务参数解析失败: %w", err)
+	}
+	return nil
+}
@@ -39,6 +39,9 @@ func (s *ModelConfigService) Create(ctx context.Context, deviceID uuid.UUID, req
 		logger.Error("提供商不存在", zap.Int("provider_id", req.ProviderID), zap.Error(err))
 		return nil, errors.New("提供商不存在")
 	}
+	if req.APIKey == "" && provider.AuthType != "none" {
+		return nil, errors.New("API Key 不能为空")
+	}
 
 	config := &model.ModelConfig{
 		DeviceID:   deviceID,
@@ -121,6 +124,14 @@ func (s *ModelConfigService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Health 获取模型配置的并发与熔断状态，尚未发生过调用时为正常状态
+func (s *ModelConfigService) Health(configID uuid.UUID) llm.GuardStatus {
+	if status, ok := s.llmManager.GuardStatus(configID.String()); ok {
+		return status
+	}
+	return llm.GuardStatus{State: llm.BreakerClosed}
+}
+
 // ListProviders 获取提供商列表
 func (s *ModelConfigService) ListProviders(ctx context.Context) ([]*model.ModelProvider, error) {
 	providers, err := s.modelRepo.ListActiveProviders(ctx)
@@ -139,31 +150,23 @@ func (s *ModelConfigService) Validate(ctx context.Context, req *dto.ValidateMode
 		return errors.New("提供商不存在")
 	}
 
+	if req.APIKey == "" && provider.AuthType != "none" {
+		return errors.New("API Key 不能为空")
+	}
+
 	// 确定 BaseURL
 	baseURL := req.BaseURL
 	if baseURL == "" {
 		baseURL = provider.BaseURL
 	}
 
-	// 使用 OpenAI 适配器进行验证
-	adapter := llm.NewOpenAIAdapter(baseURL, "gpt-3.5-turbo")
+	// 按提供商选择对应的适配器进行验证
+	adapter := s.llmManager.BaseAdapter(provider.Name, baseURL, req.ModelName)
 	if err := adapter.ValidateConfig(req.APIKey, baseURL); err != nil {
-		return err
-	}
-
-	// 尝试发送一个简单请求验证
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: "Hello"},
-	}
-	options := llm.ChatOptions{
-		Temperature: 0.7,
-		MaxTokens:   10,
-		APIKey:      req.APIKey,
-	}
-
-	_, err = adapter.ChatCompletion(ctx, messages, options)
-	if err != nil {
-		logger.Error("验证模型配置失败", zap.Error(err))
+		logger.Error("验证模型配置失败",
+			zap.String("provider", provider.Name),
+			zap.Error(err),
+		)
 		return errors.New("API 验证失败: " + err.Error())
 	}
 
@@ -196,12 +199,17 @@ func (s *ModelConfigService) ListBindings(ctx context.Context, deviceID uuid.UUI
 	return bindings, nil
 }
 
-// UpsertBinding 创建或更新功能绑定
-func (s *ModelConfigService) UpsertBinding(ctx context.Context, deviceID uuid.UUID, purpose string, configID uuid.UUID) (*model.ModelBinding, error) {
-	// 验证 configID 对应的配置存在
-	if _, err := s.modelRepo.GetByID(ctx, configID.String()); err != nil {
+// UpsertBinding 创建或更新功能绑定，fallbackIDs / params 为 nil 时保留原有设置
+func (s *ModelConfigService) UpsertBinding(ctx context.Context, deviceID uuid.UUID, purpose string, configID uuid.UUID, fallbackIDs []uuid.UUID, params *model.GenerationParams) (*model.ModelBinding, error) {
+	// 验证 configID 对应的配置存在且属于该设备
+	if config, err := s.modelRepo.GetByID(ctx, configID.String()); err != nil || config.DeviceID != deviceID {
 		return nil, errors.New("模型配置不存在")
 	}
+	if params != nil {
+		if err := validateGenerationParams(params); err != nil {
+			return nil, err
+		}
+	}
 
 	binding := &model.ModelBinding{
 		DeviceID:      deviceID,
@@ -209,6 +217,34 @@ func (s *ModelConfigService) UpsertBinding(ctx context.Context, deviceID uuid.UU
 		ModelConfigID: configID,
 	}
 
+	if fallbackIDs == nil || params == nil {
+		if existing, err := s.modelRepo.GetBinding(ctx, deviceID.String(), purpose); err == nil {
+			if fallbackIDs == nil {
+				fallbackIDs = existing.GetFallbackConfigIDs()
+			}
+			if params == nil {
+				binding.Params = existing.Params
+			}
+		}
+	}
+	if params != nil {
+		binding.SetParams(*params)
+	}
+	// 去重，并排除主模型自身
+	seen := map[uuid.UUID]bool{configID: true}
+	chain := make([]uuid.UUID, 0, len(fallbackIDs))
+	for _, id := range fallbackIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if config, err := s.modelRepo.GetByID(ctx, id.String()); err != nil || config.DeviceID != deviceID {
+			return nil, errors.New("备用模型配置不存在: " + id.String())
+		}
+		chain = append(chain, id)
+	}
+	binding.SetFallbackConfigIDs(chain)
+
 	if err := s.modelRepo.UpsertBinding(ctx, binding); err != nil {
 		logger.Error("保存功能绑定失败", zap.Error(err))
 		return nil, err
@@ -227,6 +263,34 @@ func (s *ModelConfigService) UpsertBinding(ctx context.Context, deviceID uuid.UU
 	return binding, nil
 }
 
+// validateGenerationParams 检查生成参数取值范围
+func validateGenerationParams(p *model.GenerationParams) error {
+	if p.Temperature != nil && (*p.Temperature <= 0 || *p.Temperature > 2) {
+		return errors.New("temperature 取值范围为 (0, 2]")
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return errors.New("top_p 取值范围为 (0, 1]")
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return errors.New("max_tokens 必须大于 0")
+	}
+	if len(p.Stop) > 4 {
+		return errors.New("stop 最多 4 个")
+	}
+	for _, s := range p.Stop {
+		if s == "" {
+			return errors.New("stop 不能包含空字符串")
+		}
+	}
+	if p.PresencePenalty != nil && (*p.PresencePenalty < -2 || *p.PresencePenalty > 2) {
+		return errors.New("presence_penalty 取值范围为 [-2, 2]")
+	}
+	if p.FrequencyPenalty != nil && (*p.FrequencyPenalty < -2 || *p.FrequencyPenalty > 2) {
+		return errors.New("frequency_penalty 取值范围为 [-2, 2]")
+	}
+	return nil
+}
+
 // DeleteBinding 删除功能绑定
 func (s *ModelConfigService) DeleteBinding(ctx context.Context, deviceID uuid.UUID, purpose string) error {
 	if err := s.modelRepo.DeleteBinding(ctx, deviceID.String(), purpose); err != nil {
@@ -0,0 +1,564 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/internal/repository"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Purpose 模型调用用途
+type Purpose string
+
+const (
+	PurposeArchitecture   Purpose = "architecture"    // 架构生成
+	PurposeBlueprint      Purpose = "blueprint"       // 章节大纲
+	PurposeChapter        Purpose = "chapter"         // 章节正文
+	PurposeEnrich         Purpose = "enrich"          // 章节扩写
+	PurposeGraph          Purpose = "graph"           // 图谱提取
+	PurposeReview         Purpose = "review"          // 审阅与检测
+	PurposeWriting        Purpose = "writing"         // 写作助手
+	PurposeChat           Purpose = "chat"            // 对话
+	PurposeSummary        Purpose = "summary"         // 章节摘要与前文压缩
+	PurposeCharacterState Purpose = "character_state" // 角色状态更新
+	PurposeEmbedding      Purpose = "embedding"       // 向量嵌入
+)
+
+// purposeProfile 用途对应的功能绑定与默认生成参数
+type purposeProfile struct {
+	Binding     string
+	Temperature float32
+	MaxTokens   int
+}
+
+var purposeProfiles = map[Purpose]purposeProfile{
+	PurposeArchitecture:   {Binding: "architecture", Temperature: 0.7, MaxTokens: 4000},
+	PurposeBlueprint:      {Binding: "architecture", Temperature: 0.7, MaxTokens: 4096},
+	PurposeChapter:        {Binding: "chapter", Temperature: 0.8, MaxTokens: 8000},
+	PurposeEnrich:         {Binding: "chapter", Temperature: 0.7, MaxTokens: 10000},
+	PurposeGraph:          {Binding: "architecture", Temperature: 0.3, MaxTokens: 4096},
+	PurposeReview:         {Binding: "review", Temperature: 0.3, MaxTokens: 4096},
+	PurposeWriting:        {Binding: "writing", Temperature: 0.7, MaxTokens: 4096},
+	PurposeChat:           {Binding: "general", Temperature: 0.85, MaxTokens: 4096},
+	PurposeSummary:        {Binding: "general", Temperature: 0.3, MaxTokens: 2048},
+	PurposeCharacterState: {Binding: "architecture", Temperature: 0.3, MaxTokens: 4096},
+	PurposeEmbedding:      {Binding: "embedding"},
+}
+
+func (p Purpose) profile() purposeProfile {
+	if profile, ok := purposeProfiles[p]; ok {
+		return profile
+	}
+	return purposeProfile{Binding: "general", Temperature: 0.7, MaxTokens: 4096}
+}
+
+// ErrModelNotConfigured 当前用途没有可用的模型配置
+var ErrModelNotConfigured = errors.New("未配置可用的模型")
+
+// ErrEmbeddingNotConfigured 没有单独绑定嵌入模型（对话模型一般不能计算向量，因此不回退到 general）
+var ErrEmbeddingNotConfigured = errors.New("未绑定嵌入模型")
+
+// InvokeRequest 模型调用请求
+type InvokeRequest struct {
+	DeviceID  uuid.UUID
+	ProjectID string // 关联项目，用于用量统计，可为空
+	Purpose   Purpose
+	Messages  []llm.ChatMessage
+
+	// 为 0 时使用用途默认值；功能绑定设置了生成参数时以绑定为准
+	Temperature float32
+	MaxTokens   int
+
+	// 结构化输出要求，一般通过 ChatJSON 设置
+	ResponseFormat *llm.ResponseFormat
+	// 跳过响应缓存，也可通过 llm.WithCacheBypass 在 ctx 上设置
+	NoCache bool
+	// 可供模型调用的工具，一般通过 ChatWithTools / StreamWithTools 设置
+	Tools []llm.Tool
+	// 指定使用的模型配置，为空时使用用途绑定的模型链；指定时不切换备用模型
+	ModelConfigID *uuid.UUID
+}
+
+// userMessages 将单条提示词包装为消息列表
+func userMessages(prompt string) []llm.ChatMessage {
+	return []llm.ChatMessage{{Role: "user", Content: prompt}}
+}
+
+// ModelInvoker 模型调用服务：按用途解析绑定的模型配置并调用对应适配器
+type ModelInvoker struct {
+	modelRepo    modelChainStore
+	llmManager   *llm.Manager
+	usageService *UsageService
+}
+
+// NewModelInvoker 创建模型调用服务
+func NewModelInvoker(
+	modelRepo *repository.ModelConfigRepository,
+	llmManager *llm.Manager,
+	usageService *UsageService,
+) *ModelInvoker {
+	return &ModelInvoker{
+		modelRepo:    modelRepo,
+		llmManager:   llmManager,
+		usageService: usageService,
+	}
+}
+
+// Resolve 获取用途绑定的主模型配置（未绑定时回退到 general）
+func (s *ModelInvoker) Resolve(ctx context.Context, deviceID uuid.UUID, purpose Purpose) (*model.ModelConfig, error) {
+	chain, _, err := s.resolveChain(ctx, deviceID, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return chain[0], nil
+}
+
+// ResolveConfig 获取设备下指定的可用模型配置
+func (s *ModelInvoker) ResolveConfig(ctx context.Context, deviceID, configID uuid.UUID) (*model.ModelConfig, error) {
+	config, err := s.modelRepo.GetByID(ctx, configID.String())
+	if err != nil || config.DeviceID != deviceID {
+		return nil, fmt.Errorf("%w（%s）: 模型配置不存在", ErrModelNotConfigured, configID)
+	}
+	if !config.IsActive {
+		return nil, fmt.Errorf("%w（%s）: 模型配置已停用", ErrModelNotConfigured, config.ModelName)
+	}
+	return config, nil
+}
+
+// resolveRequest 获取调用使用的模型链：指定了模型配置时只使用该配置，生成参数仍取用途绑定的设置
+func (s *ModelInvoker) resolveRequest(ctx context.Context, req *InvokeRequest) ([]*model.ModelConfig, model.GenerationParams, error) {
+	if req.ModelConfigID == nil {
+		return s.resolveChain(ctx, req.DeviceID, req.Purpose)
+	}
+	config, err := s.ResolveConfig(ctx, req.DeviceID, *req.ModelConfigID)
+	if err != nil {
+		return nil, model.GenerationParams{}, err
+	}
+	var params model.GenerationParams
+	if binding, err := s.modelRepo.GetBinding(ctx, req.DeviceID.String(), req.Purpose.profile().Binding); err == nil {
+		params = binding.GetParams()
+	}
+	return []*model.ModelConfig{config}, params, nil
+}
+
+// resolveChain 获取用途绑定的模型链（主模型 + 备用模型）与绑定的生成参数
+func (s *ModelInvoker) resolveChain(ctx context.Context, deviceID uuid.UUID, purpose Purpose) ([]*model.ModelConfig, model.GenerationParams, error) {
+	name := purpose.profile().Binding
+	binding, chain, err := s.modelRepo.GetChainByPurpose(ctx, deviceID.String(), name)
+	if err != nil {
+		return nil, model.GenerationParams{}, fmt.Errorf("%w（%s）: %v", ErrModelNotConfigured, name, err)
+	}
+	return chain, binding.GetParams(), nil
+}
+
+// Precheck 调用前检查模型配置与配额，用于流式接口在发出响应头之前返回错误；
+// 模型链中任一模型未超出配额即可通过，返回主模型的配额错误
+func (s *ModelInvoker) Precheck(ctx context.Context, req *InvokeRequest) error {
+	chain, _, err := s.resolveRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	var first error
+	for i, config := range chain {
+		err := s.checkQuota(ctx, req, config)
+		if err == nil {
+			return nil
+		}
+		if i == 0 {
+			first = err
+		}
+	}
+	return first
+}
+
+// Chat 非流式调用，主模型失败时按顺序切换到备用模型
+func (s *ModelInvoker) Chat(ctx context.Context, req *InvokeRequest) (string, error) {
+	resp, _, err := s.chat(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// ChatResult 非流式调用结果
+type ChatResult struct {
+	Content     string
+	Synthetic   bool               // 由模拟提供商生成
+	ModelConfig *model.ModelConfig // 实际提供服务的模型配置，可能是备用模型
+}
+
+// ChatDetailed 同 Chat，并返回实际提供服务的模型
+func (s *ModelInvoker) ChatDetailed(ctx context.Context, req *InvokeRequest) (*ChatResult, error) {
+	resp, config, err := s.chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatResult{Content: resp.Content, Synthetic: resp.Synthetic, ModelConfig: config}, nil
+}
+
+func (s *ModelInvoker) chat(ctx context.Context, req *InvokeRequest) (*llm.ChatResponse, *model.ModelConfig, error) {
+	chain, params, err := s.resolveRequest(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, config := range chain {
+		// 配额按模型计算，超出配额的模型直接切换到下一个
+		if err := s.checkQuota(ctx, req, config); err != nil {
+			if !s.canFailover(ctx, i, len(chain)) {
+				return nil, nil, err
+			}
+			continue
+		}
+		adapter, options := s.prepare(config, req, params)
+		resp, err := adapter.ChatCompletion(ctx, req.Messages, options)
+		if err == nil {
+			if resp.Synthetic {
+				llm.MarkSynthetic(ctx)
+			}
+			s.logServed(config, req, i, resp)
+			s.recordUsage(ctx, config, req, resp)
+			return resp, config, nil
+		}
+		s.logFailure(config, req, err)
+		if !s.canFailover(ctx, i, len(chain)) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, ErrModelNotConfigured
+}
+
+// Stream 流式调用，仅在尚未输出任何内容时切换到备用模型；出错时返回已输出的部分内容
+func (s *ModelInvoker) Stream(ctx context.Context, req *InvokeRequest, callback llm.StreamCallback) (string, error) {
+	resp, err := s.stream(ctx, req, callback)
+	if resp == nil {
+		return "", err
+	}
+	return resp.Content, err
+}
+
+func (s *ModelInvoker) stream(ctx context.Context, req *InvokeRequest, callback llm.StreamCallback) (*llm.ChatResponse, error) {
+	chain, params, err := s.resolveRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	started := false
+	tracked := func(chunk string) error {
+		started = true
+		return callback(chunk)
+	}
+	for i, config := range chain {
+		if err := s.checkQuota(ctx, req, config); err != nil {
+			if !s.canFailover(ctx, i, len(chain)) {
+				return nil, err
+			}
+			continue
+		}
+		adapter, options := s.prepare(config, req, params)
+		// 流式响应头在首个数据块前发出，需提前标记模拟输出
+		if isMockConfig(config) {
+			llm.MarkSynthetic(ctx)
+		}
+		resp, err := adapter.StreamChatCompletion(ctx, req.Messages, options, tracked)
+		if err == nil {
+			s.logServed(config, req, i, resp)
+			s.recordUsage(ctx, config, req, resp)
+			return resp, nil
+		}
+		s.logFailure(config, req, err)
+		if started || !s.canFailover(ctx, i, len(chain)) {
+			if resp != nil {
+				// 已输出的部分内容同样消耗了 token
+				s.recordUsage(ctx, config, req, resp)
+			}
+			return resp, err
+		}
+	}
+	return nil, ErrModelNotConfigured
+}
+
+// embedBatchSize 单次嵌入请求最多包含的文本数
+const embedBatchSize = 64
+
+// EmbedResult 向量嵌入结果
+type EmbedResult struct {
+	Vectors [][]float32 // 与输入文本一一对应
+	Model   string      // 实际计算向量的模型，不同模型的向量不可混用
+}
+
+// Embed 使用 embedding 绑定的模型计算文本向量，主模型失败时按顺序切换到备用模型；
+// dimensions 为请求的输出维度，0 表示使用模型默认值。
+// 未绑定 embedding 时仅在 general 绑定为模拟提供商时回退，否则返回 ErrEmbeddingNotConfigured
+func (s *ModelInvoker) Embed(ctx context.Context, deviceID uuid.UUID, projectID string, texts []string, dimensions int) (*EmbedResult, error) {
+	binding, chain, err := s.modelRepo.GetChainByPurpose(ctx, deviceID.String(), PurposeEmbedding.profile().Binding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEmbeddingNotConfigured, err)
+	}
+	if binding.Purpose != PurposeEmbedding.profile().Binding && !isMockConfig(chain[0]) {
+		return nil, ErrEmbeddingNotConfigured
+	}
+
+	req := &InvokeRequest{DeviceID: deviceID, ProjectID: projectID, Purpose: PurposeEmbedding}
+	for _, text := range texts {
+		req.Messages = append(req.Messages, llm.ChatMessage{Role: "user", Content: text})
+	}
+	for i, config := range chain {
+		if err := s.checkQuota(ctx, req, config); err != nil {
+			if !s.canFailover(ctx, i, len(chain)) {
+				return nil, err
+			}
+			continue
+		}
+		vectors, err := s.embedWith(ctx, config, req, dimensions)
+		if err == nil {
+			logger.Info("向量嵌入完成", append(logFields(config, req),
+				zap.Int("fallback", i),
+				zap.Int("texts", len(texts)),
+			)...)
+			return &EmbedResult{Vectors: vectors, Model: config.ModelName}, nil
+		}
+		s.logFailure(config, req, err)
+		if !s.canFailover(ctx, i, len(chain)) {
+			return nil, err
+		}
+	}
+	return nil, ErrEmbeddingNotConfigured
+}
+
+// embedWith 使用指定模型分批计算向量并记录用量
+func (s *ModelInvoker) embedWith(ctx context.Context, config *model.ModelConfig, req *InvokeRequest, dimensions int) ([][]float32, error) {
+	adapter, options := s.prepare(config, req, model.GenerationParams{})
+	vectors := make([][]float32, 0, len(req.Messages))
+	for start := 0; start < len(req.Messages); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(req.Messages) {
+			end = len(req.Messages)
+		}
+		batch := make([]string, 0, end-start)
+		for _, m := range req.Messages[start:end] {
+			batch = append(batch, m.Content)
+		}
+
+		resp, err := adapter.Embed(ctx, batch, llm.EmbeddingOptions{APIKey: options.APIKey, Dimensions: dimensions})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Synthetic {
+			llm.MarkSynthetic(ctx)
+		}
+		usage := resp.Usage
+		if usage.IsZero() {
+			usage = llm.EstimateUsage(req.Messages[start:end], "")
+		}
+		s.recordUsage(ctx, config, req, &llm.ChatResponse{Usage: usage, Synthetic: resp.Synthetic})
+		vectors = append(vectors, resp.Vectors...)
+	}
+	return vectors, nil
+}
+
+// maxToolRounds 单次调用最多执行的工具轮数，之后不再提供工具，要求模型直接回答
+const maxToolRounds = 5
+
+// ChatWithTools 非流式调用；模型要求调用工具时执行工具并把结果发回，直到模型给出最终回答
+func (s *ModelInvoker) ChatWithTools(ctx context.Context, req *InvokeRequest, toolbox *llm.Toolbox) (string, error) {
+	return s.runTools(ctx, req, toolbox, func(round *InvokeRequest) (*llm.ChatResponse, error) {
+		resp, _, err := s.chat(ctx, round)
+		return resp, err
+	})
+}
+
+// StreamWithTools 流式调用，工具循环同 ChatWithTools；各轮输出的文本都会推送给 callback
+func (s *ModelInvoker) StreamWithTools(ctx context.Context, req *InvokeRequest, toolbox *llm.Toolbox, callback llm.StreamCallback) (string, error) {
+	return s.runTools(ctx, req, toolbox, func(round *InvokeRequest) (*llm.ChatResponse, error) {
+		return s.stream(ctx, round, callback)
+	})
+}
+
+// runTools 工具循环，返回各轮输出文本的拼接
+func (s *ModelInvoker) runTools(ctx context.Context, req *InvokeRequest, toolbox *llm.Toolbox, call func(*InvokeRequest) (*llm.ChatResponse, error)) (string, error) {
+	round := *req
+	round.Messages = append([]llm.ChatMessage(nil), req.Messages...)
+
+	var content strings.Builder
+	for i := 0; ; i++ {
+		round.Tools = nil
+		if i < maxToolRounds {
+			round.Tools = toolbox.Tools()
+		}
+
+		resp, err := call(&round)
+		if resp != nil {
+			content.WriteString(resp.Content)
+		}
+		if err != nil {
+			return content.String(), err
+		}
+		if len(resp.ToolCalls) == 0 || round.Tools == nil {
+			return content.String(), nil
+		}
+
+		round.Messages = append(round.Messages, llm.ChatMessage{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, tc := range resp.ToolCalls {
+			logger.Info("执行工具调用",
+				zap.String("purpose", string(req.Purpose)),
+				zap.String("tool", tc.Name),
+				zap.String("arguments", tc.Arguments),
+			)
+			round.Messages = append(round.Messages, llm.ChatMessage{
+				Role:       llm.RoleTool,
+				Content:    toolbox.Call(ctx, tc),
+				ToolCallID: tc.ID,
+			})
+		}
+	}
+}
+
+// canFailover 是否还能切换到下一个备用模型（调用方取消时不再切换）
+func (s *ModelInvoker) canFailover(ctx context.Context, index, total int) bool {
+	return index+1 < total && ctx.Err() == nil
+}
+
+// prepare 构建适配器与调用参数：用途默认值 < 调用方指定 < 功能绑定的生成参数
+func (s *ModelInvoker) prepare(config *model.ModelConfig, req *InvokeRequest, params model.GenerationParams) (llm.LLMAdapter, llm.ChatOptions) {
+	provider := "openai"
+	baseURL := config.BaseURL
+	if config.Provider != nil {
+		provider = config.Provider.Name
+		if baseURL == "" {
+			baseURL = config.Provider.BaseURL
+		}
+	}
+
+	profile := req.Purpose.profile()
+	options := llm.ChatOptions{
+		Temperature:    profile.Temperature,
+		MaxTokens:      profile.MaxTokens,
+		APIKey:         config.APIKey,
+		ResponseFormat: req.ResponseFormat,
+		NoCache:        req.NoCache,
+		Tools:          req.Tools,
+	}
+	if req.Temperature > 0 {
+		options.Temperature = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		options.MaxTokens = req.MaxTokens
+	}
+	applyGenerationParams(&options, params)
+
+	return s.llmManager.NewGuardedAdapter(config.ID.String(), provider, baseURL, config.ModelName), options
+}
+
+// applyGenerationParams 用功能绑定的生成参数覆盖调用参数
+func applyGenerationParams(options *llm.ChatOptions, params model.GenerationParams) {
+	if params.Temperature != nil {
+		options.Temperature = *params.Temperature
+	}
+	if params.TopP != nil {
+		options.TopP = *params.TopP
+	}
+	if params.MaxTokens != nil {
+		options.MaxTokens = *params.MaxTokens
+	}
+	if len(params.Stop) > 0 {
+		options.Stop = params.Stop
+	}
+	if params.PresencePenalty != nil {
+		options.PresencePenalty = *params.PresencePenalty
+	}
+	if params.FrequencyPenalty != nil {
+		options.FrequencyPenalty = *params.FrequencyPenalty
+	}
+}
+
+// isMockConfig 是否为模拟提供商的模型配置
+func isMockConfig(config *model.ModelConfig) bool {
+	return config.Provider != nil && config.Provider.Name == llm.MockProvider
+}
+
+// checkQuota 调用前检查月度配额
+func (s *ModelInvoker) checkQuota(ctx context.Context, req *InvokeRequest, config *model.ModelConfig) error {
+	if s.usageService == nil {
+		return nil
+	}
+	if err := s.usageService.CheckQuota(ctx, req.DeviceID, req.ProjectID, config.ModelName, req.Messages); err != nil {
+		logger.Warn("LLM 调用被配额拒绝", append(logFields(config, req), zap.Error(err))...)
+		return err
+	}
+	return nil
+}
+
+// recordUsage 记录调用用量与费用，上游未返回用量时按字数估算；写入失败不影响调用结果
+func (s *ModelInvoker) recordUsage(ctx context.Context, config *model.ModelConfig, req *InvokeRequest, resp *llm.ChatResponse) {
+	// 命中缓存与模拟输出的调用不产生费用
+	if s.usageService == nil || resp == nil || resp.Cached || resp.Synthetic {
+		return
+	}
+
+	usage := resp.Usage
+	if usage.IsZero() {
+		if resp.Content == "" {
+			return
+		}
+		usage = llm.EstimateUsage(req.Messages, resp.Content)
+	}
+
+	record := &model.LLMUsage{
+		DeviceID:         req.DeviceID,
+		Purpose:          string(req.Purpose),
+		ModelConfigID:    config.ID,
+		Model:            config.ModelName,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Estimated:        usage.Estimated,
+	}
+	if config.Provider != nil {
+		record.Provider = config.Provider.Name
+	}
+	if projectID, err := uuid.Parse(req.ProjectID); err == nil {
+		record.ProjectID = &projectID
+	}
+
+	// 调用方取消时仍需落库
+	if err := s.usageService.Record(context.WithoutCancel(ctx), record); err != nil {
+		logger.Warn("记录 LLM 用量失败", append(logFields(config, req), zap.Error(err))...)
+	}
+}
+
+// logFields 调用日志的公共字段
+func logFields(config *model.ModelConfig, req *InvokeRequest) []zap.Field {
+	fields := []zap.Field{
+		zap.String("purpose", string(req.Purpose)),
+		zap.String("model_config_id", config.ID.String()),
+		zap.String("model", config.ModelName),
+	}
+	if config.Provider != nil {
+		fields = append(fields, zap.String("provider", config.Provider.Name))
+	}
+	return fields
+}
+
+// logServed 记录实际提供服务的模型，fallback 为 0 表示主模型
+func (s *ModelInvoker) logServed(config *model.ModelConfig, req *InvokeRequest, fallback int, resp *llm.ChatResponse) {
+	logger.Info("LLM 调用完成", append(logFields(config, req),
+		zap.Int("fallback", fallback),
+		zap.Bool("cached", resp.Cached),
+		zap.Bool("synthetic", resp.Synthetic),
+	)...)
+}
+
+func (s *ModelInvoker) logFailure(config *model.ModelConfig, req *InvokeRequest, err error) {
+	logger.Error("LLM 调用失败", append(logFields(config, req), zap.Error(err))...)
+}
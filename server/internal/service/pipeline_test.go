@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// testdata/fixtures 中的响应是手写的桩数据（model 为 handwritten-stub），不是真实模型的输出，
+// 用于检查服务构建的提示词与保存的结果；服务改变提示词或调用顺序后回放找不到匹配的 fixture，测试失败。
+// 设置 -update 时改为调用 XNOVEL_FIXTURE_* 配置的上游，用真实输出替换这些文件：
+// XNOVEL_FIXTURE_BASE_URL=... XNOVEL_FIXTURE_API_KEY=... XNOVEL_FIXTURE_MODEL=... \
+// go test ./internal/service -run TestPipelineReplay -update
+var updateFixtures = flag.Bool("update", false, "调用 XNOVEL_FIXTURE_* 配置的上游并录制 testdata/fixtures")
+
+const pipelineFixtureDir = "testdata/fixtures"
+
+// pipelineEnv 使用内存仓储的项目与章节服务，模型调用默认严格回放 testdata/fixtures，不访问网络
+type pipelineEnv struct {
+	deviceID uuid.UUID
+	projects *memProjectStore
+	chapters *memChapterStore
+	states   *memCharacterStateStore
+
+	projectService *ProjectService
+	chapterService *ChapterService
+}
+
+func newPipelineEnv(t *testing.T) *pipelineEnv {
+	t.Helper()
+	logger.Logger = zap.NewNop()
+	store := llm.NewFixtureStore(pipelineFixtureDir)
+	manager := llm.NewManager()
+	deviceID := uuid.New()
+	config := &model.ModelConfig{
+		ID:        uuid.New(),
+		DeviceID:  deviceID,
+		ModelName: "handwritten-stub",
+		APIKey:    "test",
+		// 回放时不会访问该地址
+		BaseURL:  "http://127.0.0.1:1",
+		IsActive: true,
+		Provider: &model.ModelProvider{Name: "openai"},
+	}
+
+	if *updateFixtures {
+		baseURL := os.Getenv("XNOVEL_FIXTURE_BASE_URL")
+		if baseURL == "" {
+			t.Fatal("录制需要设置 XNOVEL_FIXTURE_BASE_URL、XNOVEL_FIXTURE_API_KEY 与 XNOVEL_FIXTURE_MODEL")
+		}
+		config.BaseURL = baseURL
+		config.APIKey = os.Getenv("XNOVEL_FIXTURE_API_KEY")
+		config.ModelName = os.Getenv("XNOVEL_FIXTURE_MODEL")
+		if provider := os.Getenv("XNOVEL_FIXTURE_PROVIDER"); provider != "" {
+			config.Provider.Name = provider
+		}
+		manager.SetFixtures(llm.FixtureModeRecord, store, false)
+	} else {
+		manager.SetFixtures(llm.FixtureModeReplay, store, true)
+	}
+
+	env := &pipelineEnv{
+		deviceID: deviceID,
+		projects: newMemProjectStore(),
+		chapters: newMemChapterStore(),
+		states:   newMemCharacterStateStore(),
+	}
+	invoker := &ModelInvoker{modelRepo: &memModelChainStore{config: config}, llmManager: manager}
+	env.projectService = &ProjectService{
+		projectRepo:        env.projects,
+		chapterRepo:        env.chapters,
+		characterStateRepo: env.states,
+		invoker:            invoker,
+	}
+	env.chapterService = &ChapterService{
+		projectRepo:        env.projects,
+		chapterRepo:        env.chapters,
+		characterStateRepo: env.states,
+		invoker:            invoker,
+		opts: ChapterOptions{
+			SummaryTokenBudget: defaultSummaryTokenBudget,
+			PreviousParagraphs: defaultPreviousParagraphs,
+			PreviousMaxChars:   defaultPreviousMaxChars,
+		},
+	}
+	return env
+}
+
+// chapter 按章节号读取已保存的章节
+func (e *pipelineEnv) chapter(t *testing.T, projectID string, n int) *model.Chapter {
+	t.Helper()
+	ch, err := e.chapters.GetByProjectAndNumber(context.Background(), projectID, n)
+	if err != nil {
+		t.Fatalf("第 %d 章不存在: %v", n, err)
+	}
+	return ch
+}
+
+// TestPipelineReplay 依次调用服务生成 架构 → 大纲 → 第一章（流式）→ 定稿 → 第二章，
+// 检查各阶段保存的结果，以及定稿得到的摘要与角色状态用于下一章
+func TestPipelineReplay(t *testing.T) {
+	ctx := context.Background()
+	env := newPipelineEnv(t)
+
+	project := &model.Project{
+		DeviceID:        env.deviceID,
+		Title:           "雾城旧事",
+		Topic:           "一名年轻的修表匠在雾城追查父亲失踪的真相",
+		Genre:           `["悬疑","都市"]`,
+		ChapterCount:    3,
+		WordsPerChapter: 2000,
+		UserGuidance:    "节奏紧凑，悬疑为主",
+	}
+	if err := env.projects.Create(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+	projectID := project.ID.String()
+
+	// 架构：逐步生成并保存
+	if _, err := env.projectService.GenerateArchitecture(ctx, env.deviceID, projectID); err != nil {
+		t.Fatalf("生成架构失败（提示词有变化时需要更新 fixture）: %v", err)
+	}
+	saved, _ := env.projects.GetByID(ctx, projectID)
+	if !saved.ArchitectureGenerated || !saved.ArchitectureComplete() || saved.CharacterState == "" {
+		t.Fatal("架构步骤未全部保存")
+	}
+
+	// 大纲：保存后解析为各章节
+	_, sync, err := env.projectService.GenerateBlueprint(ctx, env.deviceID, projectID, &dto.GenerateBlueprintRequest{})
+	if err != nil {
+		t.Fatalf("生成大纲失败: %v", err)
+	}
+	if sync.Parsed != project.ChapterCount || sync.Created != project.ChapterCount || len(sync.Unparsed) > 0 {
+		t.Fatalf("大纲解析结果不符合预期: %+v", sync)
+	}
+	for n := 1; n <= project.ChapterCount; n++ {
+		if ch := env.chapter(t, projectID, n); ch.Title == "" || ch.BlueprintSummary == "" {
+			t.Fatalf("第 %d 章缺少标题或简述", n)
+		}
+	}
+
+	// 第一章：流式生成，推送的内容与保存的正文一致
+	first := env.chapter(t, projectID, 1)
+	stream, err := env.chapterService.PrepareChapterContentStream(ctx, env.deviceID, projectID, first.ID.String(), &dto.GenerateChapterRequest{ChapterNumber: 1})
+	if err != nil {
+		t.Fatalf("准备流式生成失败: %v", err)
+	}
+	var pushed strings.Builder
+	if _, err := stream.Run(ctx, func(chunk string) error {
+		pushed.WriteString(chunk)
+		return nil
+	}); err != nil {
+		t.Fatalf("流式生成第一章失败: %v", err)
+	}
+	first = env.chapter(t, projectID, 1)
+	if first.Content == "" || first.Content != pushed.String() || first.Status != "draft" {
+		t.Fatalf("第一章保存结果不符合预期: status=%s 推送 %d 字，保存 %d 字", first.Status, len(pushed.String()), len(first.Content))
+	}
+
+	// 定稿：生成摘要、重建全局摘要并更新角色状态
+	if _, err := env.chapterService.FinalizeChapter(ctx, env.deviceID, projectID, first.ID.String(), &dto.FinalizeChapterRequest{
+		UpdateSummary:        true,
+		UpdateCharacterState: true,
+	}); err != nil {
+		t.Fatalf("定稿第一章失败: %v", err)
+	}
+	first = env.chapter(t, projectID, 1)
+	if first.Status != "completed" || !first.IsFinalized || first.Summary == "" {
+		t.Fatalf("第一章定稿结果不符合预期: status=%s finalized=%v summary=%q", first.Status, first.IsFinalized, first.Summary)
+	}
+	saved, _ = env.projects.GetByID(ctx, projectID)
+	if !strings.Contains(saved.GlobalSummary, first.Summary) {
+		t.Fatal("全局摘要中缺少第一章的摘要")
+	}
+	versions, _ := env.states.ListByProject(ctx, projectID)
+	if len(versions) != 2 || versions[0].ChapterNumber != 0 || versions[1].ChapterNumber != 1 {
+		t.Fatalf("角色状态版本不符合预期: %d 个", len(versions))
+	}
+	if saved.CharacterState != versions[1].Content {
+		t.Fatal("项目当前的角色状态应为第一章定稿后的版本")
+	}
+
+	// 第二章：使用第一章的摘要、结尾原文与更新后的角色状态（均包含在回放的提示词中）
+	second := env.chapter(t, projectID, 2)
+	if _, err := env.chapterService.GenerateChapterContent(ctx, env.deviceID, projectID, second.ID.String(), &dto.GenerateChapterRequest{ChapterNumber: 2}); err != nil {
+		t.Fatalf("生成第二章失败: %v", err)
+	}
+	if second = env.chapter(t, projectID, 2); second.Content == "" || second.Status != "draft" {
+		t.Fatalf("第二章保存结果不符合预期: status=%s", second.Status)
+	}
+}
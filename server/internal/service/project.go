@@ -20,30 +20,36 @@ import (
 
 // ProjectService 项目服务
 type ProjectService struct {
-	projectRepo  *repository.ProjectRepository
-	chapterRepo  *repository.ChapterRepository
-	modelRepo    *repository.ModelConfigRepository
-	llmManager   *llm.Manager
+	projectRepo  projectStore
+	chapterRepo  chapterStore
+	invoker      *ModelInvoker
 	exportService *ExportService
+	characterStateRepo characterStateStore
+	retriever    *Retriever
 }
 
 // NewProjectService 创建项目服务
 func NewProjectService(
 	projectRepo *repository.ProjectRepository,
 	chapterRepo *repository.ChapterRepository,
-	modelRepo *repository.ModelConfigRepository,
-	llmManager *llm.Manager,
+	characterStateRepo *repository.CharacterStateRepository,
+	invoker *ModelInvoker,
 	exportService *ExportService,
 ) *ProjectService {
 	return &ProjectService{
 		projectRepo:  projectRepo,
 		chapterRepo:  chapterRepo,
-		modelRepo:    modelRepo,
-		llmManager:   llmManager,
+		characterStateRepo: characterStateRepo,
+		invoker:      invoker,
 		exportService: exportService,
 	}
 }
 
+// SetRetriever 设置前文检索，删除项目时同时删除其索引
+func (s *ProjectService) SetRetriever(retriever *Retriever) {
+	s.retriever = retriever
+}
+
 // Create 创建项目
 func (s *ProjectService) Create(ctx context.Context, deviceID uuid.UUID, req *dto.CreateProjectRequest) (*model.Project, error) {
 	// 将 Genre 数组转换为 JSON 字符串
@@ -159,14 +165,36 @@ func (s *ProjectService) Update(ctx context.Context, id string, req *dto.UpdateP
 		project.CharacterState = *req.CharacterState
 	}
 
+	// 手动编辑过的步骤视为已更新
+	edited := map[string]bool{
+		model.ArchitectureStepCoreSeed:          req.CoreSeed != nil,
+		model.ArchitectureStepCharacterDynamics: req.CharacterDynamics != nil,
+		model.ArchitectureStepWorldBuilding:     req.WorldBuilding != nil,
+		model.ArchitectureStepPlotArchitecture:  req.PlotArchitecture != nil,
+		model.ArchitectureStepCharacterState:    req.CharacterState != nil,
+	}
+	var stale []string
+	for _, step := range project.GetArchitectureStale() {
+		if !edited[step] {
+			stale = append(stale, step)
+		}
+	}
+	project.SetArchitectureStale(stale)
+
 	// 更新大纲数据
 	if req.ChapterBlueprint != nil {
 		project.ChapterBlueprint = *req.ChapterBlueprint
 	}
 
-	// 更新全局摘要
-	if req.GlobalSummary != nil {
+	// 更新全局摘要：手动修改的内容作为截至最新已完成章节的前情概要，之后定稿的章节接在其后
+	if req.GlobalSummary != nil && *req.GlobalSummary != project.GlobalSummary {
+		through, err := s.chapterRepo.LatestCompletedNumber(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 		project.GlobalSummary = *req.GlobalSummary
+		project.SummaryRecap = *req.GlobalSummary
+		project.SummaryRecapThrough = through
 	}
 
 	if err := s.projectRepo.Update(ctx, project); err != nil {
@@ -189,24 +217,44 @@ func (s *ProjectService) Delete(ctx context.Context, id string) error {
 		)
 		return err
 	}
+	// 索引不随项目级联删除；删除失败只留下无法再被检索到的片段，不影响删除项目
+	if s.retriever != nil {
+		if err := s.retriever.DeleteProject(ctx, id); err != nil {
+			logger.Warn("删除项目的检索索引失败",
+				zap.String("project_id", id),
+				zap.Error(err),
+			)
+		}
+	}
 	return nil
 }
 
-// GenerateArchitecture 生成小说架构
-func (s *ProjectService) GenerateArchitecture(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateArchitectureRequest) (*model.Project, error) {
+// ResetArchitecture 清空全部架构步骤，用于覆盖重新生成；
+// 由旧架构的角色状态推演出的各章版本一并删除
+func (s *ProjectService) ResetArchitecture(ctx context.Context, projectID string) error {
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// 检查是否已经生成过
-	if project.ArchitectureGenerated && !req.Overwrite {
-		return nil, errors.New("架构已生成，如需重新生成请设置 overwrite=true")
+	if err := s.characterStateRepo.DeleteAfter(ctx, projectID, -1); err != nil {
+		return err
+	}
+	for _, step := range model.ArchitectureSteps {
+		project.SetArchitectureStep(step, "")
 	}
+	project.SetArchitectureStale(nil)
+	project.ArchitectureGenerated = false
+	project.ArchitectureSynthetic = false
+	return s.projectRepo.Update(ctx, project)
+}
 
-	logger.Info("开始生成小说架构",
-		zap.String("project_id", projectID),
-	)
+// GenerateArchitecture 生成小说架构，每完成一步立即保存；
+// 跳过已有内容的步骤，失败后再次调用时从第一个缺失的步骤继续
+func (s *ProjectService) GenerateArchitecture(ctx context.Context, deviceID uuid.UUID, projectID string) (*model.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析 Genre
 	var genres []string
@@ -217,291 +265,274 @@ func (s *ProjectService) GenerateArchitecture(ctx context.Context, deviceID uuid
 		}
 	}
 
-	// 获取用于架构生成的模型配置
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "architecture")
-	if err != nil {
-		logger.Error("获取架构生成模型配置失败", zap.Error(err))
+	// 记录各步骤是否由模拟提供商生成
+	ctx = llm.TrackSynthetic(ctx)
 
-		// 开发模式：返回模拟数据
-		logger.Info("使用模拟模式生成架构")
-		return s.generateMockArchitecture(ctx, project, genres)
-	}
+	logger.Info("开始生成小说架构",
+		zap.String("project_id", projectID),
+	)
 
-	// 准备提示词参数
-	promptParams := ArchitecturePromptParams{
-		Topic:          project.Topic,
-		Genre:          genres,
-		ChapterCount:   project.ChapterCount,
-		WordsPerChapter: project.WordsPerChapter,
-		UserGuidance:   project.UserGuidance,
+	for i, step := range model.ArchitectureSteps {
+		if project.ArchitectureStep(step) != "" {
+			continue
+		}
+
+		label := architectureStepLabels[step]
+		logger.Info(fmt.Sprintf("步骤%d: 生成%s", i+1, label))
+		reportProgress(ctx, "生成"+label, i+1, len(model.ArchitectureSteps))
+
+		content, err := s.generateArchitectureStep(ctx, deviceID, projectID, step, architectureParams(project, genres), "")
+		if err != nil {
+			logger.Error("生成"+label+"失败", zap.Error(err))
+			return nil, fmt.Errorf("生成%s失败（已完成的步骤已保存，可再次生成以继续）: %w", label, err)
+		}
+		if err := s.saveArchitectureStep(ctx, project, step, content, nil); err != nil {
+			logger.Error("保存架构数据失败", zap.String("step", step), zap.Error(err))
+			return nil, err
+		}
+		logger.Info(label + "生成成功")
 	}
 
-	// 步骤1: 生成核心种子
-	logger.Info("步骤1: 生成核心种子")
-	coreSeed, err := s.generateArchitectureStep(ctx, modelConfig, "core_seed", promptParams, "")
+	logger.Info("小说架构生成完成",
+		zap.String("project_id", projectID),
+	)
+
+	return project, nil
+}
+
+// RegenerateArchitectureStep 重新生成单个架构步骤，可附带修改要求；
+// 依赖该步骤的下游步骤保持不变，默认标记为需要更新
+func (s *ProjectService) RegenerateArchitectureStep(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.RegenerateArchitectureStepRequest) (*model.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
-		logger.Error("生成核心种子失败", zap.Error(err))
 		return nil, err
 	}
-	project.CoreSeed = coreSeed
-	promptParams.CoreSeed = coreSeed
-	logger.Info("核心种子生成成功", zap.String("core_seed", coreSeed))
 
-	// 步骤2: 生成角色动力学
-	logger.Info("步骤2: 生成角色动力学")
-	characterDynamics, err := s.generateArchitectureStep(ctx, modelConfig, "character_dynamics", promptParams, "")
-	if err != nil {
-		logger.Error("生成角色动力学失败", zap.Error(err))
+	if err := checkArchitectureStepRegenerate(project, req); err != nil {
 		return nil, err
 	}
-	project.CharacterDynamics = characterDynamics
-	promptParams.CharacterDynamics = characterDynamics
-	logger.Info("角色动力学生成成功")
 
-	// 步骤3: 生成世界观
-	logger.Info("步骤3: 生成世界观")
-	worldBuilding, err := s.generateArchitectureStep(ctx, modelConfig, "world_building", promptParams, "")
-	if err != nil {
-		logger.Error("生成世界观失败", zap.Error(err))
-		return nil, err
+	var genres []string
+	if project.Genre != "" {
+		if err := json.Unmarshal([]byte(project.Genre), &genres); err != nil {
+			logger.Error("解析 Genre 失败", zap.Error(err))
+			return nil, err
+		}
 	}
-	project.WorldBuilding = worldBuilding
-	promptParams.WorldBuilding = worldBuilding
-	logger.Info("世界观生成成功")
 
-	// 步骤4: 生成情节架构
-	logger.Info("步骤4: 生成情节架构")
-	plotArchitecture, err := s.generateArchitectureStep(ctx, modelConfig, "plot_architecture", promptParams, "")
+	label := architectureStepLabels[req.Step]
+	logger.Info("重新生成架构步骤",
+		zap.String("project_id", projectID),
+		zap.String("step", req.Step),
+		zap.Bool("has_instruction", req.Instruction != ""),
+	)
+	reportProgress(ctx, "重新生成"+label, 1, 1)
+
+	ctx = llm.TrackSynthetic(ctx)
+	prompt := BuildArchitectureRevisionPrompt(
+		GetArchitecturePrompt(req.Step, architectureParams(project, genres)),
+		project.ArchitectureStep(req.Step),
+		req.Instruction,
+	)
+	content, err := s.callLLM(ctx, deviceID, projectID, PurposeArchitecture, prompt)
 	if err != nil {
-		logger.Error("生成情节架构失败", zap.Error(err))
+		logger.Error("重新生成"+label+"失败", zap.Error(err))
 		return nil, err
 	}
-	project.PlotArchitecture = plotArchitecture
-	logger.Info("情节架构生成成功")
 
-	// 步骤5: 生成角色状态
-	logger.Info("步骤5: 生成角色状态")
-	characterState, err := s.generateArchitectureStep(ctx, modelConfig, "character_state", promptParams, "")
-	if err != nil {
-		logger.Error("生成角色状态失败", zap.Error(err))
+	var stale []string
+	if req.Downstream != "keep" {
+		for _, step := range architectureDownstream(req.Step) {
+			if project.ArchitectureStep(step) != "" {
+				stale = append(stale, step)
+			}
+		}
+	}
+	if err := s.saveArchitectureStep(ctx, project, req.Step, content, stale); err != nil {
+		logger.Error("保存架构数据失败", zap.String("step", req.Step), zap.Error(err))
 		return nil, err
 	}
-	project.CharacterState = characterState
-	logger.Info("角色状态生成成功")
 
-	// 标记架构已生成
-	project.ArchitectureGenerated = true
+	logger.Info(label+"重新生成完成", zap.Strings("stale", project.GetArchitectureStale()))
+	return project, nil
+}
+
+// saveArchitectureStep 保存单个步骤：该步骤不再需要更新，markStale 中的步骤标记为需要更新；
+// 全部步骤都有内容时标记架构已生成
+func (s *ProjectService) saveArchitectureStep(ctx context.Context, project *model.Project, step, content string, markStale []string) error {
+	if step == model.ArchitectureStepCharacterState {
+		if err := s.resetCharacterStates(ctx, project, content); err != nil {
+			return err
+		}
+	}
+	project.SetArchitectureStep(step, content)
+
+	stale := make([]string, 0, len(markStale)+1)
+	for _, st := range project.GetArchitectureStale() {
+		if st != step {
+			stale = append(stale, st)
+		}
+	}
+	project.SetArchitectureStale(append(stale, markStale...))
 
+	project.ArchitectureGenerated = project.ArchitectureComplete()
+	project.ArchitectureSynthetic = project.ArchitectureSynthetic || llm.IsSynthetic(ctx)
 	// 手动更新 UpdatedAt 以确保前端能检测到变化
 	project.UpdatedAt = time.Now()
 
-	// 保存更新
-	if err := s.projectRepo.Update(ctx, project); err != nil {
-		logger.Error("保存架构数据失败", zap.Error(err))
-		return nil, err
-	}
+	return s.projectRepo.UpdateArchitecture(ctx, project.ID.String(), map[string]interface{}{
+		step:                     content,
+		"architecture_stale":     project.ArchitectureStale,
+		"architecture_generated": project.ArchitectureGenerated,
+		"architecture_synthetic": project.ArchitectureSynthetic,
+		"updated_at":             project.UpdatedAt,
+	})
+}
 
-	logger.Info("小说架构生成完成",
+// resetCharacterStates 角色状态重新生成后，以新内容作为第 0 章的版本，
+// 删除由旧内容推演出的各章版本；之后的章节重新定稿时从新的角色状态开始更新
+func (s *ProjectService) resetCharacterStates(ctx context.Context, project *model.Project, content string) error {
+	projectID := project.ID.String()
+	latest, err := s.characterStateRepo.LatestNumber(ctx, projectID)
+	if err != nil || latest < 0 {
+		return err
+	}
+	if err := s.characterStateRepo.Save(ctx, &model.CharacterStateVersion{
+		ProjectID:     project.ID,
+		ChapterNumber: 0,
+		Content:       content,
+		Synthetic:     llm.IsSynthetic(ctx),
+	}); err != nil {
+		return err
+	}
+	if err := s.characterStateRepo.DeleteAfter(ctx, projectID, 0); err != nil {
+		return err
+	}
+	logger.Info("角色状态已重新生成，删除各章的角色状态版本",
 		zap.String("project_id", projectID),
+		zap.Int("latest", latest),
 	)
+	return nil
+}
 
-	return project, nil
+// architectureParams 按项目当前的架构内容构建提示词参数
+func architectureParams(project *model.Project, genres []string) ArchitecturePromptParams {
+	return ArchitecturePromptParams{
+		Topic:             project.Topic,
+		Genre:             genres,
+		ChapterCount:      project.ChapterCount,
+		WordsPerChapter:   project.WordsPerChapter,
+		UserGuidance:      project.UserGuidance,
+		CoreSeed:          project.CoreSeed,
+		CharacterDynamics: project.CharacterDynamics,
+		WorldBuilding:     project.WorldBuilding,
+	}
 }
 
-// generateArchitectureStep 执行单个架构生成步骤
-func (s *ProjectService) generateArchitectureStep(ctx context.Context, modelConfig *model.ModelConfig, step string, params ArchitecturePromptParams, systemPrompt string) (string, error) {
-	// 构建用户提示词
-	userPrompt := GetArchitecturePrompt(step, params)
+// architectureStepLabels 架构步骤名称
+var architectureStepLabels = map[string]string{
+	model.ArchitectureStepCoreSeed:          "核心种子",
+	model.ArchitectureStepCharacterDynamics: "角色动力学",
+	model.ArchitectureStepWorldBuilding:     "世界观",
+	model.ArchitectureStepPlotArchitecture:  "情节架构",
+	model.ArchitectureStepCharacterState:    "角色状态",
+}
 
-	// 构建消息
-	messages := []llm.ChatMessage{}
-	if systemPrompt != "" {
-		messages = append(messages, llm.ChatMessage{Role: "system", Content: systemPrompt})
-	}
-	messages = append(messages, llm.ChatMessage{Role: "user", Content: userPrompt})
+// architectureStepDeps 各步骤提示词引用的上游步骤
+var architectureStepDeps = map[string][]string{
+	model.ArchitectureStepCharacterDynamics: {model.ArchitectureStepCoreSeed},
+	model.ArchitectureStepWorldBuilding:     {model.ArchitectureStepCoreSeed},
+	model.ArchitectureStepPlotArchitecture:  {model.ArchitectureStepCoreSeed, model.ArchitectureStepCharacterDynamics, model.ArchitectureStepWorldBuilding},
+	model.ArchitectureStepCharacterState:    {model.ArchitectureStepCharacterDynamics},
+}
 
-	// 调用 LLM
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
+// architectureDownstream 直接或间接依赖 step 的步骤（按执行顺序）
+func architectureDownstream(step string) []string {
+	affected := map[string]bool{step: true}
+	var downstream []string
+	for _, st := range model.ArchitectureSteps {
+		for _, dep := range architectureStepDeps[st] {
+			if affected[dep] {
+				affected[st] = true
+				downstream = append(downstream, st)
+				break
+			}
+		}
 	}
+	return downstream
+}
 
-	options := llm.ChatOptions{
-		Temperature: 0.7,
-		MaxTokens:   4000,
-		APIKey:      modelConfig.APIKey,
+// checkArchitectureGenerate 检查能否生成架构
+func checkArchitectureGenerate(project *model.Project, overwrite bool) error {
+	if project.ArchitectureGenerated && !overwrite {
+		return errors.New("架构已生成，如需重新生成请设置 overwrite=true")
 	}
+	return nil
+}
 
-	// 如果有自定义 BaseURL，需要创建临时适配器
-	var result string
-	var err error
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		result, err = adapter.ChatCompletion(ctx, messages, options)
-	} else {
-		result, err = s.llmManager.ChatCompletion(ctx, provider, messages, options)
+// checkArchitectureStepRegenerate 检查能否重新生成单个步骤：步骤有效且上游步骤均已生成
+func checkArchitectureStepRegenerate(project *model.Project, req *dto.RegenerateArchitectureStepRequest) error {
+	if _, ok := architectureStepLabels[req.Step]; !ok {
+		return fmt.Errorf("未知的架构步骤 %q，可选值：%s", req.Step, strings.Join(model.ArchitectureSteps, ", "))
 	}
-
-	if err != nil {
-		return "", err
+	if req.Downstream != "" && req.Downstream != "keep" && req.Downstream != "stale" {
+		return fmt.Errorf("downstream 可选值：keep, stale")
 	}
-
-	return result, nil
+	for _, dep := range architectureStepDeps[req.Step] {
+		if project.ArchitectureStep(dep) == "" {
+			return fmt.Errorf("请先生成%s", architectureStepLabels[dep])
+		}
+	}
+	return nil
 }
 
-// generateMockArchitecture 生成模拟架构数据（用于开发测试）
-func (s *ProjectService) generateMockArchitecture(ctx context.Context, project *model.Project, genres []string) (*model.Project, error) {
-	genreStr := "通用"
-	if len(genres) > 0 {
-		genreStr = genres[0]
-	}
-
-	// 根据不同类型生成不同的模拟内容
-	project.CoreSeed = fmt.Sprintf("当程序员李明在代码中发现一个神秘的AI程序，获得了超能力，必须阻止隐藏在暗处的黑暗组织，否则整个世界将被数字化控制。")
-
-	project.CharacterDynamics = fmt.Sprintf(`【%s】核心角色设计：
-
-**李明（主角）**
-- 特征：28岁男性，程序员，戴着黑框眼镜，身材瘦弱
-- 核心驱动力三角：
-  * 表面追求：升职加薪，买学区房
-  * 深层渴望：获得认可，证明自己的价值
-  * 灵魂需求：寻找生命的意义和责任
-- 角色弧线：普通程序员 → 发现超能力 → 被迫卷入斗争 → 接受使命 → 成为守护者
-
-**苏小雨（女主角）**
-- 特征：26岁女性，神秘组织的前成员，短发干练，眼神锐利
-- 核心驱动力三角：
-  * 表面追求：逃离组织控制
-  * 深层渴望：信任与被信任
-  * 灵魂需求：救赎过去的错误
-- 角色弧线：组织杀手 → 遇见主角 → 内心转变 → 选择正义 → 与主角并肩作战
-
-**黑暗组织首领（反派）**
-- 特征：50岁男性，科技巨头CEO，外表儒雅但心机深沉
-- 核心驱动力三角：
-  * 表面追求：掌控全球科技
-  * 深层渴望：永生与不朽
-  * 灵魂需求：超越人类的极限
-`, genreStr)
-
-	project.WorldBuilding = fmt.Sprintf(`【%s】世界观设定：
-
-**物理维度**
-- 空间结构：近未来的都市设定，充满高科技感与传统城市的对比
-- 时间背景：2035年，AI技术已经渗透到生活的方方面面
-- 规则体系：超能力来源于一种神秘的量子算法，可以通过代码操控现实
-
-**社会维度**
-- 社会结构：科技巨头掌控社会资源，普通人成为数字时代的"无产阶级"
-- 文化氛围：虚拟与现实界限模糊，人们更愿意生活在虚拟世界中
-- 生活方式：高度依赖AI助手，人际关系逐渐数字化
-
-**情感维度**
-- 核心意象：代码、数据流、虚拟与现实的重叠
-- 环境氛围：赛博朋克式的压抑与希望并存
-- 情感体验：科技冷漠中的温情，数字时代的真实情感
-`, genreStr)
-
-	project.PlotArchitecture = fmt.Sprintf(`【%s】三幕式情节架构：
-
-**第一幕（开端）**
-1. 日常状态：李明在公司加班，处理bug，生活平淡无奇（3处场景铺垫）
-2. 引出故事：
-   - 主线：发现AI程序中的异常代码
-   - 感情线：与苏小雨初次相遇
-   - 副线：公司的秘密项目露出端倪
-3. 契机事件：李明意外激活AI程序，获得超能力
-4. 初步反应：震惊、恐惧，试图隐藏这个秘密
-
-**第二幕（发展）**
-1. 剧情深入：
-   - 黑暗组织察觉到李明的存在
-   - 苏小雨接近李明，身份成谜
-   - 李明逐渐掌握超能力
-2. 挑战与成长：
-   - 李明被组织追杀
-   - 第一次使用超能力战斗
-   - 与苏小雨建立信任
-3. 情感升温：两人在逃亡中产生感情
-4. 重要转折：苏小雨揭露身份，李明陷入信任危机
-
-**第三幕（高潮与结局）**
-1. 核心冲突爆发：黑暗组织启动"数字永生"计划
-2. 角色抉择：李明选择牺牲自己的超能力拯救世界
-3. 结局收尾：组织被瓦解，李明回归普通生活，但内心已成长
-`, genreStr)
-
-	project.CharacterState = fmt.Sprintf(`【角色状态文档】
-
-**李明**
-├── 物品:
-│  ├── 黑色笔记本：记录着神秘的AI代码
-│  └── 破旧眼镜：实际上是一副AR眼镜，可以显示数据流
-├── 能力
-│  ├── 技能1：代码操控现实：可以通过编写代码改变物理世界
-│  └── 技能2：数据感知：能够看到数字世界的流动
-├── 状态
-│  ├── 身体状态：普通程序员体格，经常加班熬夜
-│  └── 心理状态：从平凡到震撼，正在慢慢接受新的身份
-├── 主要角色间关系网
-│  ├── 苏小雨：从怀疑到信任，再到相互依靠
-│  └── 黑暗组织：被追杀的对象，也是最终要对抗的敌人
-├── 触发或加深的事件
-│  ├── 发现AI程序：改变命运的关键时刻
-│  └── 苏小雨揭露身份：信任的考验，感情的转折
-`)
-
-	// 标记架构已生成
-	project.ArchitectureGenerated = true
+// checkBlueprintGenerate 检查能否生成大纲
+func checkBlueprintGenerate(project *model.Project, overwrite bool) error {
+	if !project.ArchitectureGenerated {
+		return errors.New("请先生成小说架构")
+	}
+	if project.BlueprintGenerated && !overwrite {
+		return errors.New("大纲已生成，如需重新生成请设置 overwrite=true")
+	}
+	return nil
+}
 
-	// 手动更新 UpdatedAt 以确保前端能检测到变化
-	project.UpdatedAt = time.Now()
+// generateArchitectureStep 执行单个架构生成步骤
+func (s *ProjectService) generateArchitectureStep(ctx context.Context, deviceID uuid.UUID, projectID, step string, params ArchitecturePromptParams, systemPrompt string) (string, error) {
+	// 构建用户提示词
+	userPrompt := GetArchitecturePrompt(step, params)
 
-	// 保存更新
-	if err := s.projectRepo.Update(ctx, project); err != nil {
-		logger.Error("保存模拟架构数据失败", zap.Error(err))
-		return nil, err
+	// 构建消息
+	messages := []llm.ChatMessage{}
+	if systemPrompt != "" {
+		messages = append(messages, llm.ChatMessage{Role: "system", Content: systemPrompt})
 	}
+	messages = append(messages, llm.ChatMessage{Role: "user", Content: userPrompt})
 
-	logger.Info("模拟架构生成完成",
-		zap.String("project_id", project.ID.String()),
-	)
-
-	return project, nil
+	return s.invoker.Chat(ctx, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   PurposeArchitecture,
+		Messages:  messages,
+	})
 }
 
-// GenerateBlueprint 生成章节大纲
-func (s *ProjectService) GenerateBlueprint(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateBlueprintRequest) (*model.Project, error) {
+// GenerateBlueprint 生成章节大纲，保存后解析为各章节的大纲信息
+func (s *ProjectService) GenerateBlueprint(ctx context.Context, deviceID uuid.UUID, projectID string, req *dto.GenerateBlueprintRequest) (*model.Project, *dto.BlueprintSyncResponse, error) {
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
-		return nil, err
-	}
-
-	// 检查架构是否已生成
-	if !project.ArchitectureGenerated {
-		return nil, errors.New("请先生成小说架构")
+		return nil, nil, err
 	}
 
-	// 检查是否已经生成过
-	if project.BlueprintGenerated && !req.Overwrite {
-		return nil, errors.New("大纲已生成，如需重新生成请设置 overwrite=true")
+	if err := checkBlueprintGenerate(project, req.Overwrite); err != nil {
+		return nil, nil, err
 	}
 
 	logger.Info("开始生成章节大纲",
 		zap.String("project_id", projectID),
 	)
 
-	// 获取用于大纲生成的模型配置（尝试 blueprint 或 architecture）
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), "blueprint")
-	if err != nil {
-		modelConfig, err = s.modelRepo.GetByPurpose(ctx, deviceID.String(), "architecture")
-		if err != nil {
-			logger.Error("获取大纲生成模型配置失败", zap.Error(err))
-			logger.Info("使用模拟模式生成章节大纲")
-			return s.generateMockBlueprint(ctx, project)
-		}
-	}
+	ctx = llm.TrackSynthetic(ctx)
 
 	params := BlueprintPromptParams{
 		UserGuidance:      project.UserGuidance,
@@ -516,11 +547,12 @@ func (s *ProjectService) GenerateBlueprint(ctx context.Context, deviceID uuid.UU
 	var fullBlueprint string
 
 	if project.ChapterCount <= chunkSize {
+		reportProgress(ctx, "生成章节大纲", 1, 1)
 		prompt := BuildBlueprintPrompt(params)
-		result, err := s.callLLM(ctx, modelConfig, prompt)
+		result, err := s.callLLM(ctx, deviceID, projectID, PurposeBlueprint, prompt)
 		if err != nil {
-			logger.Error("LLM 大纲生成失败，回退到模拟模式", zap.Error(err))
-			return s.generateMockBlueprint(ctx, project)
+			logger.Error("LLM 大纲生成失败", zap.Error(err))
+			return nil, nil, err
 		}
 		fullBlueprint = result
 	} else {
@@ -541,14 +573,15 @@ func (s *ProjectService) GenerateBlueprint(ctx context.Context, deviceID uuid.UU
 				zap.Int("end", end),
 			)
 
+			reportProgress(ctx, fmt.Sprintf("生成第 %d-%d 章大纲", start, end), chunk+1, totalChunks)
 			prompt := BuildChunkedBlueprintPrompt(params, start, end, strings.Join(parts, "\n\n"))
-			result, err := s.callLLM(ctx, modelConfig, prompt)
+			result, err := s.callLLM(ctx, deviceID, projectID, PurposeBlueprint, prompt)
 			if err != nil {
-				logger.Error("分块大纲生成失败，回退到模拟模式",
+				logger.Error("分块大纲生成失败",
 					zap.Int("chunk", chunk+1),
 					zap.Error(err),
 				)
-				return s.generateMockBlueprint(ctx, project)
+				return nil, nil, err
 			}
 			parts = append(parts, strings.TrimSpace(result))
 		}
@@ -557,71 +590,140 @@ func (s *ProjectService) GenerateBlueprint(ctx context.Context, deviceID uuid.UU
 
 	project.ChapterBlueprint = fullBlueprint
 	project.BlueprintGenerated = true
+	project.BlueprintSynthetic = llm.IsSynthetic(ctx)
 	project.UpdatedAt = time.Now()
 
 	if err := s.projectRepo.Update(ctx, project); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	logger.Info("章节大纲生成完成",
 		zap.String("project_id", project.ID.String()),
 		zap.Int("chapter_count", project.ChapterCount),
 	)
-	return project, nil
-}
 
-// callLLM 调用大模型
-func (s *ProjectService) callLLM(ctx context.Context, modelConfig *model.ModelConfig, prompt string) (string, error) {
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: prompt},
-	}
-	options := llm.ChatOptions{
-		Temperature: 0.7,
-		MaxTokens:   4096,
-		APIKey:      modelConfig.APIKey,
+	sync, err := s.syncBlueprintChapters(ctx, project)
+	if err != nil {
+		logger.Error("解析章节大纲失败", zap.Error(err))
+		return nil, nil, fmt.Errorf("大纲已保存，但解析章节失败（可稍后重新解析）: %w", err)
 	}
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		return adapter.ChatCompletion(ctx, messages, options)
+	return project, sync, nil
+}
+
+// ErrBlueprintNotGenerated 项目还没有章节大纲
+var ErrBlueprintNotGenerated = errors.New("请先生成章节大纲")
+
+// SyncBlueprintChapters 将项目的章节大纲解析为各章节的大纲信息，
+// 不存在的章节会被创建；重复执行结果相同，不影响章节正文
+func (s *ProjectService) SyncBlueprintChapters(ctx context.Context, projectID string) (*dto.BlueprintSyncResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
 	}
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
+	if strings.TrimSpace(project.ChapterBlueprint) == "" {
+		return nil, ErrBlueprintNotGenerated
 	}
-	return s.llmManager.ChatCompletion(ctx, provider, messages, options)
+	return s.syncBlueprintChapters(ctx, project)
 }
 
-// generateMockBlueprint 生成模拟章节大纲（用于开发测试）
-func (s *ProjectService) generateMockBlueprint(ctx context.Context, project *model.Project) (*model.Project, error) {
-	// 准备参数
-	params := BlueprintPromptParams{
-		UserGuidance:      project.UserGuidance,
-		CoreSeed:          project.CoreSeed,
-		CharacterDynamics: project.CharacterDynamics,
-		WorldBuilding:     project.WorldBuilding,
-		PlotArchitecture:  project.PlotArchitecture,
-		ChapterCount:      project.ChapterCount,
+func (s *ProjectService) syncBlueprintChapters(ctx context.Context, project *model.Project) (*dto.BlueprintSyncResponse, error) {
+	reportProgress(ctx, "解析章节大纲", 1, 1)
+
+	parsed, issues := ParseBlueprint(project.ChapterBlueprint, project.ChapterCount)
+
+	existing, err := s.chapterRepo.ListByProject(ctx, project.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	byNumber := make(map[int]*model.Chapter, len(existing))
+	for _, ch := range existing {
+		byNumber[ch.ChapterNumber] = ch
 	}
 
-	// 生成模拟大纲
-	project.ChapterBlueprint = GenerateMockBlueprint(params)
-	project.BlueprintGenerated = true
+	resp := &dto.BlueprintSyncResponse{
+		Parsed:   len(parsed),
+		Unparsed: make([]dto.BlueprintSyncIssue, 0, len(issues)),
+	}
+	for _, bp := range parsed {
+		chapter, ok := byNumber[bp.Number]
+		if !ok {
+			chapter = &model.Chapter{
+				ProjectID:     project.ID,
+				ChapterNumber: bp.Number,
+				Status:        "not_started",
+			}
+		}
+		if !applyBlueprintChapter(chapter, bp) && ok {
+			resp.Unchanged++
+			continue
+		}
 
-	// 手动更新 UpdatedAt 以确保前端能检测到变化
-	project.UpdatedAt = time.Now()
+		if !ok {
+			if err := s.chapterRepo.Create(ctx, chapter); err != nil {
+				return nil, err
+			}
+			resp.Created++
+			continue
+		}
+		if err := s.chapterRepo.Update(ctx, chapter); err != nil {
+			return nil, err
+		}
+		resp.Updated++
+	}
 
-	// 保存更新
-	if err := s.projectRepo.Update(ctx, project); err != nil {
-		logger.Error("保存模拟大纲数据失败", zap.Error(err))
-		return nil, err
+	for _, issue := range issues {
+		resp.Unparsed = append(resp.Unparsed, dto.BlueprintSyncIssue{
+			ChapterNumber: issue.ChapterNumber,
+			Line:          issue.Line,
+			Text:          truncate(issue.Text, 100),
+			Reason:        issue.Reason,
+		})
 	}
 
-	logger.Info("模拟章节大纲生成完成",
+	logger.Info("章节大纲解析完成",
 		zap.String("project_id", project.ID.String()),
-		zap.Int("chapter_count", project.ChapterCount),
+		zap.Int("parsed", resp.Parsed),
+		zap.Int("created", resp.Created),
+		zap.Int("updated", resp.Updated),
+		zap.Int("unparsed", len(resp.Unparsed)),
 	)
+	return resp, nil
+}
 
-	return project, nil
+// applyBlueprintChapter 写入解析出的标题与大纲信息（按数据库列长度截断），返回是否有变化
+func applyBlueprintChapter(chapter *model.Chapter, bp BlueprintChapter) bool {
+	changed := false
+	set := func(field *string, value string, maxLen int) {
+		if runes := []rune(value); maxLen > 0 && len(runes) > maxLen {
+			value = string(runes[:maxLen])
+		}
+		if *field != value {
+			*field = value
+			changed = true
+		}
+	}
+
+	// 大纲中没有标题时保留原标题
+	if bp.Title != "" {
+		set(&chapter.Title, bp.Title, 200)
+	}
+	set(&chapter.BlueprintPosition, bp.Position, 100)
+	set(&chapter.BlueprintPurpose, bp.Purpose, 100)
+	set(&chapter.BlueprintSuspense, bp.Suspense, 100)
+	set(&chapter.BlueprintForeshadowing, bp.Foreshadowing, 0)
+	set(&chapter.BlueprintTwistLevel, bp.TwistLevel, 20)
+	set(&chapter.BlueprintSummary, bp.Summary, 0)
+	return changed
+}
+
+// callLLM 调用大模型
+func (s *ProjectService) callLLM(ctx context.Context, deviceID uuid.UUID, projectID string, purpose Purpose, prompt string) (string, error) {
+	return s.invoker.Chat(ctx, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   purpose,
+		Messages:  userMessages(prompt),
+	})
 }
 
 // ExportProject 导出项目
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/model"
+	"x-novel/internal/repository"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRetrievalChunkSize 未配置时每个片段的目标字数
+	defaultRetrievalChunkSize = 500
+	// defaultRetrievalTopK 未配置时每次检索返回的片段数
+	defaultRetrievalTopK = 6
+	// defaultRetrievalMaxChars 未配置时写入提示词的检索内容最大字数
+	defaultRetrievalMaxChars = 3000
+	// defaultRetrievalDimensions 未配置时的向量维度
+	defaultRetrievalDimensions = 1536
+	// retrievalMaxCharacters 按出场角色追加检索的最多角色数
+	retrievalMaxCharacters = 3
+)
+
+// ErrRetrievalDisabled 未启用前文检索
+var ErrRetrievalDisabled = errors.New("未启用前文检索")
+
+// RetrievalOptions 前文检索配置
+type RetrievalOptions struct {
+	ChunkSize  int // 每个片段的目标字数
+	TopK       int // 每次检索返回的片段数
+	MaxChars   int // 写入提示词的检索内容最大字数
+	Dimensions int // 向量维度，请求嵌入模型按该维度输出，维度不一致的向量不写入索引
+}
+
+// Retriever 前文检索：定稿章节切分为片段建立向量索引，生成新章节时按大纲与出场角色检索相关片段
+type Retriever struct {
+	store       repository.VectorStore
+	storeName   string
+	invoker     *ModelInvoker
+	chapterRepo *repository.ChapterRepository
+	opts        RetrievalOptions
+}
+
+// NewRetriever 创建前文检索，storeName 仅用于展示索引类型
+func NewRetriever(store repository.VectorStore, storeName string, invoker *ModelInvoker, chapterRepo *repository.ChapterRepository, opts RetrievalOptions) *Retriever {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultRetrievalChunkSize
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = defaultRetrievalTopK
+	}
+	if opts.MaxChars <= 0 {
+		opts.MaxChars = defaultRetrievalMaxChars
+	}
+	if opts.Dimensions <= 0 {
+		opts.Dimensions = defaultRetrievalDimensions
+	}
+	return &Retriever{
+		store:       store,
+		storeName:   storeName,
+		invoker:     invoker,
+		chapterRepo: chapterRepo,
+		opts:        opts,
+	}
+}
+
+// IndexChapter 切分章节正文并重建该章的索引，返回片段数
+func (r *Retriever) IndexChapter(ctx context.Context, deviceID uuid.UUID, chapter *model.Chapter) (int, error) {
+	projectID := chapter.ProjectID.String()
+	texts := chunkChapter(chapter.Content, r.opts.ChunkSize)
+	if len(texts) == 0 {
+		return 0, r.store.ReplaceChapter(ctx, projectID, chapter.ChapterNumber, nil)
+	}
+
+	result, err := r.embed(ctx, deviceID, projectID, texts)
+	if err != nil {
+		return 0, err
+	}
+	chunks := make([]repository.VectorChunk, 0, len(texts))
+	for i, text := range texts {
+		chunks = append(chunks, repository.VectorChunk{
+			ChapterNumber: chapter.ChapterNumber,
+			ChunkIndex:    i,
+			Content:       text,
+			Model:         result.Model,
+			Embedding:     result.Vectors[i],
+		})
+	}
+	if err := r.store.ReplaceChapter(ctx, projectID, chapter.ChapterNumber, chunks); err != nil {
+		return 0, err
+	}
+
+	logger.Info("章节已建立检索索引",
+		zap.String("project_id", projectID),
+		zap.Int("chapter_number", chapter.ChapterNumber),
+		zap.Int("chunks", len(chunks)),
+		zap.String("model", result.Model),
+	)
+	return len(chunks), nil
+}
+
+// Retrieve 检索与本章相关的前文片段，按章节顺序排列并限制总字数。
+// 上一章已通过摘要与结尾原文提供，只在更早的章节中检索
+func (r *Retriever) Retrieve(ctx context.Context, deviceID uuid.UUID, project *model.Project, chapter *model.Chapter) ([]repository.VectorMatch, error) {
+	if chapter.ChapterNumber <= 2 {
+		return nil, nil
+	}
+	queries := retrievalQueries(project, chapter)
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	projectID := project.ID.String()
+	result, err := r.embed(ctx, deviceID, projectID, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多个查询命中同一片段时保留最高分
+	best := make(map[[2]int]repository.VectorMatch)
+	for _, vector := range result.Vectors {
+		matches, err := r.store.Search(ctx, projectID, result.Model, vector, chapter.ChapterNumber-1, r.opts.TopK)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			key := [2]int{m.ChapterNumber, m.ChunkIndex}
+			if prev, ok := best[key]; !ok || m.Score > prev.Score {
+				best[key] = m
+			}
+		}
+	}
+
+	merged := make([]repository.VectorMatch, 0, len(best))
+	for _, m := range best {
+		merged = append(merged, m)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if len(merged) > r.opts.TopK {
+		merged = merged[:r.opts.TopK]
+	}
+
+	// 按相关度依次保留，超出字数上限的片段跳过
+	selected := merged[:0]
+	total := 0
+	for _, m := range merged {
+		n := utf8.RuneCountInString(m.Content)
+		if total+n > r.opts.MaxChars {
+			continue
+		}
+		total += n
+		selected = append(selected, m)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].ChapterNumber != selected[j].ChapterNumber {
+			return selected[i].ChapterNumber < selected[j].ChapterNumber
+		}
+		return selected[i].ChunkIndex < selected[j].ChunkIndex
+	})
+	return selected, nil
+}
+
+// embed 按配置的维度计算向量；模型不支持指定维度且默认维度不一致时返回错误
+func (r *Retriever) embed(ctx context.Context, deviceID uuid.UUID, projectID string, texts []string) (*EmbedResult, error) {
+	result, err := r.invoker.Embed(ctx, deviceID, projectID, texts, r.opts.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range result.Vectors {
+		if len(v) != r.opts.Dimensions {
+			return nil, fmt.Errorf("嵌入模型 %s 返回 %d 维向量，与 rag.dimensions（%d）不一致", result.Model, len(v), r.opts.Dimensions)
+		}
+	}
+	return result, nil
+}
+
+// Status 索引状态：已索引的章节与尚未索引的已完成章节
+func (r *Retriever) Status(ctx context.Context, projectID string) (*dto.RetrievalStatusResponse, error) {
+	indexed, err := r.store.IndexedChapters(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := r.chapterRepo.ListCompleted(ctx, projectID, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	has := make(map[int]bool, len(indexed))
+	for _, n := range indexed {
+		has[n] = true
+	}
+	resp := &dto.RetrievalStatusResponse{
+		Enabled:         true,
+		Store:           r.storeName,
+		IndexedChapters: indexed,
+		MissingChapters: []int{},
+	}
+	for _, ch := range completed {
+		if ch.Content != "" && !has[ch.ChapterNumber] {
+			resp.MissingChapters = append(resp.MissingChapters, ch.ChapterNumber)
+		}
+	}
+	return resp, nil
+}
+
+// Reindex 重建项目全部已完成章节的索引，单章失败时继续处理其余章节
+func (r *Retriever) Reindex(ctx context.Context, deviceID uuid.UUID, projectID string) (*dto.RetrievalReindexResponse, error) {
+	completed, err := r.chapterRepo.ListCompleted(ctx, projectID, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.RetrievalReindexResponse{Failed: []int{}}
+	for _, ch := range completed {
+		if ch.Content == "" {
+			continue
+		}
+		n, err := r.IndexChapter(ctx, deviceID, ch)
+		if errors.Is(err, ErrEmbeddingNotConfigured) || ctx.Err() != nil {
+			return nil, err
+		}
+		if err != nil {
+			logger.Warn("重建章节索引失败",
+				zap.String("project_id", projectID),
+				zap.Int("chapter_number", ch.ChapterNumber),
+				zap.Error(err),
+			)
+			resp.Failed = append(resp.Failed, ch.ChapterNumber)
+			continue
+		}
+		resp.Chapters++
+		resp.Chunks += n
+	}
+	return resp, nil
+}
+
+// DeleteChapter 删除某一章的索引
+func (r *Retriever) DeleteChapter(ctx context.Context, projectID string, chapterNumber int) error {
+	return r.store.ReplaceChapter(ctx, projectID, chapterNumber, nil)
+}
+
+// DeleteProject 删除项目的全部索引
+func (r *Retriever) DeleteProject(ctx context.Context, projectID string) error {
+	return r.store.DeleteProject(ctx, projectID)
+}
+
+// retrievalQueries 检索查询：本章标题与大纲（含伏笔），以及大纲中提到的图谱角色
+func retrievalQueries(project *model.Project, chapter *model.Chapter) []string {
+	outline := strings.TrimSpace(strings.Join([]string{
+		chapter.Title,
+		chapter.BlueprintPurpose,
+		chapter.BlueprintSummary,
+	}, "\n"))
+	if outline == "" {
+		return nil
+	}
+
+	queries := []string{outline}
+	if foreshadowing := strings.TrimSpace(chapter.BlueprintForeshadowing); foreshadowing != "" {
+		queries = append(queries, foreshadowing)
+	}
+	mentioned := outline + "\n" + chapter.BlueprintForeshadowing
+	for _, name := range mentionedCharacters(project, mentioned) {
+		queries = append(queries, name+"："+chapter.BlueprintSummary)
+	}
+	return queries
+}
+
+// mentionedCharacters 图谱中在 text 里出现的角色名，最多 retrievalMaxCharacters 个
+func mentionedCharacters(project *model.Project, text string) []string {
+	if project.GraphData == "" {
+		return nil
+	}
+	var graph GraphData
+	if err := json.Unmarshal([]byte(project.GraphData), &graph); err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, node := range graph.Nodes {
+		name := strings.TrimSpace(node.Name)
+		if name == "" || !strings.Contains(text, name) {
+			continue
+		}
+		names = append(names, name)
+		if len(names) == retrievalMaxCharacters {
+			break
+		}
+	}
+	return names
+}
+
+// chunkChapter 按段落把正文切分为约 size 字的片段，超长段落按字数硬切
+func chunkChapter(content string, size int) []string {
+	var chunks []string
+	var current []string
+	currentLen := 0
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, "\n"))
+			current, currentLen = nil, 0
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		runes := []rune(line)
+		for len(runes) > size {
+			flush()
+			chunks = append(chunks, string(runes[:size]))
+			runes = runes[size:]
+		}
+		if currentLen > 0 && currentLen+len(runes) > size {
+			flush()
+		}
+		current = append(current, string(runes))
+		currentLen += len(runes)
+	}
+	flush()
+	return chunks
+}
+
+// formatRetrievedPassages 将检索到的片段（已按章节顺序排列）写入提示词
+func formatRetrievedPassages(matches []repository.VectorMatch) string {
+	var b strings.Builder
+	for i, m := range matches {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		// 同一章的片段只标注一次章节号
+		if i == 0 || matches[i-1].ChapterNumber != m.ChapterNumber {
+			fmt.Fprintf(&b, "【第 %d 章】\n", m.ChapterNumber)
+		}
+		b.WriteString(m.Content)
+	}
+	return b.String()
+}
+
+// retrievePassages 检索相关前文片段；未启用检索或未绑定嵌入模型时返回空，检索失败时记录提示
+func (s *ChapterService) retrievePassages(ctx context.Context, deviceID uuid.UUID, project *model.Project, chapter *model.Chapter) string {
+	if s.retriever == nil {
+		return ""
+	}
+	matches, err := s.retriever.Retrieve(ctx, deviceID, project, chapter)
+	if errors.Is(err, ErrEmbeddingNotConfigured) {
+		return ""
+	}
+	if err != nil {
+		logger.Warn("检索前文片段失败",
+			zap.String("project_id", project.ID.String()),
+			zap.Int("chapter_number", chapter.ChapterNumber),
+			zap.Error(err),
+		)
+		chapter.GenerationWarnings = append(chapter.GenerationWarnings, "前文检索失败，本章未参考更早章节的相关片段")
+		return ""
+	}
+	return formatRetrievedPassages(matches)
+}
+
+// RetrievalStatus 前文检索的索引状态
+func (s *ChapterService) RetrievalStatus(ctx context.Context, projectID string) (*dto.RetrievalStatusResponse, error) {
+	if s.retriever == nil {
+		return &dto.RetrievalStatusResponse{IndexedChapters: []int{}, MissingChapters: []int{}}, nil
+	}
+	return s.retriever.Status(ctx, projectID)
+}
+
+// ReindexRetrieval 重建项目的前文检索索引
+func (s *ChapterService) ReindexRetrieval(ctx context.Context, deviceID uuid.UUID, projectID string) (*dto.RetrievalReindexResponse, error) {
+	if s.retriever == nil {
+		return nil, ErrRetrievalDisabled
+	}
+	return s.retriever.Reindex(ctx, deviceID, projectID)
+}
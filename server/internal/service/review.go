@@ -30,8 +30,9 @@ type DetectionIssue struct {
 type DetectionResult struct {
 	Issues     []DetectionIssue `json:"issues"`
 	Summary    string           `json:"summary"`
-	TotalCount int              `json:"total_count"`
-	TypeCounts map[string]int   `json:"type_counts"`
+	TotalCount int              `json:"total_count" schema:"-"` // 由服务端统计
+	TypeCounts map[string]int   `json:"type_counts" schema:"-"`
+	Synthetic  bool             `json:"synthetic,omitempty" schema:"-"` // 由模拟提供商生成
 }
 
 // ReviewScore 审阅评分项
@@ -49,6 +50,7 @@ type ReviewResult struct {
 	Issues      []string      `json:"issues"`
 	Suggestions []string      `json:"suggestions"`
 	Summary     string        `json:"summary"`
+	Synthetic   bool          `json:"synthetic,omitempty" schema:"-"` // 由模拟提供商生成
 }
 
 // MarketPrediction 市场预测结果
@@ -62,6 +64,7 @@ type MarketPrediction struct {
 	Risks           []string           `json:"risks"`             // 风险提示
 	Recommendations []string           `json:"recommendations"`   // 改进建议
 	Summary         string             `json:"summary"`           // 总结
+	Synthetic       bool               `json:"synthetic,omitempty" schema:"-"` // 由模拟提供商生成
 }
 
 // MarketTrendItem 市场趋势条目
@@ -91,21 +94,18 @@ type MonetizationAdvice struct {
 type ReviewService struct {
 	projectRepo *repository.ProjectRepository
 	chapterRepo *repository.ChapterRepository
-	modelRepo   *repository.ModelConfigRepository
-	llmManager  *llm.Manager
+	invoker     *ModelInvoker
 }
 
 func NewReviewService(
 	projectRepo *repository.ProjectRepository,
 	chapterRepo *repository.ChapterRepository,
-	modelRepo *repository.ModelConfigRepository,
-	llmManager *llm.Manager,
+	invoker *ModelInvoker,
 ) *ReviewService {
 	return &ReviewService{
 		projectRepo: projectRepo,
 		chapterRepo: chapterRepo,
-		modelRepo:   modelRepo,
-		llmManager:  llmManager,
+		invoker:     invoker,
 	}
 }
 
@@ -116,17 +116,19 @@ func (s *ReviewService) DetectErrors(ctx context.Context, deviceID uuid.UUID, co
 	}
 
 	prompt := getDetectionPrompt(content, types)
-	result, err := s.callLLM(ctx, deviceID, prompt, 0.2, "review")
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, "", prompt, 0.2, "detection_result", &DetectionResult{})
 	if err != nil {
 		logger.Error("错误检测 LLM 调用失败", zap.Error(err))
-		return s.getMockDetection(content), nil
+		return nil, err
 	}
 
 	detection, err := parseDetectionResult(result)
 	if err != nil {
 		logger.Error("解析检测结果失败", zap.Error(err))
-		return s.getMockDetection(content), nil
+		return nil, fmt.Errorf("解析检测结果失败: %w", err)
 	}
+	detection.Synthetic = llm.IsSynthetic(ctx)
 
 	return detection, nil
 }
@@ -148,17 +150,19 @@ func (s *ReviewService) ReviewChapter(ctx context.Context, deviceID uuid.UUID, p
 	}
 
 	prompt := getReviewPrompt(project.Title, chapterNumber, chapter.Title, chapter.Content)
-	result, err := s.callLLM(ctx, deviceID, prompt, 0.3, "review")
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, projectID, prompt, 0.3, "review_result", &ReviewResult{})
 	if err != nil {
 		logger.Error("AI 审阅 LLM 调用失败", zap.Error(err))
-		return s.getMockReview(), nil
+		return nil, err
 	}
 
 	review, err := parseReviewResult(result)
 	if err != nil {
 		logger.Error("解析审阅结果失败", zap.Error(err))
-		return s.getMockReview(), nil
+		return nil, fmt.Errorf("解析审阅结果失败: %w", err)
 	}
+	review.Synthetic = llm.IsSynthetic(ctx)
 
 	return review, nil
 }
@@ -194,16 +198,19 @@ func (s *ReviewService) ReviewProject(ctx context.Context, deviceID uuid.UUID, p
 
 	fullContent := strings.Join(contentParts, "\n\n---\n\n")
 	prompt := getProjectReviewPrompt(project.Title, fullContent)
-	result, err := s.callLLM(ctx, deviceID, prompt, 0.3, "review")
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, projectID, prompt, 0.3, "review_result", &ReviewResult{})
 	if err != nil {
 		logger.Error("项目审阅 LLM 调用失败", zap.Error(err))
-		return s.getMockReview(), nil
+		return nil, err
 	}
 
 	review, err := parseReviewResult(result)
 	if err != nil {
-		return s.getMockReview(), nil
+		logger.Error("解析审阅结果失败", zap.Error(err))
+		return nil, fmt.Errorf("解析审阅结果失败: %w", err)
 	}
+	review.Synthetic = llm.IsSynthetic(ctx)
 
 	return review, nil
 }
@@ -234,49 +241,32 @@ func (s *ReviewService) MarketPredict(ctx context.Context, deviceID uuid.UUID, p
 	}
 
 	prompt := getMarketPredictPrompt(project.Title, project.Genre, project.CoreSeed, project.PlotArchitecture, strings.Join(contentParts, "\n"))
-	result, err := s.callLLM(ctx, deviceID, prompt, 0.4, "review")
+	ctx = llm.TrackSynthetic(ctx)
+	result, err := s.callLLM(ctx, deviceID, projectID, prompt, 0.4, "market_prediction", &MarketPrediction{})
 	if err != nil {
 		logger.Error("市场预测 LLM 调用失败", zap.Error(err))
-		return s.getMockMarketPrediction(), nil
+		return nil, err
 	}
 
 	prediction, err := parseMarketPrediction(result)
 	if err != nil {
 		logger.Error("解析市场预测结果失败", zap.Error(err))
-		return s.getMockMarketPrediction(), nil
+		return nil, fmt.Errorf("解析市场预测结果失败: %w", err)
 	}
+	prediction.Synthetic = llm.IsSynthetic(ctx)
 
 	return prediction, nil
 }
 
-func (s *ReviewService) callLLM(ctx context.Context, deviceID uuid.UUID, prompt string, temperature float32, purpose string) (string, error) {
-	modelConfig, err := s.modelRepo.GetByPurpose(ctx, deviceID.String(), purpose)
-	if err != nil {
-		modelConfig, err = s.modelRepo.GetByPurpose(ctx, deviceID.String(), "general")
-		if err != nil {
-			return "", fmt.Errorf("未配置模型: %w", err)
-		}
-	}
-
-	messages := []llm.ChatMessage{
-		{Role: "user", Content: prompt},
-	}
-	options := llm.ChatOptions{
+// callLLM 以结构化输出模式调用模型，返回通过 target 结构校验的 JSON
+func (s *ReviewService) callLLM(ctx context.Context, deviceID uuid.UUID, projectID, prompt string, temperature float32, schemaName string, target interface{}) (string, error) {
+	return s.invoker.ChatJSON(ctx, &InvokeRequest{
+		DeviceID:    deviceID,
+		ProjectID:   projectID,
+		Purpose:     PurposeReview,
+		Messages:    userMessages(prompt),
 		Temperature: temperature,
-		MaxTokens:   4096,
-		APIKey:      modelConfig.APIKey,
-	}
-
-	if modelConfig.BaseURL != "" {
-		adapter := llm.NewOpenAIAdapter(modelConfig.BaseURL, modelConfig.ModelName)
-		return adapter.ChatCompletion(ctx, messages, options)
-	}
-
-	provider := "openai"
-	if modelConfig.Provider != nil {
-		provider = modelConfig.Provider.Name
-	}
-	return s.llmManager.ChatCompletion(ctx, provider, messages, options)
+	}, schemaName, target)
 }
 
 // ========== 提示词 ==========
@@ -449,7 +439,7 @@ func getMarketPredictPrompt(title, genre, coreSeed, plotArchitecture, chaptersSu
 // ========== 解析 ==========
 
 func parseDetectionResult(raw string) (*DetectionResult, error) {
-	cleaned := cleanJSONStr(raw)
+	cleaned := cleanJSON(raw)
 	var result DetectionResult
 	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
 		return nil, err
@@ -466,7 +456,7 @@ func parseDetectionResult(raw string) (*DetectionResult, error) {
 }
 
 func parseReviewResult(raw string) (*ReviewResult, error) {
-	cleaned := cleanJSONStr(raw)
+	cleaned := cleanJSON(raw)
 	var result ReviewResult
 	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
 		return nil, err
@@ -487,7 +477,7 @@ func parseReviewResult(raw string) (*ReviewResult, error) {
 }
 
 func parseMarketPrediction(raw string) (*MarketPrediction, error) {
-	cleaned := cleanJSONStr(raw)
+	cleaned := cleanJSON(raw)
 	var result MarketPrediction
 	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
 		return nil, err
@@ -506,74 +496,3 @@ func parseMarketPrediction(raw string) (*MarketPrediction, error) {
 	}
 	return &result, nil
 }
-
-func cleanJSONStr(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
-}
-
-// ========== 模拟数据 ==========
-
-func (s *ReviewService) getMockDetection(content string) *DetectionResult {
-	issues := []DetectionIssue{
-		{Type: "grammar", Severity: "warning", Position: "段落开头", Original: "（从原文中提取）", Suggestion: "建议调整语序使表达更流畅", Explanation: "当前句式略显冗长，可以拆分"},
-		{Type: "repetition", Severity: "info", Position: "中间段落", Original: "（从原文中提取）", Suggestion: "考虑使用近义词替换", Explanation: "连续使用了相同的描述词"},
-	}
-	typeCounts := map[string]int{"grammar": 1, "repetition": 1}
-	return &DetectionResult{
-		Issues:     issues,
-		Summary:    "文本整体质量良好，有少量语法和重复问题可以优化（模拟数据）",
-		TotalCount: len(issues),
-		TypeCounts: typeCounts,
-	}
-}
-
-func (s *ReviewService) getMockMarketPrediction() *MarketPrediction {
-	return &MarketPrediction{
-		MarketScore:    72,
-		TargetAudience: "18-35岁男性读者，偏好都市异能、科幻类型",
-		CompetitiveEdge: "将程序员文化与超能力设定结合，贴近年轻读者群体，代入感强",
-		MarketTrends: []MarketTrendItem{
-			{Trend: "都市异能热度回升", Fit: 85, Analysis: "近年都市异能类作品在多平台表现优秀，本作设定契合"},
-			{Trend: "科技与超能力融合", Fit: 78, Analysis: "AI+超能力题材新颖，符合当下科技焦点"},
-			{Trend: "短视频改编潜力", Fit: 65, Analysis: "节奏紧凑，有一定短剧改编潜力"},
-		},
-		ReaderAppeal: []ReaderAppealItem{
-			{Dimension: "情感共鸣", Score: 78, Comment: "程序员主角设定易引发职场读者共鸣"},
-			{Dimension: "代入感", Score: 82, Comment: "现代都市背景提供良好代入体验"},
-			{Dimension: "爽点密度", Score: 70, Comment: "超能力获得节奏尚可，建议增加反转"},
-			{Dimension: "悬念设置", Score: 75, Comment: "黑暗组织线索铺设合理"},
-			{Dimension: "社交传播性", Score: 68, Comment: "需要增加更多社交媒体友好的金句和名场面"},
-		},
-		Monetization: MonetizationAdvice{
-			Platforms:    []string{"番茄小说", "起点中文网", "七猫"},
-			PricingModel: "免费+广告 或 VIP 章节付费",
-			IPPotential:  65,
-			Suggestion:   "建议先在番茄小说免费连载积累读者，后续考虑付费转化（模拟数据）",
-		},
-		Risks:           []string{"都市异能类竞品较多，需要强化差异化", "程序员题材可能限制受众范围", "前期节奏需要更紧凑"},
-		Recommendations: []string{"开头前三章加入更强的钩子，提高留存率", "增加更多爽点和高潮情节", "考虑设计具有传播力的名场面"},
-		Summary:         "本作在都市异能赛道具有一定竞争力，程序员+超能力设定有新意。建议优化开头节奏和爽点密度，可以先在免费平台积累口碑。（模拟数据）",
-	}
-}
-
-func (s *ReviewService) getMockReview() *ReviewResult {
-	return &ReviewResult{
-		Scores: []ReviewScore{
-			{Dimension: "plot", Score: 78, Comment: "情节发展合理，但转折可以更自然"},
-			{Dimension: "character", Score: 82, Comment: "主角形象鲜明，配角可进一步丰富"},
-			{Dimension: "writing", Score: 75, Comment: "文笔流畅，部分描写可以更细腻"},
-			{Dimension: "pacing", Score: 70, Comment: "前半段节奏偏快，建议适当放缓"},
-			{Dimension: "creativity", Score: 80, Comment: "设定有新意，可以进一步深挖"},
-			{Dimension: "readability", Score: 85, Comment: "可读性强，适合目标受众"},
-		},
-		OverallScore: 78,
-		Highlights:   []string{"开篇引人入胜，悬念设置巧妙", "角色对话自然生动", "世界观设定有独创性"},
-		Issues:       []string{"部分过渡段落衔接不够自然", "次要角色形象略显单薄"},
-		Suggestions:  []string{"建议在转折处增加角色心理描写", "可以通过细节描写增强场景沉浸感", "考虑加入更多伏笔增强故事深度"},
-		Summary:      "这是一部有潜力的作品，情节设计和角色塑造都有亮点，文笔流畅可读性强。建议在节奏把控和细节描写上进一步打磨。（模拟数据）",
-	}
-}
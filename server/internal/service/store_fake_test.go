@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"x-novel/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 内存仓储：按数据库的行为返回副本，服务修改返回值后必须写回才会生效
+
+type memProjectStore struct {
+	projects map[string]*model.Project
+}
+
+func newMemProjectStore() *memProjectStore {
+	return &memProjectStore{projects: make(map[string]*model.Project)}
+}
+
+func (s *memProjectStore) Create(ctx context.Context, project *model.Project) error {
+	if project.ID == uuid.Nil {
+		project.ID = uuid.New()
+	}
+	p := *project
+	s.projects[project.ID.String()] = &p
+	return nil
+}
+
+func (s *memProjectStore) GetByID(ctx context.Context, id string) (*model.Project, error) {
+	p, ok := s.projects[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (s *memProjectStore) List(ctx context.Context, deviceID string, offset, limit int) ([]*model.Project, int64, error) {
+	var projects []*model.Project
+	for _, p := range s.projects {
+		if p.DeviceID.String() == deviceID {
+			copied := *p
+			projects = append(projects, &copied)
+		}
+	}
+	total := int64(len(projects))
+	if offset >= len(projects) {
+		return nil, total, nil
+	}
+	projects = projects[offset:]
+	if limit < len(projects) {
+		projects = projects[:limit]
+	}
+	return projects, total, nil
+}
+
+func (s *memProjectStore) Update(ctx context.Context, project *model.Project) error {
+	if _, ok := s.projects[project.ID.String()]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	p := *project
+	s.projects[project.ID.String()] = &p
+	return nil
+}
+
+func (s *memProjectStore) Delete(ctx context.Context, id string) error {
+	delete(s.projects, id)
+	return nil
+}
+
+func (s *memProjectStore) UpdateArchitecture(ctx context.Context, id string, architecture map[string]interface{}) error {
+	p, ok := s.projects[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for column, value := range architecture {
+		switch column {
+		case "architecture_stale":
+			p.ArchitectureStale = value.(string)
+		case "architecture_generated":
+			p.ArchitectureGenerated = value.(bool)
+		case "architecture_synthetic":
+			p.ArchitectureSynthetic = value.(bool)
+		case "updated_at":
+			p.UpdatedAt = value.(time.Time)
+		default:
+			if !isArchitectureStep(column) {
+				return fmt.Errorf("未知的架构列 %q", column)
+			}
+			p.SetArchitectureStep(column, value.(string))
+		}
+	}
+	return nil
+}
+
+func (s *memProjectStore) UpdateSummaryState(ctx context.Context, id string, globalSummary, recap string, recapThrough int) error {
+	p, ok := s.projects[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	p.GlobalSummary = globalSummary
+	p.SummaryRecap = recap
+	p.SummaryRecapThrough = recapThrough
+	return nil
+}
+
+func isArchitectureStep(column string) bool {
+	for _, step := range model.ArchitectureSteps {
+		if step == column {
+			return true
+		}
+	}
+	return false
+}
+
+type memChapterStore struct {
+	chapters map[string]*model.Chapter
+}
+
+func newMemChapterStore() *memChapterStore {
+	return &memChapterStore{chapters: make(map[string]*model.Chapter)}
+}
+
+// filter 返回满足条件的章节副本，按章节号升序
+func (s *memChapterStore) filter(match func(*model.Chapter) bool) []*model.Chapter {
+	var chapters []*model.Chapter
+	for _, ch := range s.chapters {
+		if match(ch) {
+			copied := *ch
+			chapters = append(chapters, &copied)
+		}
+	}
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].ChapterNumber < chapters[j].ChapterNumber })
+	return chapters
+}
+
+// update 修改已保存的章节
+func (s *memChapterStore) update(id string, apply func(*model.Chapter)) error {
+	ch, ok := s.chapters[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	apply(ch)
+	return nil
+}
+
+func (s *memChapterStore) Create(ctx context.Context, chapter *model.Chapter) error {
+	if chapter.ID == uuid.Nil {
+		chapter.ID = uuid.New()
+	}
+	ch := *chapter
+	s.chapters[chapter.ID.String()] = &ch
+	return nil
+}
+
+func (s *memChapterStore) GetByID(ctx context.Context, id string) (*model.Chapter, error) {
+	ch, ok := s.chapters[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *ch
+	return &copied, nil
+}
+
+func (s *memChapterStore) GetByProjectAndNumber(ctx context.Context, projectID string, chapterNumber int) (*model.Chapter, error) {
+	chapters := s.filter(func(ch *model.Chapter) bool {
+		return ch.ProjectID.String() == projectID && ch.ChapterNumber == chapterNumber
+	})
+	if len(chapters) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return chapters[0], nil
+}
+
+func (s *memChapterStore) List(ctx context.Context, projectID string, offset, limit int) ([]*model.Chapter, int64, error) {
+	chapters := s.filter(func(ch *model.Chapter) bool { return ch.ProjectID.String() == projectID })
+	total := int64(len(chapters))
+	if offset >= len(chapters) {
+		return nil, total, nil
+	}
+	chapters = chapters[offset:]
+	if limit < len(chapters) {
+		chapters = chapters[:limit]
+	}
+	return chapters, total, nil
+}
+
+func (s *memChapterStore) Update(ctx context.Context, chapter *model.Chapter) error {
+	return s.update(chapter.ID.String(), func(ch *model.Chapter) { *ch = *chapter })
+}
+
+func (s *memChapterStore) Delete(ctx context.Context, id string) error {
+	delete(s.chapters, id)
+	return nil
+}
+
+func (s *memChapterStore) UpdatePartialContent(ctx context.Context, id string, content string) error {
+	return s.update(id, func(ch *model.Chapter) { ch.PartialContent = content })
+}
+
+func (s *memChapterStore) UpdateSummary(ctx context.Context, id string, summary string) error {
+	return s.update(id, func(ch *model.Chapter) { ch.Summary = summary })
+}
+
+func (s *memChapterStore) LatestCompletedNumber(ctx context.Context, projectID string) (int, error) {
+	chapters, _ := s.ListCompleted(ctx, projectID, int(^uint32(0)>>1))
+	if len(chapters) == 0 {
+		return 0, nil
+	}
+	return chapters[len(chapters)-1].ChapterNumber, nil
+}
+
+func (s *memChapterStore) UpdateStatus(ctx context.Context, id string, status string) error {
+	return s.update(id, func(ch *model.Chapter) { ch.Status = status })
+}
+
+func (s *memChapterStore) SetFinalized(ctx context.Context, id string, finalized bool) error {
+	return s.update(id, func(ch *model.Chapter) { ch.IsFinalized = finalized })
+}
+
+func (s *memChapterStore) ListCompleted(ctx context.Context, projectID string, beforeChapterNumber int) ([]*model.Chapter, error) {
+	return s.filter(func(ch *model.Chapter) bool {
+		return ch.ProjectID.String() == projectID && ch.ChapterNumber < beforeChapterNumber && ch.Status == "completed"
+	}), nil
+}
+
+func (s *memChapterStore) ListByProject(ctx context.Context, projectID string) ([]*model.Chapter, error) {
+	return s.filter(func(ch *model.Chapter) bool { return ch.ProjectID.String() == projectID }), nil
+}
+
+type memCharacterStateStore struct {
+	versions map[string]map[int]*model.CharacterStateVersion // 项目 ID -> 章节号 -> 版本
+}
+
+func newMemCharacterStateStore() *memCharacterStateStore {
+	return &memCharacterStateStore{versions: make(map[string]map[int]*model.CharacterStateVersion)}
+}
+
+func (s *memCharacterStateStore) Save(ctx context.Context, version *model.CharacterStateVersion) error {
+	projectID := version.ProjectID.String()
+	if s.versions[projectID] == nil {
+		s.versions[projectID] = make(map[int]*model.CharacterStateVersion)
+	}
+	if version.ID == uuid.Nil {
+		version.ID = uuid.New()
+	}
+	v := *version
+	s.versions[projectID][version.ChapterNumber] = &v
+	return nil
+}
+
+func (s *memCharacterStateStore) ListByProject(ctx context.Context, projectID string) ([]*model.CharacterStateVersion, error) {
+	var versions []*model.CharacterStateVersion
+	for _, v := range s.versions[projectID] {
+		copied := *v
+		versions = append(versions, &copied)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ChapterNumber < versions[j].ChapterNumber })
+	return versions, nil
+}
+
+func (s *memCharacterStateStore) GetByChapter(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error) {
+	v, ok := s.versions[projectID][chapterNumber]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *v
+	return &copied, nil
+}
+
+func (s *memCharacterStateStore) LatestBefore(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error) {
+	versions, _ := s.ListByProject(ctx, projectID)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].ChapterNumber < chapterNumber {
+			return versions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memCharacterStateStore) DeleteByChapter(ctx context.Context, projectID string, chapterNumber int) error {
+	delete(s.versions[projectID], chapterNumber)
+	return nil
+}
+
+func (s *memCharacterStateStore) DeleteAfter(ctx context.Context, projectID string, chapterNumber int) error {
+	for n := range s.versions[projectID] {
+		if n > chapterNumber {
+			delete(s.versions[projectID], n)
+		}
+	}
+	return nil
+}
+
+func (s *memCharacterStateStore) LatestNumber(ctx context.Context, projectID string) (int, error) {
+	latest := -1
+	for n := range s.versions[projectID] {
+		if n > latest {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+// memModelChainStore 所有用途都绑定到同一个模型配置
+type memModelChainStore struct {
+	config *model.ModelConfig
+}
+
+func (s *memModelChainStore) GetByID(ctx context.Context, id string) (*model.ModelConfig, error) {
+	if id != s.config.ID.String() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.config, nil
+}
+
+func (s *memModelChainStore) GetBinding(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, error) {
+	if purpose != "general" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.ModelBinding{DeviceID: s.config.DeviceID, Purpose: "general", ModelConfigID: s.config.ID}, nil
+}
+
+func (s *memModelChainStore) GetChainByPurpose(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, []*model.ModelConfig, error) {
+	if deviceID != s.config.DeviceID.String() {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	binding, err := s.GetBinding(ctx, deviceID, "general")
+	if err != nil {
+		return nil, nil, err
+	}
+	return binding, []*model.ModelConfig{s.config}, nil
+}
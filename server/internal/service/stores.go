@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"x-novel/internal/model"
+)
+
+// 生成流程使用的仓储接口，由 repository 包中对应的仓储实现；测试中可替换为内存实现
+
+// projectStore 项目仓储
+type projectStore interface {
+	Create(ctx context.Context, project *model.Project) error
+	GetByID(ctx context.Context, id string) (*model.Project, error)
+	List(ctx context.Context, deviceID string, offset, limit int) ([]*model.Project, int64, error)
+	Update(ctx context.Context, project *model.Project) error
+	Delete(ctx context.Context, id string) error
+	UpdateArchitecture(ctx context.Context, id string, architecture map[string]interface{}) error
+	UpdateSummaryState(ctx context.Context, id string, globalSummary, recap string, recapThrough int) error
+}
+
+// chapterStore 章节仓储
+type chapterStore interface {
+	Create(ctx context.Context, chapter *model.Chapter) error
+	GetByID(ctx context.Context, id string) (*model.Chapter, error)
+	GetByProjectAndNumber(ctx context.Context, projectID string, chapterNumber int) (*model.Chapter, error)
+	List(ctx context.Context, projectID string, offset, limit int) ([]*model.Chapter, int64, error)
+	Update(ctx context.Context, chapter *model.Chapter) error
+	Delete(ctx context.Context, id string) error
+	UpdatePartialContent(ctx context.Context, id string, content string) error
+	UpdateSummary(ctx context.Context, id string, summary string) error
+	LatestCompletedNumber(ctx context.Context, projectID string) (int, error)
+	UpdateStatus(ctx context.Context, id string, status string) error
+	SetFinalized(ctx context.Context, id string, finalized bool) error
+	ListCompleted(ctx context.Context, projectID string, beforeChapterNumber int) ([]*model.Chapter, error)
+	ListByProject(ctx context.Context, projectID string) ([]*model.Chapter, error)
+}
+
+// characterStateStore 角色状态历史仓储
+type characterStateStore interface {
+	Save(ctx context.Context, version *model.CharacterStateVersion) error
+	ListByProject(ctx context.Context, projectID string) ([]*model.CharacterStateVersion, error)
+	GetByChapter(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error)
+	LatestBefore(ctx context.Context, projectID string, chapterNumber int) (*model.CharacterStateVersion, error)
+	DeleteByChapter(ctx context.Context, projectID string, chapterNumber int) error
+	DeleteAfter(ctx context.Context, projectID string, chapterNumber int) error
+	LatestNumber(ctx context.Context, projectID string) (int, error)
+}
+
+// chapterDraftStore 章节候选稿仓储
+type chapterDraftStore interface {
+	Create(ctx context.Context, draft *model.ChapterDraft) error
+	ListByChapter(ctx context.Context, projectID string, chapterNumber int) ([]*model.ChapterDraft, error)
+	GetByChapter(ctx context.Context, projectID string, chapterNumber int, id string) (*model.ChapterDraft, error)
+	ExistsContent(ctx context.Context, projectID string, chapterNumber int, content string) (bool, error)
+	MarkPromoted(ctx context.Context, id string, at time.Time) error
+	Delete(ctx context.Context, projectID string, chapterNumber int, id string) (bool, error)
+}
+
+// modelChainStore 调用模型时使用的模型配置仓储
+type modelChainStore interface {
+	GetByID(ctx context.Context, id string) (*model.ModelConfig, error)
+	GetBinding(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, error)
+	GetChainByPurpose(ctx context.Context, deviceID string, purpose string) (*model.ModelBinding, []*model.ModelConfig, error)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"x-novel/internal/llm"
+	"x-novel/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// maxJSONRepairs JSON 校验失败后请求模型修复的最大次数
+const maxJSONRepairs = 1
+
+// ErrInvalidJSONOutput 模型输出经修复后仍不符合要求的结构
+var ErrInvalidJSONOutput = errors.New("模型输出的 JSON 不符合要求")
+
+// ChatJSON 结构化输出调用：以 JSON 模式请求模型，按 target 的结构校验并解析到 target；
+// 校验失败时把错误反馈给模型修复，返回校验通过的 JSON 文本
+func (s *ModelInvoker) ChatJSON(ctx context.Context, req *InvokeRequest, name string, target interface{}) (string, error) {
+	format := llm.JSONSchemaFormat(name, target)
+	jsonReq := *req
+	jsonReq.ResponseFormat = format
+
+	raw, err := s.Chat(ctx, &jsonReq)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; ; attempt++ {
+		cleaned := llm.ExtractJSON(raw)
+		verr := format.Schema.Validate([]byte(cleaned))
+		if verr == nil {
+			verr = json.Unmarshal([]byte(cleaned), target)
+		}
+		if verr == nil {
+			return cleaned, nil
+		}
+		if attempt >= maxJSONRepairs {
+			return "", fmt.Errorf("%w（%s）: %v", ErrInvalidJSONOutput, name, verr)
+		}
+
+		logger.Warn("模型输出的 JSON 未通过校验，请求修复",
+			zap.String("purpose", string(req.Purpose)),
+			zap.String("schema", name),
+			zap.Error(verr),
+		)
+		repairReq := jsonReq
+		repairReq.Messages = userMessages(getJSONRepairPrompt(raw, verr, format))
+		repairReq.Temperature = 0.1
+		if raw, err = s.Chat(ctx, &repairReq); err != nil {
+			return "", err
+		}
+	}
+}
+
+// getJSONRepairPrompt 修复 JSON 的提示词
+func getJSONRepairPrompt(raw string, verr error, format *llm.ResponseFormat) string {
+	schema, _ := json.Marshal(format.Schema)
+	return fmt.Sprintf(`下面是一段应当符合指定 JSON Schema 的输出，但未通过校验。
+
+【校验错误】
+%s
+
+【JSON Schema】
+%s
+
+【原始输出】
+%s
+
+请修复上述输出，保留其中的原有内容，补齐缺失的必填字段并修正类型错误。
+只输出修复后的 JSON，不要输出代码块标记或任何解释文字。`, verr.Error(), string(schema), raw)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSummaryTokenBudget 未配置时全局摘要的 token 预算
+	defaultSummaryTokenBudget = 2000
+	// summaryRecentChapters 全局摘要中始终保留原文摘要的最近章节数
+	summaryRecentChapters = 3
+)
+
+// summarizeChapter 根据章节正文生成摘要
+func (s *ChapterService) summarizeChapter(ctx context.Context, deviceID uuid.UUID, projectID string, chapter *model.Chapter) (string, error) {
+	summary, err := s.invoker.Chat(ctx, &InvokeRequest{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		Purpose:   PurposeSummary,
+		Messages:  userMessages(GetSummaryPrompt(chapter.Content, chapter.ChapterNumber)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// refreshGlobalSummary 重建全局摘要：前情概要 + 之后已完成章节的摘要。
+// 超出 token 预算时，把最近几章之前的章节摘要分批压缩进前情概要；
+// changed 为摘要有变化的章节号，已被压缩进前情概要时整体重新压缩
+func (s *ChapterService) refreshGlobalSummary(ctx context.Context, deviceID uuid.UUID, projectID string, changed int) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	chapters, err := s.chapterRepo.ListCompleted(ctx, projectID, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	budget := s.opts.SummaryTokenBudget
+	recap, through := project.SummaryRecap, project.SummaryRecapThrough
+	if changed <= through {
+		recap, through = "", 0
+	}
+	var entries []*model.Chapter
+	for _, ch := range chapters {
+		if ch.ChapterNumber > through {
+			entries = append(entries, ch)
+		}
+	}
+
+	for llm.EstimateTokens(buildGlobalSummary(recap, through, entries)) > budget && len(entries) > summaryRecentChapters {
+		// 每批压缩的章节摘要约为预算的一半，至少一章
+		foldable := entries[:len(entries)-summaryRecentChapters]
+		n, tokens := 0, 0
+		for n < len(foldable) && (n == 0 || tokens < budget/2) {
+			tokens += llm.EstimateTokens(chapterSummaryEntry(foldable[n]))
+			n++
+		}
+
+		var batch strings.Builder
+		for _, ch := range foldable[:n] {
+			batch.WriteString(chapterSummaryEntry(ch))
+		}
+		compressed, err := s.invoker.Chat(ctx, &InvokeRequest{
+			DeviceID:  deviceID,
+			ProjectID: projectID,
+			Purpose:   PurposeSummary,
+			Messages:  userMessages(GetCompressSummaryPrompt(recap, batch.String(), budget/2)),
+		})
+		if err != nil {
+			// 压缩失败时保留未压缩的摘要，下次定稿时重试
+			logger.Warn("压缩前文摘要失败", zap.String("project_id", projectID), zap.Error(err))
+			break
+		}
+
+		recap = strings.TrimSpace(compressed)
+		through = foldable[n-1].ChapterNumber
+		entries = entries[n:]
+		logger.Info("已压缩前文摘要",
+			zap.String("project_id", projectID),
+			zap.Int("recap_through", through),
+			zap.Int("recap_tokens", llm.EstimateTokens(recap)),
+		)
+	}
+
+	return s.projectRepo.UpdateSummaryState(ctx, projectID, buildGlobalSummary(recap, through, entries), recap, through)
+}
+
+// retrievalGlobalSummary 有检索片段时写入提示词的精简全局摘要：保留前情概要，
+// 其后的章节摘要从最近的往前保留，总量不超过 token 预算的一半；更早章节的细节由检索片段提供。
+// 上一章的摘要已单独提供，不重复写入
+func (s *ChapterService) retrievalGlobalSummary(ctx context.Context, project *model.Project, chapterNumber int) (string, error) {
+	chapters, err := s.chapterRepo.ListCompleted(ctx, project.ID.String(), chapterNumber-1)
+	if err != nil {
+		return "", err
+	}
+
+	recap, through := project.SummaryRecap, project.SummaryRecapThrough
+	budget := s.opts.SummaryTokenBudget/2 - llm.EstimateTokens(buildGlobalSummary(recap, through, nil))
+	start := len(chapters)
+	for start > 0 && chapters[start-1].ChapterNumber > through {
+		tokens := llm.EstimateTokens(chapterSummaryEntry(chapters[start-1]))
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+		start--
+	}
+	return buildGlobalSummary(recap, through, chapters[start:]), nil
+}
+
+// buildGlobalSummary 拼接全局摘要
+func buildGlobalSummary(recap string, through int, entries []*model.Chapter) string {
+	var b strings.Builder
+	if recap != "" {
+		fmt.Fprintf(&b, "【前情概要（第1-%d章）】\n%s\n\n", through, recap)
+	}
+	for _, ch := range entries {
+		b.WriteString(chapterSummaryEntry(ch))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// chapterSummaryEntry 单章在全局摘要中的条目，没有正文摘要时使用大纲摘要
+func chapterSummaryEntry(ch *model.Chapter) string {
+	summary := ch.Summary
+	if summary == "" {
+		summary = ch.BlueprintSummary
+	}
+	title := fmt.Sprintf("第%d章", ch.ChapterNumber)
+	if ch.Title != "" {
+		title += " " + ch.Title
+	}
+	return fmt.Sprintf("【%s】\n%s\n\n", title, summary)
+}
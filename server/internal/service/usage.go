@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"x-novel/internal/dto"
+	"x-novel/internal/llm"
+	"x-novel/internal/model"
+	"x-novel/internal/repository"
+	"x-novel/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded 超出月度用量配额
+var ErrQuotaExceeded = errors.New("已超出本月用量配额")
+
+// QuotaLimits 月度配额限制，限额为 0 表示不限
+type QuotaLimits struct {
+	MonthlyTokens int64
+	MonthlyCost   float64
+	WarnRatio     float64
+}
+
+// UsageService 用量统计与配额服务
+type UsageService struct {
+	usageRepo *repository.UsageRepository
+	prices    *llm.PriceTable
+	cache     *llm.ResponseCache // 可为 nil
+	defaults  QuotaLimits        // 未单独设置配额的设备使用的默认限制
+
+	mu     sync.Mutex
+	warned map[string]bool // 本月已告警的配额，避免重复告警
+}
+
+// NewUsageService 创建用量统计服务
+func NewUsageService(usageRepo *repository.UsageRepository, prices *llm.PriceTable, cache *llm.ResponseCache, defaults QuotaLimits) *UsageService {
+	return &UsageService{
+		usageRepo: usageRepo,
+		prices:    prices,
+		cache:     cache,
+		defaults:  defaults,
+		warned:    make(map[string]bool),
+	}
+}
+
+// GetSummary 按天与项目汇总用量，时间范围为 [from, to]（按日期，含两端）
+func (s *UsageService) GetSummary(ctx context.Context, deviceID uuid.UUID, from, to time.Time, projectID *uuid.UUID) (*dto.UsageSummaryResponse, error) {
+	filter := repository.UsageFilter{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	}
+
+	total, err := s.usageRepo.Sum(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.usageRepo.SumByDay(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.usageRepo.SumByProject(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.UsageSummaryResponse{
+		Currency: s.prices.Currency,
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Total:    usageStats(total),
+		Days:     make([]dto.DailyUsageResponse, 0, len(days)),
+		Projects: make([]dto.ProjectUsageResponse, 0, len(projects)),
+	}
+	for _, d := range days {
+		resp.Days = append(resp.Days, dto.DailyUsageResponse{Date: d.Day, UsageStats: usageStats(&d.UsageSum)})
+	}
+	for _, p := range projects {
+		resp.Projects = append(resp.Projects, dto.ProjectUsageResponse{
+			ProjectID:    p.ProjectID,
+			ProjectTitle: p.ProjectTitle,
+			UsageStats:   usageStats(&p.UsageSum),
+		})
+	}
+	return resp, nil
+}
+
+// GetCacheStats 获取响应缓存命中统计（进程启动以来）
+func (s *UsageService) GetCacheStats() *dto.CacheStatsResponse {
+	if s.cache == nil {
+		return &dto.CacheStatsResponse{Enabled: false}
+	}
+	stats := s.cache.Stats()
+	resp := &dto.CacheStatsResponse{
+		Enabled:    true,
+		TTLSeconds: int64(s.cache.TTL().Seconds()),
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		resp.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return resp
+}
+
+func usageStats(sum *repository.UsageSum) dto.UsageStats {
+	return dto.UsageStats{
+		PromptTokens:     sum.PromptTokens,
+		CompletionTokens: sum.CompletionTokens,
+		TotalTokens:      sum.TotalTokens,
+		Cost:             sum.Cost,
+		Calls:            sum.Calls,
+	}
+}
+
+// Record 计算费用并记录一次调用的用量，达到预警阈值时告警
+func (s *UsageService) Record(ctx context.Context, record *model.LLMUsage) error {
+	record.Cost = s.prices.Cost(record.Model, llm.Usage{
+		PromptTokens:     record.PromptTokens,
+		CompletionTokens: record.CompletionTokens,
+	})
+	if err := s.usageRepo.Create(ctx, record); err != nil {
+		return err
+	}
+
+	for _, projectID := range quotaScopes(record.ProjectID) {
+		status, err := s.quotaStatus(ctx, record.DeviceID, projectID)
+		if err != nil || status == nil || !status.Warning {
+			continue
+		}
+		s.warnOnce(record.DeviceID, status)
+	}
+	return nil
+}
+
+// CheckQuota 调用前检查设备及项目的月度配额，已用量加上本次提示词预估超出限额时拒绝
+func (s *UsageService) CheckQuota(ctx context.Context, deviceID uuid.UUID, projectID string, modelName string, messages []llm.ChatMessage) error {
+	var pid *uuid.UUID
+	if id, err := uuid.Parse(projectID); err == nil {
+		pid = &id
+	}
+
+	prompt := llm.EstimateUsage(messages, "")
+	promptCost := s.prices.Cost(modelName, prompt)
+
+	for _, scope := range quotaScopes(pid) {
+		status, err := s.quotaStatus(ctx, deviceID, scope)
+		if err != nil {
+			// 配额查询失败时不阻断调用
+			logger.Warn("查询用量配额失败", zap.String("device_id", deviceID.String()), zap.Error(err))
+			continue
+		}
+		if status == nil {
+			continue
+		}
+
+		if status.MonthlyTokens > 0 && status.UsedTokens+int64(prompt.TotalTokens) > status.MonthlyTokens {
+			return fmt.Errorf("%w（%s）：已用 %d / %d tokens", ErrQuotaExceeded, scopeName(scope), status.UsedTokens, status.MonthlyTokens)
+		}
+		if status.MonthlyCost > 0 && status.UsedCost+promptCost > status.MonthlyCost {
+			return fmt.Errorf("%w（%s）：已用 %.4f / %.4f %s", ErrQuotaExceeded, scopeName(scope), status.UsedCost, status.MonthlyCost, s.prices.Currency)
+		}
+	}
+	return nil
+}
+
+// ListQuotas 获取设备级配额及各项目配额的本月使用情况
+func (s *UsageService) ListQuotas(ctx context.Context, deviceID uuid.UUID) ([]*dto.UsageQuotaResponse, error) {
+	quotas, err := s.usageRepo.ListQuotas(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*dto.UsageQuotaResponse, 0, len(quotas)+1)
+	hasDeviceQuota := false
+	for _, q := range quotas {
+		if q.ProjectID == nil {
+			hasDeviceQuota = true
+		}
+	}
+	// 设备未单独设置时展示默认配额
+	if !hasDeviceQuota {
+		status, err := s.quotaStatus(ctx, deviceID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if status != nil {
+			result = append(result, status)
+		}
+	}
+	for _, q := range quotas {
+		status, err := s.buildQuotaStatus(ctx, deviceID, q.ProjectID, s.limitsOf(q))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// UpsertQuota 设置设备级或项目级配额
+func (s *UsageService) UpsertQuota(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID, req *dto.UpsertUsageQuotaRequest) (*dto.UsageQuotaResponse, error) {
+	quota := &model.UsageQuota{
+		DeviceID:      deviceID,
+		ProjectID:     projectID,
+		MonthlyTokens: req.MonthlyTokens,
+		MonthlyCost:   req.MonthlyCost,
+		WarnRatio:     req.WarnRatio,
+	}
+	if err := s.usageRepo.UpsertQuota(ctx, quota); err != nil {
+		return nil, err
+	}
+	s.resetWarning(deviceID, projectID)
+	return s.buildQuotaStatus(ctx, deviceID, projectID, s.limitsOf(quota))
+}
+
+// DeleteQuota 删除配额，设备级配额删除后恢复为默认限制
+func (s *UsageService) DeleteQuota(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID) error {
+	if err := s.usageRepo.DeleteQuota(ctx, deviceID, projectID); err != nil {
+		return err
+	}
+	s.resetWarning(deviceID, projectID)
+	return nil
+}
+
+// quotaStatus 获取配额状态，未设置任何限制时返回 nil
+func (s *UsageService) quotaStatus(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID) (*dto.UsageQuotaResponse, error) {
+	var limits QuotaLimits
+	quota, err := s.usageRepo.GetQuota(ctx, deviceID, projectID)
+	switch {
+	case err == nil:
+		limits = s.limitsOf(quota)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if projectID != nil {
+			return nil, nil
+		}
+		limits = s.defaults
+	default:
+		return nil, err
+	}
+
+	if limits.MonthlyTokens <= 0 && limits.MonthlyCost <= 0 {
+		return nil, nil
+	}
+	return s.buildQuotaStatus(ctx, deviceID, projectID, limits)
+}
+
+// buildQuotaStatus 统计本月用量并与限制比较
+func (s *UsageService) buildQuotaStatus(ctx context.Context, deviceID uuid.UUID, projectID *uuid.UUID, limits QuotaLimits) (*dto.UsageQuotaResponse, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	used, err := s.usageRepo.Sum(ctx, repository.UsageFilter{
+		DeviceID:  deviceID,
+		ProjectID: projectID,
+		From:      monthStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dto.UsageQuotaResponse{
+		ProjectID:     projectID,
+		Period:        monthStart.Format("2006-01"),
+		MonthlyTokens: limits.MonthlyTokens,
+		MonthlyCost:   limits.MonthlyCost,
+		WarnRatio:     limits.WarnRatio,
+		UsedTokens:    used.TotalTokens,
+		UsedCost:      used.Cost,
+	}
+	if limits.MonthlyTokens > 0 {
+		ratio := float64(used.TotalTokens) / float64(limits.MonthlyTokens)
+		status.Warning = status.Warning || ratio >= limits.WarnRatio
+		status.Exceeded = status.Exceeded || ratio >= 1
+	}
+	if limits.MonthlyCost > 0 {
+		ratio := used.Cost / limits.MonthlyCost
+		status.Warning = status.Warning || ratio >= limits.WarnRatio
+		status.Exceeded = status.Exceeded || ratio >= 1
+	}
+	return status, nil
+}
+
+// limitsOf 配额记录对应的限制，未设置预警比例时使用默认值
+func (s *UsageService) limitsOf(quota *model.UsageQuota) QuotaLimits {
+	limits := QuotaLimits{
+		MonthlyTokens: quota.MonthlyTokens,
+		MonthlyCost:   quota.MonthlyCost,
+		WarnRatio:     quota.WarnRatio,
+	}
+	if limits.WarnRatio <= 0 {
+		limits.WarnRatio = s.defaults.WarnRatio
+	}
+	return limits
+}
+
+// warnOnce 每个配额每月只告警一次
+func (s *UsageService) warnOnce(deviceID uuid.UUID, status *dto.UsageQuotaResponse) {
+	key := warningKey(deviceID, status.ProjectID) + "@" + status.Period
+	s.mu.Lock()
+	if s.warned[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.warned[key] = true
+	s.mu.Unlock()
+
+	logger.Warn("用量接近月度配额上限",
+		zap.String("device_id", deviceID.String()),
+		zap.String("scope", scopeName(status.ProjectID)),
+		zap.String("period", status.Period),
+		zap.Int64("used_tokens", status.UsedTokens),
+		zap.Int64("monthly_tokens", status.MonthlyTokens),
+		zap.Float64("used_cost", status.UsedCost),
+		zap.Float64("monthly_cost", status.MonthlyCost),
+		zap.Bool("exceeded", status.Exceeded),
+	)
+}
+
+// resetWarning 配额变更后允许重新告警
+func (s *UsageService) resetWarning(deviceID uuid.UUID, projectID *uuid.UUID) {
+	prefix := warningKey(deviceID, projectID) + "@"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.warned {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.warned, key)
+		}
+	}
+}
+
+func warningKey(deviceID uuid.UUID, projectID *uuid.UUID) string {
+	if projectID == nil {
+		return deviceID.String()
+	}
+	return deviceID.String() + "/" + projectID.String()
+}
+
+// quotaScopes 需要检查的配额范围：设备级，以及关联项目时的项目级
+func quotaScopes(projectID *uuid.UUID) []*uuid.UUID {
+	if projectID == nil {
+		return []*uuid.UUID{nil}
+	}
+	return []*uuid.UUID{nil, projectID}
+}
+
+func scopeName(projectID *uuid.UUID) string {
+	if projectID == nil {
+		return "设备"
+	}
+	return "项目 " + projectID.String()
+}
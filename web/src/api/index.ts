@@ -4,6 +4,19 @@ import type {
   Device,
   DeviceSettings,
   Project,
+  ArchitectureStep,
+  BlueprintGenerateResult,
+  BlueprintSyncResult,
+  ChapterBatchResult,
+  GenerateChapterBatchRequest,
+  ChapterDraft,
+  ChapterDraftsResult,
+  ChapterDraftCompare,
+  GenerateChapterDraftsRequest,
+  CharacterStateVersion,
+  CharacterStateDiff,
+  RetrievalStatus,
+  RetrievalReindexResult,
   CreateProjectRequest,
   UpdateProjectRequest,
   Chapter,
@@ -26,6 +39,7 @@ import type {
   MarketPrediction,
   BackupPreview,
   ImportResult,
+  Job,
 } from '../types';
 
 // ========== 设备相关 API ==========
@@ -80,20 +94,37 @@ export const projectApi = {
 
   // 生成小说架构
   generateArchitecture: (id: string, data: { overwrite?: boolean }) => {
-    return request.post<Response<Project>>(
+    return request.post<Response<Job<Project>>>(
       `/api/v1/projects/${id}/architecture/generate`,
       data
     );
   },
 
+  // 重新生成单个架构步骤，下游步骤默认标记为需要更新
+  regenerateArchitectureStep: (
+    id: string,
+    step: ArchitectureStep,
+    data: { instruction?: string; downstream?: 'stale' | 'keep' }
+  ) => {
+    return request.post<Response<Job<Project>>>(
+      `/api/v1/projects/${id}/architecture/steps/${step}/regenerate`,
+      data
+    );
+  },
+
   // 生成章节大纲
   generateBlueprint: (id: string, data: { overwrite?: boolean }) => {
-    return request.post<Response<Project>>(
+    return request.post<Response<Job<BlueprintGenerateResult>>>(
       `/api/v1/projects/${id}/blueprint/generate`,
       data
     );
   },
 
+  // 将章节大纲解析到各章节（修改大纲后重新解析）
+  syncBlueprint: (id: string) => {
+    return request.post<Response<BlueprintSyncResult>>(`/api/v1/projects/${id}/blueprint/sync`);
+  },
+
   // 导出项目
   export: (id: string, format: 'txt' | 'md') => {
     return request.get<Response<{ download_url: string }>>(
@@ -146,20 +177,99 @@ export const chapterApi = {
     chapterNumber: number,
     data: { overwrite?: boolean }
   ) => {
-    return request.post<Response<Chapter>>(
+    return request.post<Response<Job<Chapter>>>(
       `/api/v1/projects/${projectId}/chapters/${chapterNumber}/generate`,
       data
     );
   },
 
+  // 批量生成章节（后台任务）
+  generateBatch: (projectId: string, data: GenerateChapterBatchRequest) => {
+    return request.post<Response<Job<ChapterBatchResult>>>(
+      `/api/v1/projects/${projectId}/chapters/batch`,
+      data
+    );
+  },
+
+  // 最近一次批量生成的状态
+  getBatch: (projectId: string) => {
+    return request.get<Response<Job<ChapterBatchResult>>>(`/api/v1/projects/${projectId}/chapters/batch`);
+  },
+
+  // 继续最近一次批量生成（重新生成失败与未完成的章节）
+  resumeBatch: (projectId: string) => {
+    return request.post<Response<Job<ChapterBatchResult>>>(
+      `/api/v1/projects/${projectId}/chapters/batch/resume`
+    );
+  },
+
+  // 生成候选稿（后台任务）
+  generateDrafts: (projectId: string, chapterNumber: number, data: GenerateChapterDraftsRequest) => {
+    return request.post<Response<Job<ChapterDraftsResult>>>(
+      `/api/v1/projects/${projectId}/chapters/${chapterNumber}/drafts`,
+      data
+    );
+  },
+
+  // 候选稿列表
+  listDrafts: (projectId: string, chapterNumber: number) => {
+    return request.get<Response<ChapterDraft[]>>(`/api/v1/projects/${projectId}/chapters/${chapterNumber}/drafts`);
+  },
+
+  // 比较候选稿，current 表示章节当前正文
+  compareDrafts: (projectId: string, chapterNumber: number, ids: string[]) => {
+    return request.get<Response<ChapterDraftCompare>>(
+      `/api/v1/projects/${projectId}/chapters/${chapterNumber}/drafts/compare`,
+      { params: { ids: ids.join(',') } }
+    );
+  },
+
+  // 选用候选稿为章节正文
+  promoteDraft: (projectId: string, chapterNumber: number, draftId: string) => {
+    return request.post<Response<Chapter>>(
+      `/api/v1/projects/${projectId}/chapters/${chapterNumber}/drafts/${draftId}/promote`
+    );
+  },
+
+  // 删除候选稿
+  deleteDraft: (projectId: string, chapterNumber: number, draftId: string) => {
+    return request.delete<Response<void>>(`/api/v1/projects/${projectId}/chapters/${chapterNumber}/drafts/${draftId}`);
+  },
+
   // 定稿章节
-  finalize: (projectId: string, chapterNumber: number, data: { update_summary?: boolean }) => {
+  finalize: (
+    projectId: string,
+    chapterNumber: number,
+    data: { update_summary?: boolean; update_character_state?: boolean }
+  ) => {
     return request.post<Response<Chapter>>(
       `/api/v1/projects/${projectId}/chapters/${chapterNumber}/finalize`,
       data
     );
   },
 
+  // 角色状态历史
+  listCharacterStates: (projectId: string) => {
+    return request.get<Response<CharacterStateVersion[]>>(`/api/v1/projects/${projectId}/character-states`);
+  },
+
+  // 某一章的角色状态与上一版本的比较
+  diffCharacterState: (projectId: string, chapterNumber: number) => {
+    return request.get<Response<CharacterStateDiff>>(
+      `/api/v1/projects/${projectId}/character-states/${chapterNumber}/diff`
+    );
+  },
+
+  // 前文检索索引状态
+  retrievalStatus: (projectId: string) => {
+    return request.get<Response<RetrievalStatus>>(`/api/v1/projects/${projectId}/retrieval`);
+  },
+
+  // 重建前文检索索引
+  reindexRetrieval: (projectId: string) => {
+    return request.post<Response<RetrievalReindexResult>>(`/api/v1/projects/${projectId}/retrieval/reindex`);
+  },
+
   // 扩写章节
   enrich: (projectId: string, chapterNumber: number, data: { target_words?: number }) => {
     return request.post<Response<Chapter>>(
@@ -236,7 +346,7 @@ export const graphApi = {
 
   // 生成图谱（从架构提取）
   generate: (projectId: string) => {
-    return request.post<Response<GraphData>>(`/api/v1/projects/${projectId}/graph/generate`);
+    return request.post<Response<Job<GraphData>>>(`/api/v1/projects/${projectId}/graph/generate`);
   },
 
   // 从章节更新图谱
@@ -250,6 +360,56 @@ export const graphApi = {
   },
 };
 
+// ========== 后台任务 API ==========
+
+export const jobApi = {
+  // 获取任务状态
+  get: <T = unknown>(id: string) => {
+    return request.get<Response<Job<T>>>(`/api/v1/jobs/${id}`);
+  },
+
+  // 获取任务列表
+  list: (params?: { project_id?: string; type?: string; status?: string; page?: number; page_size?: number }) => {
+    return request.get<Response<{ jobs: Job[]; total: number }>>('/api/v1/jobs', { params });
+  },
+
+  // 取消任务
+  cancel: (id: string) => {
+    return request.post<Response<Job>>(`/api/v1/jobs/${id}/cancel`);
+  },
+};
+
+/**
+ * 轮询后台任务直到结束：成功时返回任务结果，失败或取消时抛出包含原因的错误。
+ */
+export async function waitForJob<T>(
+  job: Job<T>,
+  onProgress?: (job: Job<T>) => void,
+  interval = 2000
+): Promise<T> {
+  let current = job;
+  while (current.status === 'pending' || current.status === 'running') {
+    onProgress?.(current);
+    await new Promise((resolve) => setTimeout(resolve, interval));
+    const res = await jobApi.get<T>(current.id);
+    current = res.data;
+  }
+  if (current.status !== 'succeeded') {
+    throw new Error(current.error || (current.status === 'canceled' ? '任务已取消' : '任务失败'));
+  }
+  return current.result as T;
+}
+
+/**
+ * 任务进度文案，如"生成世界观 3/5"；排队中的任务返回"排队中"。
+ */
+export function formatJobProgress(job: Job): string {
+  if (job.status === 'pending' || !job.step) {
+    return '排队中';
+  }
+  return job.total_steps > 1 ? `${job.step} ${job.step_index}/${job.total_steps}` : job.step;
+}
+
 // ========== 数据备份 API ==========
 
 export const backupApi = {
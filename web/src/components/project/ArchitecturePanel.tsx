@@ -1,9 +1,10 @@
 import { useState, useEffect } from 'react';
-import { Button, Form, Input, Space, Collapse, App, Badge, Typography, Flex, theme } from 'antd';
-import { PlayCircleOutlined, SaveOutlined, ThunderboltOutlined } from '@ant-design/icons';
-import { projectApi } from '../../api';
-import type { Project } from '../../types';
+import { Button, Form, Input, Space, Collapse, App, Badge, Typography, Flex, Modal, Radio, Tag, theme } from 'antd';
+import { HistoryOutlined, PlayCircleOutlined, ReloadOutlined, SaveOutlined, ThunderboltOutlined } from '@ant-design/icons';
+import { projectApi, waitForJob, formatJobProgress } from '../../api';
+import type { ArchitectureStep, Project } from '../../types';
 import { useMutation, useQueryClient } from '@tanstack/react-query';
+import CharacterStateHistoryModal from './CharacterStateHistoryModal';
 
 const { TextArea } = Input;
 const { Title, Text } = Typography;
@@ -18,6 +19,17 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
   const { token } = theme.useToken();
   const [form] = Form.useForm();
   const [generating, setGenerating] = useState(false);
+  const [progress, setProgress] = useState<string>();
+  const [regenStep, setRegenStep] = useState<ArchitectureStep>();
+  const [instruction, setInstruction] = useState('');
+  const [downstream, setDownstream] = useState<'stale' | 'keep'>('stale');
+  const [historyOpen, setHistoryOpen] = useState(false);
+
+  const stale = project.architecture_stale ?? [];
+  // 部分步骤已生成（上次生成中断），再次生成时从缺失的步骤继续
+  const partial =
+    !project.architecture_generated &&
+    !!(project.core_seed || project.character_dynamics || project.world_building || project.plot_architecture || project.character_state);
 
   useEffect(() => {
     form.setFieldsValue({
@@ -42,16 +54,36 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
   });
 
   const generateMutation = useMutation({
-    mutationFn: (overwrite: boolean) =>
-      projectApi.generateArchitecture(project.id, { overwrite }),
+    mutationFn: async (overwrite: boolean) => {
+      const res = await projectApi.generateArchitecture(project.id, { overwrite });
+      return waitForJob(res.data, (job) => setProgress(formatJobProgress(job)));
+    },
     onSuccess: () => {
       message.success('架构生成成功');
       queryClient.invalidateQueries({ queryKey: ['project', project.id] });
       setGenerating(false);
+      setProgress(undefined);
     },
     onError: () => {
       message.error('架构生成失败');
       setGenerating(false);
+      setProgress(undefined);
+    },
+  });
+
+  const regenerateMutation = useMutation({
+    mutationFn: async (step: ArchitectureStep) => {
+      const res = await projectApi.regenerateArchitectureStep(project.id, step, { instruction, downstream });
+      return waitForJob(res.data);
+    },
+    onSuccess: () => {
+      message.success('重新生成成功');
+      queryClient.invalidateQueries({ queryKey: ['project', project.id] });
+      setRegenStep(undefined);
+      setInstruction('');
+    },
+    onError: (error: Error) => {
+      message.error(error.message || '重新生成失败');
     },
   });
 
@@ -78,15 +110,31 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
     updateMutation.mutate(values);
   };
 
-  const createHeader = (title: string, hasContent: boolean, color: string) => (
+  const createHeader = (title: string, hasContent: boolean, color: string, step: ArchitectureStep) => (
     <Flex align="center" gap={8} style={{ padding: '4px 0' }}>
       <Badge color={hasContent ? color : '#d9d9d9'} />
       <Text strong={hasContent} type={hasContent ? undefined : 'secondary'} style={{ fontSize: 15 }}>
         {title}
       </Text>
+      {stale.includes(step) && <Tag color="warning">上游已更新</Tag>}
     </Flex>
   );
 
+  const createExtra = (step: ArchitectureStep) => (
+    <Button
+      type="text"
+      size="small"
+      icon={<ReloadOutlined />}
+      disabled={generating}
+      onClick={(e) => {
+        e.stopPropagation();
+        setRegenStep(step);
+      }}
+    >
+      重新生成
+    </Button>
+  );
+
   const textAreaStyle: React.CSSProperties = {
     fontFamily: 'monospace',
     fontSize: 13,
@@ -99,7 +147,8 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
   const collapseItems = [
     {
       key: 'core_seed',
-      label: createHeader('1. 核心种子', !!project.core_seed, '#3b82f6'),
+      label: createHeader('1. 核心种子', !!project.core_seed, '#3b82f6', 'core_seed'),
+      extra: createExtra('core_seed'),
       children: (
         <Form.Item name="core_seed" style={{ marginBottom: 0 }}>
           <TextArea
@@ -112,7 +161,8 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
     },
     {
       key: 'character_dynamics',
-      label: createHeader('2. 角色动力学', !!project.character_dynamics, '#ec4899'),
+      label: createHeader('2. 角色动力学', !!project.character_dynamics, '#ec4899', 'character_dynamics'),
+      extra: createExtra('character_dynamics'),
       children: (
         <Form.Item name="character_dynamics" style={{ marginBottom: 0 }}>
           <TextArea
@@ -125,7 +175,8 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
     },
     {
       key: 'world_building',
-      label: createHeader('3. 世界观构建', !!project.world_building, '#10b981'),
+      label: createHeader('3. 世界观构建', !!project.world_building, '#10b981', 'world_building'),
+      extra: createExtra('world_building'),
       children: (
         <Form.Item name="world_building" style={{ marginBottom: 0 }}>
           <TextArea
@@ -138,7 +189,8 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
     },
     {
       key: 'plot_architecture',
-      label: createHeader('4. 情节架构', !!project.plot_architecture, '#f59e0b'),
+      label: createHeader('4. 情节架构', !!project.plot_architecture, '#f59e0b', 'plot_architecture'),
+      extra: createExtra('plot_architecture'),
       children: (
         <Form.Item name="plot_architecture" style={{ marginBottom: 0 }}>
           <TextArea
@@ -151,7 +203,23 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
     },
     {
       key: 'character_state',
-      label: createHeader('5. 角色状态', !!project.character_state, '#8b5cf6'),
+      label: createHeader('5. 角色状态', !!project.character_state, '#8b5cf6', 'character_state'),
+      extra: (
+        <Space size={0}>
+          <Button
+            type="text"
+            size="small"
+            icon={<HistoryOutlined />}
+            onClick={(e) => {
+              e.stopPropagation();
+              setHistoryOpen(true);
+            }}
+          >
+            历史
+          </Button>
+          {createExtra('character_state')}
+        </Space>
+      ),
       children: (
         <Form.Item name="character_state" style={{ marginBottom: 0 }}>
           <TextArea
@@ -190,7 +258,7 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
             loading={generating || generateMutation.isPending}
             size="large"
           >
-            {project.architecture_generated ? '重新生成' : 'AI 一键生成架构'}
+            {progress ?? (project.architecture_generated ? '重新生成' : partial ? '继续生成' : 'AI 一键生成架构')}
           </Button>
           <Button
             type="primary"
@@ -235,6 +303,30 @@ function ArchitecturePanel({ project }: ArchitecturePanelProps) {
           />
         </Form>
       </div>
+
+      <Modal
+        title="重新生成该步骤"
+        open={!!regenStep}
+        centered
+        confirmLoading={regenerateMutation.isPending}
+        onOk={() => regenStep && regenerateMutation.mutate(regenStep)}
+        onCancel={() => setRegenStep(undefined)}
+      >
+        <Space direction="vertical" style={{ width: '100%' }}>
+          <TextArea
+            rows={4}
+            value={instruction}
+            onChange={(e) => setInstruction(e.target.value)}
+            placeholder="修改要求（可选），例如：把世界观改为近未来赛博都市"
+          />
+          <Radio.Group value={downstream} onChange={(e) => setDownstream(e.target.value)}>
+            <Radio value="stale">标记下游步骤需要更新</Radio>
+            <Radio value="keep">保持下游步骤不变</Radio>
+          </Radio.Group>
+        </Space>
+      </Modal>
+
+      <CharacterStateHistoryModal projectId={project.id} open={historyOpen} onClose={() => setHistoryOpen(false)} />
     </div>
   );
 }
@@ -1,8 +1,8 @@
 import { useState, useEffect } from 'react';
 import { Button, Form, Input, Space, App, Typography, Flex, theme } from 'antd';
-import { PlayCircleOutlined, SaveOutlined, UnorderedListOutlined } from '@ant-design/icons';
-import { projectApi } from '../../api';
-import type { Project } from '../../types';
+import { PlayCircleOutlined, SaveOutlined, SyncOutlined, UnorderedListOutlined } from '@ant-design/icons';
+import { projectApi, waitForJob, formatJobProgress } from '../../api';
+import type { BlueprintSyncResult, Project } from '../../types';
 import { useMutation, useQueryClient } from '@tanstack/react-query';
 
 const { TextArea } = Input;
@@ -18,6 +18,7 @@ function BlueprintPanel({ project }: BlueprintPanelProps) {
   const { token } = theme.useToken();
   const [form] = Form.useForm();
   const [generating, setGenerating] = useState(false);
+  const [progress, setProgress] = useState<string>();
 
   useEffect(() => {
     form.setFieldsValue({
@@ -37,17 +38,52 @@ function BlueprintPanel({ project }: BlueprintPanelProps) {
     },
   });
 
+  // 提示解析结果，列出未能解析的章节
+  const notifySync = (result: BlueprintSyncResult) => {
+    queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
+    if (result.unparsed.length === 0) {
+      message.success(`已解析 ${result.parsed} 章（新建 ${result.created}，更新 ${result.updated}）`);
+      return;
+    }
+    modal.warning({
+      title: `已解析 ${result.parsed} 章，${result.unparsed.length} 处未能解析`,
+      centered: true,
+      content: (
+        <ul style={{ paddingLeft: 20, maxHeight: 300, overflow: 'auto' }}>
+          {result.unparsed.map((issue, i) => (
+            <li key={i}>
+              {issue.chapter_number ? `第 ${issue.chapter_number} 章` : `第 ${issue.line} 行`}：{issue.reason}
+            </li>
+          ))}
+        </ul>
+      ),
+    });
+  };
+
+  const syncMutation = useMutation({
+    mutationFn: () => projectApi.syncBlueprint(project.id),
+    onSuccess: (res) => notifySync(res.data),
+    onError: (error: Error) => {
+      message.error(error.message || '解析失败');
+    },
+  });
+
   const generateMutation = useMutation({
-    mutationFn: (overwrite: boolean) =>
-      projectApi.generateBlueprint(project.id, { overwrite }),
-    onSuccess: () => {
+    mutationFn: async (overwrite: boolean) => {
+      const res = await projectApi.generateBlueprint(project.id, { overwrite });
+      return waitForJob(res.data, (job) => setProgress(formatJobProgress(job)));
+    },
+    onSuccess: (result) => {
       message.success('大纲生成成功');
       queryClient.invalidateQueries({ queryKey: ['project', project.id] });
+      notifySync(result.chapters);
       setGenerating(false);
+      setProgress(undefined);
     },
     onError: () => {
       message.error('大纲生成失败');
       setGenerating(false);
+      setProgress(undefined);
     },
   });
 
@@ -102,7 +138,16 @@ function BlueprintPanel({ project }: BlueprintPanelProps) {
             loading={generating || generateMutation.isPending}
             size="large"
           >
-            {project.blueprint_generated ? '重新生成' : 'AI 一键生成大纲'}
+            {progress ?? (project.blueprint_generated ? '重新生成' : 'AI 一键生成大纲')}
+          </Button>
+          <Button
+            icon={<SyncOutlined />}
+            onClick={() => syncMutation.mutate()}
+            loading={syncMutation.isPending}
+            disabled={!project.chapter_blueprint || generating}
+            size="large"
+          >
+            解析到章节
           </Button>
           <Button
             type="primary"
@@ -0,0 +1,186 @@
+import { useEffect, useState } from 'react';
+import { Modal, Form, InputNumber, Checkbox, Space, Progress, List, Tag, Typography, Alert, Button, Tooltip, App } from 'antd';
+import { useQuery, useQueryClient } from '@tanstack/react-query';
+import { chapterApi, jobApi, waitForJob, formatJobProgress } from '../../api';
+import type { ChapterBatchItem, ChapterBatchResult, GenerateChapterBatchRequest, Job, Project } from '../../types';
+
+const { Text } = Typography;
+
+interface ChapterBatchModalProps {
+  project: Project;
+  open: boolean;
+  onClose: () => void;
+}
+
+const statusTags: Record<ChapterBatchItem['status'], { color: string; text: string }> = {
+  pending: { color: 'default', text: '待生成' },
+  succeeded: { color: 'success', text: '已生成' },
+  skipped: { color: 'warning', text: '已跳过' },
+  failed: { color: 'error', text: '失败' },
+};
+
+function ChapterBatchModal({ project, open, onClose }: ChapterBatchModalProps) {
+  const { message } = App.useApp();
+  const queryClient = useQueryClient();
+  const [form] = Form.useForm<GenerateChapterBatchRequest>();
+  const [job, setJob] = useState<Job<ChapterBatchResult>>();
+  const [running, setRunning] = useState(false);
+
+  // 打开时加载最近一次批量生成，仍在执行时继续跟踪进度
+  const { data: latest } = useQuery({
+    queryKey: ['chapter-batch', project.id],
+    queryFn: () => chapterApi.getBatch(project.id).then((res) => res.data).catch(() => null),
+    enabled: open,
+  });
+
+  useEffect(() => {
+    if (latest && !job) {
+      setJob(latest);
+      if (latest.status === 'pending' || latest.status === 'running') {
+        track(latest);
+      }
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [latest]);
+
+  const track = async (submitted: Job<ChapterBatchResult>) => {
+    setRunning(true);
+    setJob(submitted);
+    try {
+      await waitForJob(submitted, (current) => {
+        setJob(current);
+        queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
+      });
+      message.success('批量生成完成');
+    } catch (err) {
+      message.error((err as Error).message || '批量生成失败');
+    } finally {
+      const res = await chapterApi.getBatch(project.id).catch(() => null);
+      if (res?.data) setJob(res.data);
+      setRunning(false);
+      queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
+      queryClient.invalidateQueries({ queryKey: ['project', project.id] });
+    }
+  };
+
+  const handleStart = async () => {
+    const values = await form.validateFields();
+    try {
+      const res = await chapterApi.generateBatch(project.id, values);
+      track(res.data);
+    } catch (err: any) {
+      message.error(err?.response?.data?.message || '提交失败');
+    }
+  };
+
+  const handleResume = async () => {
+    try {
+      const res = await chapterApi.resumeBatch(project.id);
+      track(res.data);
+    } catch (err: any) {
+      message.error(err?.response?.data?.message || '继续失败');
+    }
+  };
+
+  const handleCancel = async () => {
+    if (job) {
+      await jobApi.cancel(job.id).catch(() => null);
+    }
+  };
+
+  const result = job?.result;
+  const processed = result ? result.succeeded + result.skipped + result.failed : 0;
+  const resumable =
+    !running && !!job && (job.status === 'failed' || job.status === 'canceled' || (result?.failed ?? 0) > 0);
+
+  return (
+    <Modal
+      title="批量生成章节"
+      open={open}
+      onCancel={onClose}
+      width={560}
+      centered
+      footer={
+        <Space>
+          {running && (
+            <Button danger onClick={handleCancel}>
+              停止
+            </Button>
+          )}
+          {resumable && <Button onClick={handleResume}>继续上一次</Button>}
+          <Button type="primary" onClick={handleStart} loading={running}>
+            {running ? formatJobProgress(job!) : '开始生成'}
+          </Button>
+        </Space>
+      }
+    >
+      <Form
+        form={form}
+        layout="inline"
+        initialValues={{ from: 1, to: Math.min(project.chapter_count, 10), skip_existing: true }}
+        style={{ marginBottom: 12 }}
+        disabled={running}
+      >
+        <Form.Item name="from" label="从第" rules={[{ required: true }]}>
+          <InputNumber min={1} max={project.chapter_count} />
+        </Form.Item>
+        <Form.Item name="to" label="到第" rules={[{ required: true }]}>
+          <InputNumber min={1} max={project.chapter_count} />
+        </Form.Item>
+        <Form.Item name="skip_existing" valuePropName="checked">
+          <Checkbox>跳过已有内容的章节</Checkbox>
+        </Form.Item>
+        <Form.Item name="overwrite" valuePropName="checked">
+          <Checkbox>覆盖已有内容</Checkbox>
+        </Form.Item>
+        <Form.Item name="stop_on_error" valuePropName="checked">
+          <Checkbox>出错时停止</Checkbox>
+        </Form.Item>
+        <Form.Item name="keep_draft" valuePropName="checked">
+          <Checkbox>保留为草稿（不定稿、不更新摘要）</Checkbox>
+        </Form.Item>
+      </Form>
+
+      {job?.error && <Alert type="error" showIcon message={job.error} style={{ marginBottom: 12 }} />}
+
+      {result && (
+        <>
+          <Progress percent={Math.round((processed / result.chapters.length) * 100)} size="small" />
+          <Text type="secondary" style={{ fontSize: 12 }}>
+            第 {result.from}-{result.to} 章：已生成 {result.succeeded}，跳过 {result.skipped}，失败 {result.failed}
+          </Text>
+          <List
+            size="small"
+            style={{ maxHeight: 280, overflow: 'auto', marginTop: 8 }}
+            dataSource={result.chapters}
+            renderItem={(item) => (
+              <List.Item>
+                <Space>
+                  <Text>第 {item.chapter_number} 章</Text>
+                  <Tag color={statusTags[item.status].color} bordered={false}>
+                    {statusTags[item.status].text}
+                  </Tag>
+                  {item.word_count ? <Text type="secondary">{item.word_count} 字</Text> : null}
+                  {item.warnings?.length ? (
+                    <Tooltip title={item.warnings.join('；')}>
+                      <Tag color="gold" bordered={false}>
+                        提示
+                      </Tag>
+                    </Tooltip>
+                  ) : null}
+                </Space>
+                {item.error && (
+                  <Text type="danger" ellipsis style={{ maxWidth: 260 }}>
+                    {item.error}
+                  </Text>
+                )}
+              </List.Item>
+            )}
+          />
+        </>
+      )}
+    </Modal>
+  );
+}
+
+export default ChapterBatchModal;
@@ -0,0 +1,314 @@
+import { useEffect, useState } from 'react';
+import {
+  Modal, Form, InputNumber, Select, Space, Button, List, Tag, Typography, Checkbox, Alert, Empty, Flex, Spin,
+  Popconfirm, App, theme,
+} from 'antd';
+import { useQuery, useQueryClient } from '@tanstack/react-query';
+import { chapterApi, jobApi, modelConfigApi, waitForJob, formatJobProgress } from '../../api';
+import type { Chapter, ChapterDraft, ChapterDraftsResult, GenerateChapterDraftsRequest, Job, ModelConfig, Project } from '../../types';
+
+const { Text, Paragraph } = Typography;
+
+// 比较时表示章节当前正文
+const CURRENT = 'current';
+
+interface ChapterDraftsModalProps {
+  project: Project;
+  chapter: Chapter;
+  open: boolean;
+  onClose: () => void;
+  onPromoted: (chapter: Chapter) => void;
+}
+
+const sourceTags: Record<ChapterDraft['source'], { color: string; text: string }> = {
+  generated: { color: 'blue', text: '候选稿' },
+  replaced: { color: 'default', text: '原正文' },
+  current: { color: 'green', text: '当前正文' },
+};
+
+function ChapterDraftsModal({ project, chapter, open, onClose, onPromoted }: ChapterDraftsModalProps) {
+  const { message } = App.useApp();
+  const { token } = theme.useToken();
+  const queryClient = useQueryClient();
+  const [form] = Form.useForm<GenerateChapterDraftsRequest>();
+  const [job, setJob] = useState<Job<ChapterDraftsResult>>();
+  const [running, setRunning] = useState(false);
+  const [selected, setSelected] = useState<string[]>([]);
+  const [promoting, setPromoting] = useState<string>();
+
+  const chapterNumber = chapter.chapter_number;
+  const queryKey = ['chapter-drafts', project.id, chapterNumber];
+
+  const { data: drafts = [], isLoading } = useQuery({
+    queryKey,
+    queryFn: () => chapterApi.listDrafts(project.id, chapterNumber).then((res) => res.data || []),
+    enabled: open,
+  });
+
+  const { data: configsRes } = useQuery({
+    queryKey: ['model-configs'],
+    queryFn: () => modelConfigApi.list({ page: 1, page_size: 100 }).then((res) => res.data),
+    enabled: open,
+  });
+  const configOptions = (configsRes?.configs || [])
+    .filter((c: ModelConfig) => c.is_active)
+    .map((c: ModelConfig) => ({
+      label: `${c.provider?.display_name || '未知'} / ${c.model_name}`,
+      value: c.id,
+    }));
+
+  const { data: compare, isFetching: comparing } = useQuery({
+    queryKey: ['chapter-drafts-compare', project.id, chapterNumber, selected],
+    queryFn: () => chapterApi.compareDrafts(project.id, chapterNumber, selected).then((res) => res.data),
+    enabled: open && selected.length >= 2,
+  });
+
+  // 切换章节时清空选择与上一次任务
+  useEffect(() => {
+    setSelected([]);
+    setJob(undefined);
+  }, [chapterNumber]);
+
+  const toggle = (id: string, checked: boolean) => {
+    setSelected((prev) => (checked ? [...prev, id].slice(-3) : prev.filter((s) => s !== id)));
+  };
+
+  const handleGenerate = async () => {
+    const values = await form.validateFields();
+    setRunning(true);
+    try {
+      const res = await chapterApi.generateDrafts(project.id, chapterNumber, values);
+      setJob(res.data);
+      await waitForJob(res.data, (current) => {
+        setJob(current);
+        queryClient.invalidateQueries({ queryKey });
+      });
+      message.success('候选稿生成完成');
+    } catch (err: any) {
+      message.error(err?.response?.data?.message || (err as Error).message || '生成候选稿失败');
+    } finally {
+      setRunning(false);
+      queryClient.invalidateQueries({ queryKey });
+    }
+  };
+
+  const handleCancel = async () => {
+    if (job) {
+      await jobApi.cancel(job.id).catch(() => null);
+    }
+  };
+
+  const handlePromote = async (draft: ChapterDraft) => {
+    setPromoting(draft.id);
+    try {
+      const res = await chapterApi.promoteDraft(project.id, chapterNumber, draft.id);
+      message.success('已选用候选稿');
+      setSelected([]);
+      queryClient.invalidateQueries({ queryKey });
+      queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
+      queryClient.invalidateQueries({ queryKey: ['project', project.id] });
+      if (res?.data) onPromoted(res.data);
+    } catch (err: any) {
+      message.error(err?.response?.data?.message || '选用候选稿失败');
+    } finally {
+      setPromoting(undefined);
+    }
+  };
+
+  const handleDelete = async (draft: ChapterDraft) => {
+    try {
+      await chapterApi.deleteDraft(project.id, chapterNumber, draft.id);
+      setSelected((prev) => prev.filter((s) => s !== draft.id));
+      queryClient.invalidateQueries({ queryKey });
+    } catch (err: any) {
+      message.error(err?.response?.data?.message || '删除候选稿失败');
+    }
+  };
+
+  const lineStyles = {
+    equal: { color: token.colorTextSecondary },
+    add: { background: token.colorSuccessBg, color: token.colorSuccessText },
+    remove: { background: token.colorErrorBg, color: token.colorErrorText, textDecoration: 'line-through' },
+  };
+
+  const draftLabel = (draft: ChapterDraft) =>
+    draft.id === CURRENT ? '当前正文' : `${sourceTags[draft.source].text}${draft.model ? ` · ${draft.model}` : ''}`;
+
+  return (
+    <Modal
+      title={`第 ${chapterNumber} 章候选稿`}
+      open={open}
+      onCancel={onClose}
+      footer={null}
+      width={1080}
+      centered
+    >
+      <Form form={form} layout="inline" style={{ marginBottom: 12 }} disabled={running}>
+        <Form.Item name="count" label="数量">
+          <InputNumber min={1} max={5} placeholder="默认" />
+        </Form.Item>
+        <Form.Item name="model_config_ids" label="模型" style={{ flex: 1 }}>
+          <Select
+            mode="multiple"
+            allowClear
+            maxCount={5}
+            placeholder="默认使用章节生成绑定的模型，多选时依次轮流使用"
+            options={configOptions}
+          />
+        </Form.Item>
+        <Space>
+          {running && (
+            <Button danger onClick={handleCancel} disabled={false}>
+              停止
+            </Button>
+          )}
+          <Button type="primary" onClick={handleGenerate} loading={running}>
+            {running && job ? formatJobProgress(job) : '生成候选稿'}
+          </Button>
+        </Space>
+      </Form>
+
+      {job?.result?.drafts
+        .filter((item) => item.status === 'failed')
+        .map((item) => (
+          <Alert
+            key={item.index}
+            type="warning"
+            showIcon
+            message={`第 ${item.index} 个候选稿生成失败：${item.error}`}
+            style={{ marginBottom: 8 }}
+          />
+        ))}
+
+      <Flex gap={16} style={{ height: 520 }}>
+        <div style={{ width: 320, overflow: 'auto' }}>
+          {isLoading ? (
+            <Spin />
+          ) : drafts.length === 0 ? (
+            <Empty description="还没有候选稿" />
+          ) : (
+            <List
+              size="small"
+              dataSource={drafts}
+              header={
+                chapter.content ? (
+                  <Checkbox
+                    checked={selected.includes(CURRENT)}
+                    onChange={(e) => toggle(CURRENT, e.target.checked)}
+                  >
+                    当前正文（{chapter.word_count} 字）
+                  </Checkbox>
+                ) : undefined
+              }
+              renderItem={(draft) => (
+                <List.Item
+                  actions={[
+                    <Button
+                      key="promote"
+                      type="link"
+                      size="small"
+                      disabled={draft.current}
+                      loading={promoting === draft.id}
+                      onClick={() => handlePromote(draft)}
+                    >
+                      选用
+                    </Button>,
+                    <Popconfirm key="delete" title="确定删除这个候选稿吗？" onConfirm={() => handleDelete(draft)}>
+                      <Button type="link" size="small" danger>
+                        删除
+                      </Button>
+                    </Popconfirm>,
+                  ]}
+                >
+                  <Checkbox
+                    checked={selected.includes(draft.id)}
+                    onChange={(e) => toggle(draft.id, e.target.checked)}
+                    style={{ marginRight: 8 }}
+                  />
+                  <List.Item.Meta
+                    title={
+                      <Space size={4} wrap>
+                        <Tag color={sourceTags[draft.source].color} bordered={false}>
+                          {sourceTags[draft.source].text}
+                        </Tag>
+                        {draft.current && (
+                          <Tag color="green" bordered={false}>
+                            当前
+                          </Tag>
+                        )}
+                        {draft.synthetic && (
+                          <Tag color="gold" bordered={false}>
+                            模拟
+                          </Tag>
+                        )}
+                        <Text type="secondary" style={{ fontSize: 12 }}>
+                          {draft.word_count} 字 · {draft.paragraphs} 段
+                        </Text>
+                      </Space>
+                    }
+                    description={
+                      <>
+                        {draft.model && <Text type="secondary" style={{ fontSize: 12 }}>{draft.model}</Text>}
+                        <Paragraph type="secondary" ellipsis={{ rows: 2 }} style={{ fontSize: 12, marginBottom: 0 }}>
+                          {draft.preview}
+                        </Paragraph>
+                      </>
+                    }
+                  />
+                </List.Item>
+              )}
+            />
+          )}
+        </div>
+
+        <div style={{ flex: 1, overflow: 'auto', minWidth: 0 }}>
+          {selected.length < 2 ? (
+            <Empty description="勾选两到三个版本进行比较" />
+          ) : (
+            <Spin spinning={comparing}>
+              {compare?.diff ? (
+                <>
+                  <Flex gap={8} align="center" style={{ marginBottom: 8 }}>
+                    <Tag color="success" bordered={false}>
+                      +{compare.diff.added}
+                    </Tag>
+                    <Tag color="error" bordered={false}>
+                      -{compare.diff.removed}
+                    </Tag>
+                    <Text type="secondary" style={{ fontSize: 12 }}>
+                      {draftLabel(compare.drafts[1])} 相对 {draftLabel(compare.drafts[0])}
+                    </Text>
+                  </Flex>
+                  <pre style={{ fontSize: 13, lineHeight: 1.7, margin: 0, whiteSpace: 'pre-wrap' }}>
+                    {compare.diff.lines.map((line, i) => (
+                      <div key={i} style={lineStyles[line.op]}>
+                        {line.op === 'add' ? '+ ' : line.op === 'remove' ? '- ' : '  '}
+                        {line.text}
+                      </div>
+                    ))}
+                  </pre>
+                </>
+              ) : (
+                <Flex gap={12}>
+                  {compare?.drafts.map((draft) => (
+                    <div key={draft.id} style={{ flex: 1, minWidth: 0 }}>
+                      <Text strong>{draftLabel(draft)}</Text>
+                      <Text type="secondary" style={{ fontSize: 12, marginLeft: 8 }}>
+                        {draft.word_count} 字
+                      </Text>
+                      <pre style={{ fontSize: 13, lineHeight: 1.7, whiteSpace: 'pre-wrap', marginTop: 8 }}>
+                        {draft.content}
+                      </pre>
+                    </div>
+                  ))}
+                </Flex>
+              )}
+            </Spin>
+          )}
+        </div>
+      </Flex>
+    </Modal>
+  );
+}
+
+export default ChapterDraftsModal;
@@ -1,19 +1,21 @@
 import { useState, useRef } from 'react';
 import {
   Button, Card, Tag, Modal, InputNumber, Spin, Space, Input, Form,
-  Alert, App, Pagination, Typography, Flex, Row, Col, theme, Collapse,
+  Alert, App, Pagination, Typography, Flex, Row, Col, theme, Collapse, Tooltip,
 } from 'antd';
 import {
   PlayCircleOutlined, CheckOutlined, PlusOutlined, ExpandOutlined,
   LockOutlined, EditOutlined, FileSearchOutlined, RobotOutlined,
-  MenuFoldOutlined, MenuUnfoldOutlined, BugOutlined,
+  MenuFoldOutlined, MenuUnfoldOutlined, BugOutlined, ThunderboltOutlined, DatabaseOutlined, BranchesOutlined,
 } from '@ant-design/icons';
 import { useNavigate } from 'react-router-dom';
-import { chapterApi } from '../../api';
+import { chapterApi, waitForJob } from '../../api';
 import type { Project, Chapter } from '../../types';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import WritingAssistant from './WritingAssistant';
 import ErrorDetectionPanel from './ErrorDetectionPanel';
+import ChapterBatchModal from './ChapterBatchModal';
+import ChapterDraftsModal from './ChapterDraftsModal';
 import RichEditor, { type RichEditorRef, type ErrorMark } from '../common/RichEditor';
 import type { DetectionIssue } from '../../types';
 
@@ -33,6 +35,8 @@ function ChapterPanel({ project }: ChapterPanelProps) {
   const [selectedChapter, setSelectedChapter] = useState<Chapter | null>(null);
   const [detailModalOpen, setDetailModalOpen] = useState(false);
   const [createModalOpen, setCreateModalOpen] = useState(false);
+  const [batchModalOpen, setBatchModalOpen] = useState(false);
+  const [draftsModalOpen, setDraftsModalOpen] = useState(false);
   const [assistantOpen, setAssistantOpen] = useState(false);
   const [detectionOpen, setDetectionOpen] = useState(false);
   const [editorContent, setEditorContent] = useState('');
@@ -53,6 +57,27 @@ function ChapterPanel({ project }: ChapterPanelProps) {
     enabled: !!project.id,
   });
 
+  const { data: retrieval } = useQuery({
+    queryKey: ['retrieval', project.id],
+    queryFn: () => chapterApi.retrievalStatus(project.id).then((res) => res.data),
+    enabled: !!project.id,
+  });
+
+  const reindexMutation = useMutation({
+    mutationFn: () => chapterApi.reindexRetrieval(project.id),
+    onSuccess: (res) => {
+      const result = res.data;
+      message.success(`已索引 ${result.chapters} 章，共 ${result.chunks} 个片段`);
+      if (result.failed.length > 0) {
+        message.warning(`第 ${result.failed.join('、')} 章索引失败`);
+      }
+      queryClient.invalidateQueries({ queryKey: ['retrieval', project.id] });
+    },
+    onError: (err: any) => {
+      message.error(err?.response?.data?.message || '重建索引失败');
+    },
+  });
+
   const chapters = chaptersData?.chapters || [];
   const total = chaptersData?.total || 0;
 
@@ -83,10 +108,13 @@ function ChapterPanel({ project }: ChapterPanelProps) {
   });
 
   const generateMutation = useMutation({
-    mutationFn: (chapterNumber: number) =>
-      chapterApi.generateContent(project.id, chapterNumber, { overwrite: false }),
-    onSuccess: () => {
+    mutationFn: async (chapterNumber: number) => {
+      const res = await chapterApi.generateContent(project.id, chapterNumber, { overwrite: false });
+      return waitForJob(res.data);
+    },
+    onSuccess: (chapter) => {
       message.success('章节内容生成成功');
+      chapter?.warnings?.forEach((warning) => message.warning(warning));
       queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
       if (selectedChapter) {
         chapterApi.getByNumber(project.id, selectedChapter.chapter_number).then((res) => {
@@ -128,10 +156,13 @@ function ChapterPanel({ project }: ChapterPanelProps) {
 
   const finalizeMutation = useMutation({
     mutationFn: (chapterNumber: number) =>
-      chapterApi.finalize(project.id, chapterNumber, { update_summary: true }),
+      chapterApi.finalize(project.id, chapterNumber, { update_summary: true, update_character_state: true }),
     onSuccess: () => {
       message.success('章节定稿成功');
       queryClient.invalidateQueries({ queryKey: ['chapters', project.id] });
+      queryClient.invalidateQueries({ queryKey: ['project', project.id] });
+      queryClient.invalidateQueries({ queryKey: ['character-states', project.id] });
+      queryClient.invalidateQueries({ queryKey: ['retrieval', project.id] });
       if (selectedChapter) {
         chapterApi.getByNumber(project.id, selectedChapter.chapter_number).then((res) => {
           if (res?.data) {
@@ -231,16 +262,43 @@ function ChapterPanel({ project }: ChapterPanelProps) {
             总字数：<Text strong>{(project.total_words || 0).toLocaleString()}</Text> 字
           </Text>
         </div>
-        <Button
-          type="primary"
-          icon={<PlusOutlined />}
-          onClick={() => setCreateModalOpen(true)}
-          size="large"
-        >
-          创建章节
-        </Button>
+        <Space>
+          {retrieval?.enabled && (
+            <Tooltip
+              title={`前文检索：已索引 ${retrieval.indexed_chapters.length} 章${
+                retrieval.missing_chapters.length > 0 ? `，${retrieval.missing_chapters.length} 章待索引` : ''
+              }（${retrieval.store === 'memory' ? '进程内索引，重启后需重建' : 'pgvector'}）`}
+            >
+              <Button
+                icon={<DatabaseOutlined />}
+                onClick={() => reindexMutation.mutate()}
+                loading={reindexMutation.isPending}
+                size="large"
+              >
+                重建索引
+              </Button>
+            </Tooltip>
+          )}
+          <Button
+            icon={<ThunderboltOutlined />}
+            onClick={() => setBatchModalOpen(true)}
+            size="large"
+          >
+            批量生成
+          </Button>
+          <Button
+            type="primary"
+            icon={<PlusOutlined />}
+            onClick={() => setCreateModalOpen(true)}
+            size="large"
+          >
+            创建章节
+          </Button>
+        </Space>
       </Flex>
 
+      <ChapterBatchModal project={project} open={batchModalOpen} onClose={() => setBatchModalOpen(false)} />
+
       {isLoading ? (
         <Flex justify="center" align="center" style={{ height: 160 }}>
           <Spin size="large" />
@@ -403,6 +461,9 @@ function ChapterPanel({ project }: ChapterPanelProps) {
             {selectedChapter?.is_finalized && (
               <Tag icon={<LockOutlined />} color="success" bordered={false}>已定稿</Tag>
             )}
+            {selectedChapter?.synthetic && (
+              <Tag color="warning" bordered={false}>模拟输出</Tag>
+            )}
           </Flex>
         }
         open={detailModalOpen}
@@ -475,6 +536,9 @@ function ChapterPanel({ project }: ChapterPanelProps) {
                     )}
                   </>
                 )}
+                <Button icon={<BranchesOutlined />} onClick={() => setDraftsModalOpen(true)}>
+                  候选稿
+                </Button>
                 <Button
                   type={detectionOpen ? 'primary' : 'default'}
                   icon={<BugOutlined />}
@@ -638,6 +702,19 @@ function ChapterPanel({ project }: ChapterPanelProps) {
           </Flex>
         )}
       </Modal>
+
+      {selectedChapter && (
+        <ChapterDraftsModal
+          project={project}
+          chapter={selectedChapter}
+          open={draftsModalOpen}
+          onClose={() => setDraftsModalOpen(false)}
+          onPromoted={(chapter) => {
+            setSelectedChapter(chapter);
+            setEditorContent(chapter.content || '');
+          }}
+        />
+      )}
     </div>
   );
 }
@@ -0,0 +1,105 @@
+import { useEffect, useState } from 'react';
+import { Modal, Flex, List, Typography, Tag, Empty, Spin, theme } from 'antd';
+import { useQuery } from '@tanstack/react-query';
+import { chapterApi } from '../../api';
+
+const { Text } = Typography;
+
+interface CharacterStateHistoryModalProps {
+  projectId: string;
+  open: boolean;
+  onClose: () => void;
+}
+
+function CharacterStateHistoryModal({ projectId, open, onClose }: CharacterStateHistoryModalProps) {
+  const { token } = theme.useToken();
+  const [selected, setSelected] = useState<number>();
+
+  const { data: versions = [], isLoading } = useQuery({
+    queryKey: ['character-states', projectId],
+    queryFn: () => chapterApi.listCharacterStates(projectId).then((res) => res.data),
+    enabled: open,
+  });
+
+  // 默认查看最新一次更新
+  useEffect(() => {
+    if (open && versions.length > 0 && selected === undefined) {
+      setSelected(versions[versions.length - 1].chapter_number);
+    }
+  }, [open, versions, selected]);
+
+  const { data: diff, isFetching } = useQuery({
+    queryKey: ['character-state-diff', projectId, selected],
+    queryFn: () => chapterApi.diffCharacterState(projectId, selected!).then((res) => res.data),
+    enabled: open && selected !== undefined,
+  });
+
+  const lineStyles = {
+    equal: { color: token.colorTextSecondary },
+    add: { background: token.colorSuccessBg, color: token.colorSuccessText },
+    remove: { background: token.colorErrorBg, color: token.colorErrorText, textDecoration: 'line-through' },
+  };
+
+  return (
+    <Modal title="角色状态历史" open={open} onCancel={onClose} footer={null} width={880} centered>
+      {isLoading ? (
+        <Spin />
+      ) : versions.length === 0 ? (
+        <Empty description="定稿章节时更新角色状态后，这里会记录每一章之后的角色状态" />
+      ) : (
+        <Flex gap={16} style={{ height: 520 }}>
+          <List
+            size="small"
+            style={{ width: 160, overflow: 'auto' }}
+            dataSource={[...versions].reverse()}
+            renderItem={(version) => (
+              <List.Item
+                onClick={() => setSelected(version.chapter_number)}
+                style={{
+                  cursor: 'pointer',
+                  background: version.chapter_number === selected ? token.colorPrimaryBg : undefined,
+                  paddingInline: 8,
+                }}
+              >
+                <Text>{version.chapter_number === 0 ? '初始状态' : `第 ${version.chapter_number} 章后`}</Text>
+              </List.Item>
+            )}
+          />
+          <div style={{ flex: 1, overflow: 'auto' }}>
+            <Spin spinning={isFetching}>
+              {diff && (
+                <>
+                  <Flex gap={8} style={{ marginBottom: 8 }}>
+                    <Tag color="success" bordered={false}>
+                      +{diff.added}
+                    </Tag>
+                    <Tag color="error" bordered={false}>
+                      -{diff.removed}
+                    </Tag>
+                    <Text type="secondary" style={{ fontSize: 12 }}>
+                      {diff.previous_chapter_number === null
+                        ? '没有上一版本'
+                        : diff.previous_chapter_number === 0
+                          ? '相对初始状态'
+                          : `相对第 ${diff.previous_chapter_number} 章后`}
+                    </Text>
+                  </Flex>
+                  <pre style={{ fontSize: 13, lineHeight: 1.7, margin: 0, whiteSpace: 'pre-wrap' }}>
+                    {diff.lines.map((line, i) => (
+                      <div key={i} style={lineStyles[line.op]}>
+                        {line.op === 'add' ? '+ ' : line.op === 'remove' ? '- ' : '  '}
+                        {line.text}
+                      </div>
+                    ))}
+                  </pre>
+                </>
+              )}
+            </Spin>
+          </div>
+        </Flex>
+      )}
+    </Modal>
+  );
+}
+
+export default CharacterStateHistoryModal;
@@ -11,7 +11,7 @@ import {
 } from '@ant-design/icons';
 import ReactECharts from 'echarts-for-react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
-import { graphApi } from '../../api';
+import { graphApi, waitForJob } from '../../api';
 import type { Project, GraphData, GraphNode, GraphEdge } from '../../types';
 
 const { Title, Text } = Typography;
@@ -49,7 +49,10 @@ function GraphPanel({ project }: GraphPanelProps) {
   });
 
   const generateMutation = useMutation({
-    mutationFn: () => graphApi.generate(project.id),
+    mutationFn: async () => {
+      const res = await graphApi.generate(project.id);
+      return waitForJob(res.data);
+    },
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: ['graph', project.id] });
       messageApi.success('关系图谱生成成功');
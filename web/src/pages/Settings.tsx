@@ -2,7 +2,7 @@ import { useState, useRef } from 'react';
 import {
   Form, Input, Select, Button, Table, Modal, Space, Tag, Switch,
   Popconfirm, App, Tabs, Typography, Card, Flex, theme, Statistic,
-  Upload, Spin, Result, Divider,
+  Upload, Spin, Result, Divider, InputNumber,
 } from 'antd';
 import {
   PlusOutlined,
@@ -23,7 +23,7 @@ import {
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { modelConfigApi, backupApi } from '../api';
 import { useAppStore } from '../stores';
-import type { ModelConfig, ModelProvider, ModelBinding, CreateModelConfigRequest, BindingPurpose, ImportResult } from '../types';
+import type { ModelConfig, ModelProvider, ModelBinding, CreateModelConfigRequest, BindingPurpose, ImportResult, UpsertModelBindingRequest, GenerationParams } from '../types';
 
 const { Title, Text } = Typography;
 
@@ -33,6 +33,7 @@ const BINDING_PURPOSES: { key: BindingPurpose; label: string; description: strin
   { key: 'writing', label: '写作助手', description: '文本润色、续写、建议等辅助功能' },
   { key: 'review', label: 'AI 审阅', description: '错误检测、质量评审、市场预测' },
   { key: 'general', label: '通用 / 对话', description: '灵感对话等通用 AI 交互' },
+  { key: 'embedding', label: '向量嵌入', description: '前文检索使用的嵌入模型，需选择 embedding 模型（如 text-embedding-3-small）' },
 ];
 
 const MODEL_OPTIONS: Record<string, string[]> = {
@@ -41,6 +42,8 @@ const MODEL_OPTIONS: Record<string, string[]> = {
   deepseek: ['deepseek-chat', 'deepseek-coder'],
   qwen: ['qwen-max', 'qwen-plus', 'qwen-turbo'],
   zhipu: ['glm-4', 'glm-4-flash', 'glm-3-turbo'],
+  gemini: ['gemini-2.0-flash', 'gemini-1.5-pro', 'gemini-1.5-flash'],
+  ollama: ['qwen2.5:7b', 'llama3.1:8b', 'deepseek-r1:7b'],
   custom: [],
 };
 
@@ -53,6 +56,8 @@ function Settings() {
   const [editingConfig, setEditingConfig] = useState<ModelConfig | null>(null);
   const [form] = Form.useForm();
   const [selectedProvider, setSelectedProvider] = useState<number | null>(null);
+  const [paramsBinding, setParamsBinding] = useState<ModelBinding | null>(null);
+  const [paramsForm] = Form.useForm<GenerationParams>();
 
   const { data: providersRes } = useQuery({
     queryKey: ['model-providers'],
@@ -122,8 +127,7 @@ function Settings() {
   });
 
   const bindingMutation = useMutation({
-    mutationFn: (data: { purpose: BindingPurpose; model_config_id: string }) =>
-      modelConfigApi.upsertBinding(data),
+    mutationFn: (data: UpsertModelBindingRequest) => modelConfigApi.upsertBinding(data),
     onSuccess: () => {
       message.success('绑定已更新');
       queryClient.invalidateQueries({ queryKey: ['model-bindings'] });
@@ -165,7 +169,7 @@ function Settings() {
 
   const handleValidate = () => {
     const values = form.getFieldsValue();
-    if (!values.provider_id || !values.api_key) {
+    if (!values.provider_id || (!values.api_key && !apiKeyOptional)) {
       message.warning('请先填写提供商和 API Key');
       return;
     }
@@ -173,6 +177,27 @@ function Settings() {
       provider_id: values.provider_id,
       api_key: values.api_key,
       base_url: values.base_url,
+      model_name: values.model_name,
+    });
+  };
+
+  const openParams = (binding: ModelBinding) => {
+    setParamsBinding(binding);
+    paramsForm.resetFields();
+    paramsForm.setFieldsValue(binding.params || {});
+  };
+
+  const handleSaveParams = () => {
+    if (!paramsBinding) return;
+    paramsForm.validateFields().then((values) => {
+      // 清空的字段不提交，使用功能默认值
+      const params = Object.fromEntries(
+        Object.entries(values).filter(([, v]) => v !== null && v !== undefined && !(Array.isArray(v) && v.length === 0)),
+      ) as GenerationParams;
+      bindingMutation.mutate(
+        { purpose: paramsBinding.purpose, model_config_id: paramsBinding.model_config_id, params },
+        { onSuccess: () => setParamsBinding(null) },
+      );
     });
   };
 
@@ -206,13 +231,17 @@ function Settings() {
       title: '状态',
       dataIndex: 'is_active',
       key: 'is_active',
-      render: (isActive: boolean) =>
-        isActive ? (
+      render: (isActive: boolean, record: ModelConfig) =>
+        !isActive ? (
+          <Tag color="default" bordered={false}>已禁用</Tag>
+        ) : record.health?.degraded ? (
+          <Tag color="warning" bordered={false} title={record.health.last_error}>
+            服务降级
+          </Tag>
+        ) : (
           <Tag icon={<CheckCircleOutlined />} color="success" bordered={false}>
             已启用
           </Tag>
-        ) : (
-          <Tag color="default" bordered={false}>已禁用</Tag>
         ),
     },
     {
@@ -240,7 +269,9 @@ function Settings() {
     },
   ];
 
-  const selectedProviderName = providers.find((p: ModelProvider) => p.id === selectedProvider)?.name;
+  const selectedProviderInfo = providers.find((p: ModelProvider) => p.id === selectedProvider);
+  const selectedProviderName = selectedProviderInfo?.name;
+  const apiKeyOptional = selectedProviderInfo?.auth_type === 'none';
   const modelOptions = selectedProviderName ? MODEL_OPTIONS[selectedProviderName] || [] : [];
 
   const tabItems = [
@@ -358,6 +389,30 @@ function Settings() {
                         </Text>
                       }
                     />
+                    <Select
+                      mode="multiple"
+                      style={{ width: 280 }}
+                      placeholder="备用模型（按顺序故障切换）"
+                      disabled={!binding}
+                      value={binding?.fallback_config_ids}
+                      options={configSelectOptions.filter((o) => o.value !== binding?.model_config_id)}
+                      onChange={(values: string[]) => {
+                        if (binding) {
+                          bindingMutation.mutate({
+                            purpose: key,
+                            model_config_id: binding.model_config_id,
+                            fallback_config_ids: values,
+                          });
+                        }
+                      }}
+                    />
+                    <Button
+                      icon={<SettingOutlined />}
+                      disabled={!binding}
+                      onClick={() => binding && openParams(binding)}
+                    >
+                      生成参数
+                    </Button>
                   </Flex>
                 </Flex>
               );
@@ -523,7 +578,7 @@ function Settings() {
           <Form.Item
             name="api_key"
             label="API Key"
-            rules={[{ required: !editingConfig, message: '请输入 API Key' }]}
+            rules={[{ required: !editingConfig && !apiKeyOptional, message: '请输入 API Key' }]}
             extra={
               <Text type="secondary" style={{ fontSize: 12 }}>
                 {editingConfig ? '留空表示不修改现有 Key' : '提供商分配的身份验证凭证'}
@@ -556,6 +611,47 @@ function Settings() {
           )}
         </Form>
       </Modal>
+
+      {/* 生成参数弹窗 */}
+      <Modal
+        title={`生成参数 - ${BINDING_PURPOSES.find((p) => p.key === paramsBinding?.purpose)?.label || ''}`}
+        open={!!paramsBinding}
+        onCancel={() => setParamsBinding(null)}
+        onOk={handleSaveParams}
+        okText="保存"
+        cancelText="取消"
+        confirmLoading={bindingMutation.isPending}
+        forceRender
+        centered
+      >
+        <Text type="secondary" style={{ fontSize: 13 }}>
+          留空的参数使用各功能的默认值；部分提供商不支持惩罚参数，会自动忽略
+        </Text>
+        <Form form={paramsForm} layout="vertical" style={{ marginTop: 16 }}>
+          <Flex gap={16}>
+            <Form.Item name="temperature" label="Temperature" style={{ flex: 1 }}>
+              <InputNumber min={0.01} max={2} step={0.05} style={{ width: '100%' }} placeholder="默认" />
+            </Form.Item>
+            <Form.Item name="top_p" label="Top P" style={{ flex: 1 }}>
+              <InputNumber min={0.01} max={1} step={0.05} style={{ width: '100%' }} placeholder="默认" />
+            </Form.Item>
+          </Flex>
+          <Flex gap={16}>
+            <Form.Item name="presence_penalty" label="Presence Penalty" style={{ flex: 1 }}>
+              <InputNumber min={-2} max={2} step={0.1} style={{ width: '100%' }} placeholder="默认" />
+            </Form.Item>
+            <Form.Item name="frequency_penalty" label="Frequency Penalty" style={{ flex: 1 }}>
+              <InputNumber min={-2} max={2} step={0.1} style={{ width: '100%' }} placeholder="默认" />
+            </Form.Item>
+          </Flex>
+          <Form.Item name="max_tokens" label="最大输出 Token">
+            <InputNumber min={1} step={256} style={{ width: '100%' }} placeholder="默认" />
+          </Form.Item>
+          <Form.Item name="stop" label="停止序列" extra="最多 4 个，输入后回车添加">
+            <Select mode="tags" maxCount={4} placeholder="如 ###" open={false} />
+          </Form.Item>
+        </Form>
+      </Modal>
     </>
   );
 }
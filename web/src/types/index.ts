@@ -40,10 +40,13 @@ export interface Project {
   plot_architecture?: string;
   character_state?: string;
   architecture_generated: boolean;
+  architecture_synthetic?: boolean; // 由模拟提供商生成
+  architecture_stale?: ArchitectureStep[]; // 上游重新生成后需要更新的步骤
 
   // 大纲数据
   chapter_blueprint?: string;
   blueprint_generated: boolean;
+  blueprint_synthetic?: boolean; // 由模拟提供商生成
 
   // 统计
   global_summary?: string;
@@ -104,13 +107,18 @@ export interface Chapter {
   blueprint_twist_level?: string;
   blueprint_summary?: string;
 
+  summary?: string; // 定稿时根据正文生成的摘要
+
   // 内容
   content?: string;
   word_count: number;
+  synthetic?: boolean; // 由模拟提供商生成
+  partial_content?: string; // 流式生成中断时保留的部分内容
 
   // 状态
   status: 'not_started' | 'draft' | 'completed';
   is_finalized: boolean;
+  warnings?: string[]; // 本次生成的提示，如上一章尚未定稿
 
   created_at: string;
   updated_at: string;
@@ -135,6 +143,8 @@ export interface UpdateChapterRequest {
   blueprint_foreshadowing?: string;
   blueprint_twist_level?: string;
   blueprint_summary?: string;
+
+  summary?: string;
 }
 
 // 列表响应类型
@@ -163,6 +173,19 @@ export interface ModelConfig {
   created_at: string;
   updated_at: string;
   provider?: ModelProvider;
+  health?: ModelHealth;
+}
+
+// 模型配置的并发与熔断状态
+export interface ModelHealth {
+  state: 'closed' | 'open' | 'half_open';
+  degraded: boolean;
+  in_flight: number;
+  max_concurrent: number;
+  consecutive_failures: number;
+  last_error?: string;
+  opened_at?: string;
+  retry_at?: string;
 }
 
 export interface CreateModelConfigRequest {
@@ -181,18 +204,32 @@ export interface UpdateModelConfigRequest {
 
 export interface ValidateModelConfigRequest {
   provider_id: number;
-  api_key: string;
+  api_key?: string;
   base_url?: string;
+  model_name?: string;
 }
 
 // 功能绑定类型
-export type BindingPurpose = 'architecture' | 'chapter' | 'writing' | 'review' | 'general';
+export type BindingPurpose = 'architecture' | 'chapter' | 'writing' | 'review' | 'general' | 'embedding';
+
+// 生成参数，未设置的字段使用各功能的默认值
+export interface GenerationParams {
+  temperature?: number;
+  top_p?: number;
+  max_tokens?: number;
+  stop?: string[];
+  presence_penalty?: number;
+  frequency_penalty?: number;
+}
 
 export interface ModelBinding {
   id: string;
   purpose: BindingPurpose;
   model_config_id: string;
   model_config?: ModelConfig;
+  fallback_config_ids: string[];
+  params: GenerationParams;
+  chain: ModelConfig[];
   created_at: string;
   updated_at: string;
 }
@@ -200,6 +237,163 @@ export interface ModelBinding {
 export interface UpsertModelBindingRequest {
   purpose: BindingPurpose;
   model_config_id: string;
+  fallback_config_ids?: string[];
+  params?: GenerationParams;
+}
+
+// 架构生成步骤
+export type ArchitectureStep =
+  | 'core_seed'
+  | 'character_dynamics'
+  | 'world_building'
+  | 'plot_architecture'
+  | 'character_state';
+
+// 批量生成章节请求
+export interface GenerateChapterBatchRequest {
+  from: number;
+  to: number;
+  skip_existing?: boolean; // 跳过已有内容的章节
+  overwrite?: boolean; // 覆盖已有内容
+  stop_on_error?: boolean; // 遇到第一个失败的章节即停止
+  keep_draft?: boolean; // 生成后保留为草稿，不定稿、不更新摘要
+}
+
+// 批量生成中单章的结果
+export interface ChapterBatchItem {
+  chapter_number: number;
+  status: 'pending' | 'succeeded' | 'skipped' | 'failed';
+  error?: string;
+  warnings?: string[];
+  word_count?: number;
+  synthetic?: boolean;
+}
+
+// 批量生成章节的结果，执行中随每一章更新
+export interface ChapterBatchResult {
+  from: number;
+  to: number;
+  succeeded: number;
+  skipped: number;
+  failed: number;
+  chapters: ChapterBatchItem[];
+}
+
+// 章节候选稿，比较时 id 为 current 表示章节当前正文
+export interface ChapterDraft {
+  id: string;
+  chapter_number: number;
+  source: 'generated' | 'replaced' | 'current';
+  model_config_id?: string;
+  model?: string;
+  word_count: number;
+  paragraphs: number;
+  synthetic: boolean;
+  current: boolean; // 与章节当前正文相同
+  promoted_at?: string;
+  preview?: string;
+  content?: string;
+  created_at: string;
+}
+
+// 生成候选稿请求
+export interface GenerateChapterDraftsRequest {
+  count?: number;
+  model_config_ids?: string[]; // 依次轮流使用的模型配置
+}
+
+// 生成候选稿的结果，执行中随每个候选稿更新
+export interface ChapterDraftsResult {
+  chapter_number: number;
+  succeeded: number;
+  failed: number;
+  drafts: {
+    index: number;
+    model_config_id?: string;
+    status: 'pending' | 'succeeded' | 'failed';
+    error?: string;
+    draft?: ChapterDraft;
+  }[];
+}
+
+// 候选稿比较，恰好两个版本时附带逐段差异
+export interface ChapterDraftCompare {
+  drafts: ChapterDraft[];
+  diff?: {
+    added: number;
+    removed: number;
+    lines: { op: 'equal' | 'add' | 'remove'; text: string }[];
+  };
+}
+
+// 角色状态历史版本，chapter_number 为 0 时是架构阶段的初始状态
+export interface CharacterStateVersion {
+  chapter_number: number;
+  content: string;
+  synthetic: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+// 角色状态相对上一版本的逐行变化
+export interface CharacterStateDiff {
+  chapter_number: number;
+  previous_chapter_number: number | null;
+  added: number;
+  removed: number;
+  lines: { op: 'equal' | 'add' | 'remove'; text: string }[];
+}
+
+// 前文检索索引状态
+export interface RetrievalStatus {
+  enabled: boolean;
+  store?: 'pgvector' | 'memory';
+  indexed_chapters: number[];
+  missing_chapters: number[];
+}
+
+// 重建检索索引结果
+export interface RetrievalReindexResult {
+  chapters: number;
+  chunks: number;
+  failed: number[];
+}
+
+// 章节大纲解析结果
+export interface BlueprintSyncResult {
+  parsed: number;
+  created: number;
+  updated: number;
+  unchanged: number;
+  unparsed: { chapter_number?: number; line?: number; text?: string; reason: string }[];
+}
+
+// 生成章节大纲任务的结果
+export interface BlueprintGenerateResult {
+  project: Project;
+  chapters: BlueprintSyncResult;
+}
+
+// 后台生成任务类型
+export type JobType = 'architecture' | 'architecture_step' | 'blueprint' | 'chapter' | 'chapter_batch' | 'graph';
+export type JobStatus = 'pending' | 'running' | 'succeeded' | 'failed' | 'canceled';
+
+export interface Job<T = unknown> {
+  id: string;
+  project_id?: string;
+  type: JobType;
+  status: JobStatus;
+  step?: string;
+  step_index: number;
+  total_steps: number;
+  result?: T;
+  error?: string;
+  synthetic?: boolean;
+  attempts: number;
+  started_at?: string;
+  finished_at?: string;
+  created_at: string;
+  updated_at: string;
 }
 
 // 关系图谱相关类型